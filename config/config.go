@@ -1,19 +1,239 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
+// _id formats for ID_FORMATS
+const (
+	IDFormatObjectID = "objectid"
+	IDFormatUUID     = "uuid"
+	IDFormatKSUID    = "ksuid"
+)
+
+// api-key tiers for KEY_LIMITS: the key a request authenticated with
+const (
+	KeyTierFull     = "full"     // API_SECRET
+	KeyTierReadOnly = "readonly" // READONLY_API_SECRET
+)
+
+// Write transforms for WRITE_TRANSFORMS
+const (
+	TransformLowercase = "lowercase"
+	TransformTrim      = "trim"
+	TransformDefault   = "default"
+)
+
+// Handling of fields outside WRITABLE_FIELDS
+const (
+	WritableFieldsReject = "reject" // fail the write with 400
+	WritableFieldsStrip  = "strip"  // drop the fields and write the rest
+)
+
+// timeoutActions are the action names TIMEOUTS may set. findOne covers both the Data API
+// action and the REST route of the same name.
+var timeoutActions = map[string]bool{
+	// Data API
+	"insertOne": true, "insertMany": true, "findOne": true, "exists": true, "find": true,
+	"multiFind": true, "aggregate": true, "distinct": true, "timeBucket": true, "summarize": true,
+	"updateOne": true, "updateMany": true, "updateBulk": true, "deleteOne": true, "deleteMany": true,
+	"transaction": true,
+	// REST
+	"listDatabases": true, "listCollections": true, "createView": true, "inventory": true,
+	"findDocuments": true, "getDocument": true, "documentExists": true, "indexStats": true,
+	"insertDocument": true, "insertStream": true, "importAsync": true, "updateDocument": true,
+	"touchDocument": true, "unsetFields": true, "deleteDocument": true,
+	// Admin
+	"currentOps": true, "killOp": true, "indexSelectivity": true,
+	// Background
+	"explain": true, "usageExplain": true,
+}
+
+// USAGE_ACCOUNTING modes
+const (
+	UsageAccountingOff     = "off"     // no usage headers or ledger
+	UsageAccountingCursor  = "cursor"  // documents and bytes returned, from the results
+	UsageAccountingExplain = "explain" // also what finds examined, from a second explain run
+)
+
+// FieldTransform is one WRITE_TRANSFORMS step applied to a field on insert and update
+type FieldTransform struct {
+	Op    string      // TransformLowercase, TransformTrim or TransformDefault
+	Value interface{} // Replacement for a missing or null value (TransformDefault only)
+}
+
+// KeyLimits caps the results one api-key tier may request. Zero fields leave the global
+// behaviour in place.
+type KeyLimits struct {
+	MaxLimit            int64 // Largest find limit; requests without a limit get this one
+	MaxSkip             int64 // Largest find skip
+	MaxAggregateResults int   // Replaces MAX_AGGREGATE_RESULTS for aggregate and summarize
+	Reject              bool  // Reject a limit or skip over the cap with 400 instead of clamping it
+	MaxInflight         int   // Requests the tier may have in flight at once; replaces KEY_MAX_INFLIGHT
+}
+
 // Config holds all configuration for the application
 type Config struct {
-	MongoURI          string
-	APISecret         string
-	ReadOnlyAPISecret string
-	ServerPort        string
-	Database          string
+	MongoURI            string
+	APISecret           string
+	ReadOnlyAPISecret   string
+	AdminAPISecret      string
+	ServerPort          string
+	Database            string
+	HiddenDatabases     []string
+	AllowedDatabases    []string
+	Timeouts            map[string]time.Duration
+	MaxDistinctValues   int
+	MaxAggregateResults int
+	MaxReturnIDs        int
+
+	// MaxFilterOps and MaxFilterDepth cap the $-operators and document nesting of client
+	// filters (0 disables a cap)
+	MaxFilterOps   int
+	MaxFilterDepth int
+
+	// LatencyWarningPercent is the share of its timeout a read may use before the response
+	// is flagged with X-Latency-Warning (0 disables the flag)
+	LatencyWarningPercent int
+
+	// SlowQueryThreshold is how long a find may take before it is logged (0 disables the
+	// log). With ExplainSlowQueries, the log is followed by the query's winning plan.
+	SlowQueryThreshold time.Duration
+	ExplainSlowQueries bool
+
+	// MongoURIFallback is a second deployment that serves document reads when MongoURI
+	// cannot; writes never use it. Empty disables the fallback.
+	MongoURIFallback string
+
+	// ValidateDefaultDatabase makes startup fail unless MONGO_DATABASE is accessible
+	ValidateDefaultDatabase bool
+
+	// AggregateWriteAllowlist maps a source namespace ("db.collection") to the namespaces
+	// its aggregate pipelines may write to with $merge or $out
+	AggregateWriteAllowlist map[string][]string
+
+	// AggregateStageCaps maps a bounded pipeline stage ($sample, $limit, $bucketAuto) to
+	// the largest size clients may pass it; larger sizes are rewritten to the cap
+	AggregateStageCaps map[string]int64
+
+	// CoalesceReads shares one MongoDB round trip between identical concurrent single-document reads
+	CoalesceReads bool
+
+	// StrictCollections answers find and findOne on a collection that does not exist with
+	// 404 instead of an empty result
+	StrictCollections bool
+
+	// SortTiebreaker appends _id to client sorts so pagination order is total
+	SortTiebreaker bool
+
+	// EchoNamespace adds database and collection to Data API responses, like the REST responses
+	EchoNamespace bool
+
+	// UTCDates renders dates in responses as RFC 3339 UTC strings with millisecond precision
+	UTCDates bool
+
+	// ReadOnlyMode rejects every mutating request on the data routes, whatever the api-key
+	ReadOnlyMode bool
+
+	// DeleteConfirmation makes deleteMany two-phase: the first call returns a token that
+	// must be sent back with the same filter within DeleteConfirmationTTL to delete
+	DeleteConfirmation    bool
+	DeleteConfirmationTTL time.Duration
+
+	// RequireFilterOnDestructive rejects bulk updates/deletes with an empty filter unless confirmAll is set
+	RequireFilterOnDestructive bool
+
+	// ListExcludedFields maps a namespace ("db.collection") to fields left out of list
+	// responses unless the client's projection asks for them
+	ListExcludedFields map[string][]string
+
+	// Defaults maps a namespace ("db.collection") to field values merged into inserted
+	// documents that lack them
+	Defaults map[string]map[string]interface{}
+
+	// WriteTransforms maps a namespace ("db.collection") to the transforms applied, in
+	// order, to each named top-level field of inserted documents and of $set values
+	WriteTransforms map[string]map[string][]FieldTransform
+
+	// IDFormats maps a namespace ("db.collection") to the format of _id values generated
+	// for inserted documents that have none
+	IDFormats map[string]string
+
+	// WritableFields maps a namespace ("db.collection") to the only top-level fields clients
+	// may write; collections without an entry accept any field. WritableFieldsMode says
+	// whether other fields are rejected or stripped.
+	WritableFields     map[string][]string
+	WritableFieldsMode string
+
+	// SavedFilters maps a name to a filter document that find requests can run by name.
+	// {"$param": "name"} values in the filter are replaced by request parameters.
+	SavedFilters map[string]map[string]interface{}
+
+	// KeyLimits maps an api-key tier (KeyTierFull, KeyTierReadOnly) to its result caps
+	KeyLimits map[string]KeyLimits
+
+	// RowFilters maps an api-key tier to a predicate AND-ed into every filter the key
+	// reads, updates or deletes with, so it only reaches the documents the predicate matches.
+	// Documents the key inserts get the predicate's equality fields.
+	RowFilters map[string]map[string]interface{}
+
+	// KeyMaxInflight caps the in-flight requests of each api-key tier without its own
+	// KEY_LIMITS maxInflight (0 for no cap)
+	KeyMaxInflight int
+
+	// VersionField is the document field returned as the version after updates with returnVersion
+	VersionField string
+
+	// MetricsNamespaces limits per-namespace metrics to these "db.collection" or "db.*"
+	// entries (empty tracks every namespace)
+	MetricsNamespaces []string
+
+	// MethodOverrideMethods are the methods a POST may switch to with X-HTTP-Method-Override
+	// (empty disables overrides)
+	MethodOverrideMethods []string
+
+	// MaxInflight caps concurrently served HTTP requests (0 for no cap)
+	MaxInflight int
+
+	// ShutdownTimeout is how long in-flight requests may run after SIGINT or SIGTERM, and
+	// MongoCloseTimeout how long closing each MongoDB client may take after them
+	ShutdownTimeout   time.Duration
+	MongoCloseTimeout time.Duration
+
+	// UsageAccounting reports the MongoDB work of reads in response headers and accumulates
+	// it per api-key tier: UsageAccountingOff, UsageAccountingCursor or UsageAccountingExplain
+	UsageAccounting string
+
+	// Asynchronous imports: at most MaxImportJobs run at once, bodies are spooled to
+	// ImportSpoolDir (empty for the system temp directory), and the status of a finished
+	// job is kept for ImportJobTTL
+	MaxImportJobs  int
+	ImportSpoolDir string
+	ImportJobTTL   time.Duration
+
+	// Circuit breaker (disabled when CircuitBreakerThreshold is 0)
+	CircuitBreakerThreshold int
+	CircuitBreakerWindow    time.Duration
+	CircuitBreakerCooldown  time.Duration
+
+	// Readiness turns degraded when more than DegradedErrorRate percent of the requests in
+	// ErrorRateWindow failed, given at least ErrorRateMinRequests of them (0 disables it)
+	DegradedErrorRate    int
+	ErrorRateWindow      time.Duration
+	ErrorRateMinRequests int
+
+	// errs collects parse errors from Load so Validate can report them
+	errs []error
 }
 
 // Load reads configuration from environment variables and .env file
@@ -25,13 +245,241 @@ func Load() *Config {
 		log.Println("No .env file found, using environment variables only")
 	}
 
-	return &Config{
+	cfg := &Config{
 		MongoURI:          GetEnv("MONGO_URI", ""),
+		MongoURIFallback:  GetEnv("MONGO_URI_FALLBACK", ""),
 		APISecret:         GetEnv("API_SECRET", ""),
 		ReadOnlyAPISecret: GetEnv("READONLY_API_SECRET", ""),
+		AdminAPISecret:    GetEnv("ADMIN_API_SECRET", ""),
 		ServerPort:        GetEnv("PORT", "8080"),
 		Database:          GetEnv("MONGO_DATABASE", ""),
+		HiddenDatabases:   GetEnvList("HIDDEN_DATABASES", []string{"admin", "config", "local"}),
+		AllowedDatabases:  GetEnvList("ALLOWED_DATABASES", nil),
+		MetricsNamespaces: GetEnvList("METRICS_NAMESPACES", nil),
+	}
+
+	cfg.MethodOverrideMethods = GetEnvList("METHOD_OVERRIDE_METHODS", nil)
+	for _, method := range cfg.MethodOverrideMethods {
+		switch strings.ToUpper(method) {
+		case "PUT", "PATCH", "DELETE":
+		default:
+			cfg.errs = append(cfg.errs, &ConfigError{Field: "METHOD_OVERRIDE_METHODS", Message: "METHOD_OVERRIDE_METHODS may only contain PUT, PATCH and DELETE"})
+		}
+	}
+
+	cfg.ReadOnlyMode = cfg.envBool("READ_ONLY_MODE", false)
+	cfg.UTCDates = cfg.envBool("UTC_DATES", false)
+	cfg.EchoNamespace = cfg.envBool("ECHO_NAMESPACE", false)
+
+	cfg.ValidateDefaultDatabase = cfg.envBool("VALIDATE_DEFAULT_DB", false)
+	if cfg.ValidateDefaultDatabase && cfg.Database == "" {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "VALIDATE_DEFAULT_DB", Message: "VALIDATE_DEFAULT_DB requires MONGO_DATABASE"})
+	}
+
+	timeouts, err := parseDurationMap(GetEnv("TIMEOUTS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "TIMEOUTS", Message: "Invalid TIMEOUTS: " + err.Error()})
+	}
+	cfg.Timeouts = timeouts
+	var unknownActions []string
+	for action := range timeouts {
+		if !timeoutActions[action] {
+			unknownActions = append(unknownActions, action)
+		}
+	}
+	if len(unknownActions) > 0 {
+		sort.Strings(unknownActions)
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "TIMEOUTS", Message: "TIMEOUTS names unknown actions: " + strings.Join(unknownActions, ", ")})
+	}
+	cfg.LatencyWarningPercent = cfg.envInt("LATENCY_WARNING_PERCENT", 0)
+	if cfg.LatencyWarningPercent > 100 {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "LATENCY_WARNING_PERCENT", Message: "LATENCY_WARNING_PERCENT must be a percentage between 0 and 100"})
+	}
+
+	cfg.SlowQueryThreshold = cfg.envDuration("SLOW_QUERY_THRESHOLD", 0)
+	cfg.ExplainSlowQueries = cfg.envBool("EXPLAIN_SLOW_QUERIES", false)
+	if cfg.ExplainSlowQueries && cfg.SlowQueryThreshold == 0 {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "EXPLAIN_SLOW_QUERIES", Message: "EXPLAIN_SLOW_QUERIES requires SLOW_QUERY_THRESHOLD"})
+	}
+
+	cfg.MaxDistinctValues = cfg.envInt("MAX_DISTINCT_VALUES", 10000)
+	cfg.MaxAggregateResults = cfg.envInt("MAX_AGGREGATE_RESULTS", 10000)
+	cfg.MaxReturnIDs = cfg.envInt("MAX_RETURN_IDS", 1000)
+	cfg.MaxFilterOps = cfg.envInt("MAX_FILTER_OPS", 200)
+	cfg.MaxFilterDepth = cfg.envInt("MAX_FILTER_DEPTH", 20)
+
+	allowlist, err := parseNamespacePairs(GetEnv("AGGREGATE_WRITE_ALLOWLIST", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "AGGREGATE_WRITE_ALLOWLIST", Message: "Invalid AGGREGATE_WRITE_ALLOWLIST: " + err.Error()})
+	}
+	cfg.AggregateWriteAllowlist = allowlist
+
+	stageCaps, err := parseStageCaps(GetEnv("AGGREGATE_STAGE_CAPS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "AGGREGATE_STAGE_CAPS", Message: "Invalid AGGREGATE_STAGE_CAPS: " + err.Error()})
+	}
+	cfg.AggregateStageCaps = stageCaps
+
+	cfg.RequireFilterOnDestructive = cfg.envBool("REQUIRE_FILTER_ON_DESTRUCTIVE", true)
+	cfg.DeleteConfirmation = cfg.envBool("DELETE_CONFIRMATION", false)
+	cfg.DeleteConfirmationTTL = cfg.envDuration("DELETE_CONFIRMATION_TTL", time.Minute)
+	cfg.SortTiebreaker = cfg.envBool("SORT_TIEBREAKER", true)
+	cfg.CoalesceReads = cfg.envBool("COALESCE_READS", false)
+	cfg.StrictCollections = cfg.envBool("STRICT_COLLECTIONS", false)
+
+	excluded, err := parseNamespaceFields(GetEnv("LIST_EXCLUDED_FIELDS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "LIST_EXCLUDED_FIELDS", Message: "Invalid LIST_EXCLUDED_FIELDS: " + err.Error()})
+	}
+	cfg.ListExcludedFields = excluded
+
+	defaults, err := parseNamespaceDocuments(GetEnv("DEFAULTS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "DEFAULTS", Message: "Invalid DEFAULTS: " + err.Error()})
+	}
+	cfg.Defaults = defaults
+
+	transforms, err := parseWriteTransforms(GetEnv("WRITE_TRANSFORMS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "WRITE_TRANSFORMS", Message: "Invalid WRITE_TRANSFORMS: " + err.Error()})
+	}
+	cfg.WriteTransforms = transforms
+
+	idFormats, err := parseIDFormats(GetEnv("ID_FORMATS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "ID_FORMATS", Message: "Invalid ID_FORMATS: " + err.Error()})
+	}
+	cfg.IDFormats = idFormats
+
+	writable, err := parseWritableFields(GetEnv("WRITABLE_FIELDS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "WRITABLE_FIELDS", Message: "Invalid WRITABLE_FIELDS: " + err.Error()})
+	}
+	cfg.WritableFields = writable
+	cfg.WritableFieldsMode = strings.ToLower(GetEnv("WRITABLE_FIELDS_MODE", WritableFieldsReject))
+	if cfg.WritableFieldsMode != WritableFieldsReject && cfg.WritableFieldsMode != WritableFieldsStrip {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "WRITABLE_FIELDS_MODE", Message: "WRITABLE_FIELDS_MODE must be reject or strip"})
+	}
+
+	savedFilters, err := parseKeyedDocuments(GetEnv("SAVED_FILTERS", ""), "name", isFilterName)
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "SAVED_FILTERS", Message: "Invalid SAVED_FILTERS: " + err.Error()})
+	}
+	cfg.SavedFilters = savedFilters
+
+	keyLimits, err := parseKeyLimits(GetEnv("KEY_LIMITS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "KEY_LIMITS", Message: "Invalid KEY_LIMITS: " + err.Error()})
+	}
+	cfg.KeyLimits = keyLimits
+
+	rowFilters, err := parseRowFilters(GetEnv("ROW_FILTERS", ""))
+	if err != nil {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "ROW_FILTERS", Message: "Invalid ROW_FILTERS: " + err.Error()})
+	}
+	cfg.RowFilters = rowFilters
+	cfg.KeyMaxInflight = cfg.envInt("KEY_MAX_INFLIGHT", 0)
+
+	cfg.VersionField = GetEnv("VERSION_FIELD", "updatedAt")
+	if cfg.VersionField == "_id" || strings.HasPrefix(cfg.VersionField, "$") {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "VERSION_FIELD", Message: "VERSION_FIELD must name a document field other than _id"})
+	}
+
+	cfg.MaxInflight = cfg.envInt("MAX_INFLIGHT", 0)
+
+	cfg.ShutdownTimeout = cfg.envMillis("SHUTDOWN_TIMEOUT_MS", 10*time.Second)
+	cfg.MongoCloseTimeout = cfg.envMillis("MONGO_CLOSE_TIMEOUT_MS", 5*time.Second)
+
+	cfg.UsageAccounting = strings.ToLower(GetEnv("USAGE_ACCOUNTING", UsageAccountingOff))
+	switch cfg.UsageAccounting {
+	case UsageAccountingOff, UsageAccountingCursor, UsageAccountingExplain:
+	default:
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "USAGE_ACCOUNTING", Message: "USAGE_ACCOUNTING must be off, cursor or explain"})
+	}
+
+	cfg.MaxImportJobs = cfg.envInt("MAX_IMPORT_JOBS", 2)
+	if cfg.MaxImportJobs == 0 {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "MAX_IMPORT_JOBS", Message: "MAX_IMPORT_JOBS must be at least 1"})
+	}
+	cfg.ImportSpoolDir = GetEnv("IMPORT_SPOOL_DIR", "")
+	cfg.ImportJobTTL = cfg.envDuration("IMPORT_JOB_TTL", 24*time.Hour)
+
+	cfg.CircuitBreakerThreshold = cfg.envInt("CIRCUIT_BREAKER_THRESHOLD", 0)
+	cfg.CircuitBreakerWindow = cfg.envDuration("CIRCUIT_BREAKER_WINDOW", 30*time.Second)
+	cfg.CircuitBreakerCooldown = cfg.envDuration("CIRCUIT_BREAKER_COOLDOWN", 30*time.Second)
+
+	cfg.DegradedErrorRate = cfg.envInt("DEGRADED_ERROR_RATE", 0)
+	if cfg.DegradedErrorRate > 100 {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "DEGRADED_ERROR_RATE", Message: "DEGRADED_ERROR_RATE must be a percentage between 0 and 100"})
+	}
+	cfg.ErrorRateWindow = cfg.envDuration("ERROR_RATE_WINDOW", time.Minute)
+	if cfg.ErrorRateWindow < time.Second {
+		cfg.errs = append(cfg.errs, &ConfigError{Field: "ERROR_RATE_WINDOW", Message: "ERROR_RATE_WINDOW must be at least 1s"})
+	}
+	cfg.ErrorRateMinRequests = cfg.envInt("ERROR_RATE_MIN_REQUESTS", 20)
+
+	return cfg
+}
+
+// envInt reads a non-negative integer environment variable, recording an error if it is malformed
+func (c *Config) envInt(key string, defaultValue int) int {
+	value := GetEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		c.errs = append(c.errs, &ConfigError{Field: key, Message: key + " must be a non-negative integer"})
+		return defaultValue
+	}
+	return parsed
+}
+
+// envBool reads a boolean environment variable, recording an error if it is malformed
+func (c *Config) envBool(key string, defaultValue bool) bool {
+	value := GetEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		c.errs = append(c.errs, &ConfigError{Field: key, Message: key + " must be true or false"})
+		return defaultValue
+	}
+	return parsed
+}
+
+// envDuration reads a positive Go duration environment variable, recording an error if it is malformed
+func (c *Config) envDuration(key string, defaultValue time.Duration) time.Duration {
+	value := GetEnv(key, "")
+	if value == "" {
+		return defaultValue
 	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		c.errs = append(c.errs, &ConfigError{Field: key, Message: key + " must be a positive duration such as 30s"})
+		return defaultValue
+	}
+	return parsed
+}
+
+// envMillis reads a positive number of milliseconds from an environment variable, recording
+// an error if it is malformed
+func (c *Config) envMillis(key string, defaultValue time.Duration) time.Duration {
+	value := GetEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		c.errs = append(c.errs, &ConfigError{Field: key, Message: key + " must be a positive number of milliseconds"})
+		return defaultValue
+	}
+	return time.Duration(parsed) * time.Millisecond
 }
 
 // getEnv retrieves an environment variable or returns a default value
@@ -42,14 +490,479 @@ func GetEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// GetEnvList retrieves a comma-separated environment variable as a list.
+// An explicitly empty variable yields an empty list; an unset one yields the default.
+func GetEnvList(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseDurationMap parses a "key:duration,key:duration" list such as "aggregate:60s,findOne:3s"
+func parseDurationMap(value string) (map[string]time.Duration, error) {
+	result := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, raw, ok := strings.Cut(entry, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("entry %q must have the form key:duration", entry)
+		}
+
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", entry, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("entry %q: duration must be positive", entry)
+		}
+		result[key] = d
+	}
+	return result, nil
+}
+
+// parseNamespacePairs parses a "db.source:db.target,..." list into source -> targets
+func parseNamespacePairs(value string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		source, target, ok := strings.Cut(entry, ":")
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if !ok || !isNamespace(source) || !isNamespace(target) {
+			return nil, fmt.Errorf("entry %q must have the form db.source:db.target", entry)
+		}
+		result[source] = append(result[source], target)
+	}
+	return result, nil
+}
+
+// parseStageCaps parses a "$stage:size,..." list such as "$sample:1000,$limit:50000"
+func parseStageCaps(value string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		stage, raw, ok := strings.Cut(entry, ":")
+		stage = strings.TrimSpace(stage)
+		if !ok || stage == "" {
+			return nil, fmt.Errorf("entry %q must have the form $stage:size", entry)
+		}
+		switch stage {
+		case "$sample", "$limit", "$bucketAuto":
+		default:
+			return nil, fmt.Errorf("entry %q: stage must be $sample, $limit or $bucketAuto", entry)
+		}
+
+		size, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("entry %q: size must be a positive integer", entry)
+		}
+		result[stage] = size
+	}
+	return result, nil
+}
+
+// parseNamespaceFields parses a "db.collection:field,..." list into namespace -> fields
+func parseNamespaceFields(value string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		namespace, field, ok := strings.Cut(entry, ":")
+		namespace, field = strings.TrimSpace(namespace), strings.TrimSpace(field)
+		if !ok || !isNamespace(namespace) || field == "" || strings.HasPrefix(field, "$") {
+			return nil, fmt.Errorf("entry %q must have the form db.collection:field", entry)
+		}
+		result[namespace] = append(result[namespace], field)
+	}
+	return result, nil
+}
+
+// parseNamespaceDocuments parses a "db.collection:{...},..." list of Extended JSON objects
+// into namespace -> fields. Field names must be plain top-level names.
+func parseNamespaceDocuments(value string) (map[string]map[string]interface{}, error) {
+	result, err := parseKeyedDocuments(value, "db.collection", isNamespace)
+	if err != nil {
+		return nil, err
+	}
+	for namespace, fields := range result {
+		for field := range fields {
+			if field == "" || strings.HasPrefix(field, "$") || strings.Contains(field, ".") {
+				return nil, fmt.Errorf("%s: invalid field name %q", namespace, field)
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseWriteTransforms parses a "db.collection:{...},..." list of WRITE_TRANSFORMS entries.
+// Each field maps to a transform or an array of them, applied in order: "lowercase",
+// "trim" or {"default": value}.
+func parseWriteTransforms(value string) (map[string]map[string][]FieldTransform, error) {
+	entries, err := parseNamespaceDocuments(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string][]FieldTransform, len(entries))
+	for namespace, fields := range entries {
+		result[namespace] = make(map[string][]FieldTransform, len(fields))
+		for field, spec := range fields {
+			var steps []interface{}
+			switch v := spec.(type) {
+			case bson.A:
+				steps = v
+			case []interface{}:
+				steps = v
+			default:
+				steps = []interface{}{spec}
+			}
+			if len(steps) == 0 {
+				return nil, fmt.Errorf("%s: %s has no transforms", namespace, field)
+			}
+
+			transforms := make([]FieldTransform, 0, len(steps))
+			for _, step := range steps {
+				transform, err := parseFieldTransform(step)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s: %w", namespace, field, err)
+				}
+				transforms = append(transforms, transform)
+			}
+			result[namespace][field] = transforms
+		}
+	}
+	return result, nil
+}
+
+// parseFieldTransform parses one step of a WRITE_TRANSFORMS field
+func parseFieldTransform(step interface{}) (FieldTransform, error) {
+	switch v := step.(type) {
+	case string:
+		switch v {
+		case TransformLowercase, TransformTrim:
+			return FieldTransform{Op: v}, nil
+		}
+	case map[string]interface{}:
+		if value, ok := v[TransformDefault]; ok && len(v) == 1 {
+			return FieldTransform{Op: TransformDefault, Value: value}, nil
+		}
+	case bson.M:
+		if value, ok := v[TransformDefault]; ok && len(v) == 1 {
+			return FieldTransform{Op: TransformDefault, Value: value}, nil
+		}
+	case bson.D:
+		if len(v) == 1 && v[0].Key == TransformDefault {
+			return FieldTransform{Op: TransformDefault, Value: v[0].Value}, nil
+		}
+	}
+	return FieldTransform{}, fmt.Errorf("unknown transform %v (use \"lowercase\", \"trim\" or {\"default\": value})", step)
+}
+
+// parseKeyedDocuments parses a "key:{...},key:{...}" list of Extended JSON objects into
+// key -> document. Commas inside the objects do not split entries; repeated keys merge.
+func parseKeyedDocuments(value, keyForm string, validKey func(string) bool) (map[string]map[string]interface{}, error) {
+	result := make(map[string]map[string]interface{})
+	rest := strings.TrimSpace(value)
+	for rest != "" {
+		key, body, ok := strings.Cut(rest, ":")
+		key = strings.TrimSpace(key)
+		if !ok || !validKey(key) {
+			return nil, fmt.Errorf("entry %q must have the form %s:{...}", rest, keyForm)
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(body))
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		var fields map[string]interface{}
+		if err := bson.UnmarshalExtJSON(raw, false, &fields); err != nil {
+			return nil, fmt.Errorf("%s: must be a JSON object: %w", key, err)
+		}
+
+		if result[key] == nil {
+			result[key] = make(map[string]interface{}, len(fields))
+		}
+		for field, v := range fields {
+			result[key][field] = v
+		}
+
+		rest = strings.TrimSpace(body[decoder.InputOffset():])
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, fmt.Errorf("%s: entries must be separated by commas", key)
+			}
+			rest = strings.TrimSpace(rest[1:])
+		}
+	}
+	return result, nil
+}
+
+// isFilterName reports whether s is a valid SAVED_FILTERS name
+func isFilterName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIDFormats parses a "db.collection:format,..." list into namespace -> _id format
+func parseIDFormats(value string) (map[string]string, error) {
+	entries, err := parseNamespaceFields(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(entries))
+	for namespace, formats := range entries {
+		if len(formats) > 1 {
+			return nil, fmt.Errorf("%s has more than one format", namespace)
+		}
+		format := strings.ToLower(formats[0])
+		switch format {
+		case IDFormatObjectID, IDFormatUUID, IDFormatKSUID:
+			result[namespace] = format
+		default:
+			return nil, fmt.Errorf("%s: unknown format %q (use objectid, uuid or ksuid)", namespace, formats[0])
+		}
+	}
+	return result, nil
+}
+
+// parseWritableFields parses a "db.collection:field,..." list of WRITABLE_FIELDS entries.
+// Fields are top-level names; _id is always writable and need not be listed.
+func parseWritableFields(value string) (map[string][]string, error) {
+	entries, err := parseNamespaceFields(value)
+	if err != nil {
+		return nil, err
+	}
+	for namespace, fields := range entries {
+		for _, field := range fields {
+			if strings.Contains(field, ".") {
+				return nil, fmt.Errorf("%s: %q must be a top-level field name", namespace, field)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// parseRowFilters parses a "full|readonly:{...},..." list of ROW_FILTERS predicates. The
+// predicates are filter documents on top-level fields, such as {"tenantId": "acme"}.
+func parseRowFilters(value string) (map[string]map[string]interface{}, error) {
+	result, err := parseKeyedDocuments(value, "full|readonly", isKeyTier)
+	if err != nil {
+		return nil, err
+	}
+	for tier, predicate := range result {
+		if len(predicate) == 0 {
+			return nil, fmt.Errorf("%s: predicate must not be empty", tier)
+		}
+		for field := range predicate {
+			if field == "" || strings.HasPrefix(field, "$") || strings.Contains(field, ".") {
+				return nil, fmt.Errorf("%s: invalid field name %q", tier, field)
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseKeyLimits parses a "tier:{...},..." list of KEY_LIMITS entries into tier -> limits
+func parseKeyLimits(value string) (map[string]KeyLimits, error) {
+	entries, err := parseKeyedDocuments(value, "full|readonly", isKeyTier)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]KeyLimits, len(entries))
+	for tier, fields := range entries {
+		var limits KeyLimits
+		for field, v := range fields {
+			if field == "reject" {
+				reject, ok := v.(bool)
+				if !ok {
+					return nil, fmt.Errorf("%s: reject must be true or false", tier)
+				}
+				limits.Reject = reject
+				continue
+			}
+
+			var n int64
+			switch number := v.(type) {
+			case int32:
+				n = int64(number)
+			case int64:
+				n = number
+			default:
+				return nil, fmt.Errorf("%s: %s must be a whole number", tier, field)
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("%s: %s must not be negative", tier, field)
+			}
+
+			switch field {
+			case "maxLimit":
+				limits.MaxLimit = n
+			case "maxSkip":
+				limits.MaxSkip = n
+			case "maxAggregateResults":
+				limits.MaxAggregateResults = int(n)
+			case "maxInflight":
+				limits.MaxInflight = int(n)
+			default:
+				return nil, fmt.Errorf("%s: unknown field %q (use maxLimit, maxSkip, maxAggregateResults, maxInflight or reject)", tier, field)
+			}
+		}
+		result[tier] = limits
+	}
+	return result, nil
+}
+
+// isKeyTier reports whether s names an api-key tier
+func isKeyTier(s string) bool {
+	return s == KeyTierFull || s == KeyTierReadOnly
+}
+
+// isNamespace reports whether s has the form "db.collection"
+func isNamespace(s string) bool {
+	db, coll, ok := strings.Cut(s, ".")
+	return ok && db != "" && coll != ""
+}
+
+// Timeout returns the configured timeout for an action, or fallback when none is set
+func (c *Config) Timeout(action string, fallback time.Duration) time.Duration {
+	if d, ok := c.Timeouts[action]; ok {
+		return d
+	}
+	return fallback
+}
+
+// IsHiddenDatabase reports whether a database should be left out of listings
+func (c *Config) IsHiddenDatabase(name string) bool {
+	for _, hidden := range c.HiddenDatabases {
+		if hidden == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludedListFields returns the fields hidden from list responses for a collection
+func (c *Config) ExcludedListFields(database, collection string) []string {
+	return c.ListExcludedFields[database+"."+collection]
+}
+
+// InsertDefaults returns the DEFAULTS field values of a collection
+func (c *Config) InsertDefaults(database, collection string) map[string]interface{} {
+	return c.Defaults[database+"."+collection]
+}
+
+// Transforms returns the WRITE_TRANSFORMS of a collection, keyed by field
+func (c *Config) Transforms(database, collection string) map[string][]FieldTransform {
+	return c.WriteTransforms[database+"."+collection]
+}
+
+// IDFormat returns the ID_FORMATS format of a collection, or "" to let MongoDB assign ObjectIDs
+func (c *Config) IDFormat(database, collection string) string {
+	return c.IDFormats[database+"."+collection]
+}
+
+// WritableFieldsOf returns the WRITABLE_FIELDS of a collection, or nil when clients may write any field
+func (c *Config) WritableFieldsOf(database, collection string) []string {
+	return c.WritableFields[database+"."+collection]
+}
+
+// LimitsFor returns the KEY_LIMITS caps of an api-key tier; the zero value when none are set
+func (c *Config) LimitsFor(tier string) KeyLimits {
+	return c.KeyLimits[tier]
+}
+
+// RowFilter returns the ROW_FILTERS predicate of an api-key tier, or nil when the key is not restricted
+func (c *Config) RowFilter(tier string) map[string]interface{} {
+	return c.RowFilters[tier]
+}
+
+// MaxInflightFor returns how many requests an api-key tier may have in flight at once:
+// its KEY_LIMITS maxInflight, else KEY_MAX_INFLIGHT. 0 means no cap.
+func (c *Config) MaxInflightFor(tier string) int {
+	if limit := c.KeyLimits[tier].MaxInflight; limit > 0 {
+		return limit
+	}
+	return c.KeyMaxInflight
+}
+
+// StageCap returns the AGGREGATE_STAGE_CAPS size of a pipeline stage, or 0 when it is not capped
+func (c *Config) StageCap(stage string) int64 {
+	return c.AggregateStageCaps[stage]
+}
+
+// AllowsAggregateWrite reports whether pipelines on source may $merge or $out into target.
+// Both are namespaces of the form "db.collection".
+func (c *Config) AllowsAggregateWrite(source, target string) bool {
+	for _, allowed := range c.AggregateWriteAllowlist[source] {
+		if allowed == target {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAllowedDatabase reports whether a database may be addressed. An empty ALLOWED_DATABASES allows all.
+func (c *Config) IsAllowedDatabase(name string) bool {
+	if len(c.AllowedDatabases) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedDatabases {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Validate checks if required configuration is present
 func (c *Config) Validate() error {
+	if len(c.errs) > 0 {
+		return c.errs[0]
+	}
 	if c.MongoURI == "" {
 		return &ConfigError{Field: "MONGO_URI", Message: "MongoDB URI is required"}
 	}
 	if c.APISecret == "" {
 		return &ConfigError{Field: "API_SECRET", Message: "API Secret is required"}
 	}
+	// The admin routes can kill operations, so they must not open up to the data api-keys
+	if c.AdminAPISecret != "" && (c.AdminAPISecret == c.APISecret || c.AdminAPISecret == c.ReadOnlyAPISecret) {
+		return &ConfigError{Field: "ADMIN_API_SECRET", Message: "ADMIN_API_SECRET must differ from API_SECRET and READONLY_API_SECRET"}
+	}
 	return nil
 }
 
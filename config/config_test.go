@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// timeoutsError returns the TIMEOUTS error Load recorded, if any
+func timeoutsError(cfg *Config) *ConfigError {
+	for _, err := range cfg.errs {
+		var configErr *ConfigError
+		if errors.As(err, &configErr) && configErr.Field == "TIMEOUTS" {
+			return configErr
+		}
+	}
+	return nil
+}
+
+func TestTimeoutsActions(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeouts    string
+		wantMessage string
+	}{
+		{"known actions", "find:60s,findOne:3s,insertStream:1m,explain:5s,usageExplain:5s", ""},
+		{"unknown actions are listed in order", "findone:3s,find:60s,bogus:1s", "TIMEOUTS names unknown actions: bogus, findone"},
+		{"invalid duration", "find:soon", `Invalid TIMEOUTS: entry "find:soon": time: invalid duration "soon"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TIMEOUTS", tt.timeouts)
+			err := timeoutsError(Load())
+			switch {
+			case tt.wantMessage == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err.Message)
+			case tt.wantMessage != "" && err == nil:
+				t.Fatalf("TIMEOUTS=%s was accepted, want %q", tt.timeouts, tt.wantMessage)
+			case tt.wantMessage != "" && err.Message != tt.wantMessage:
+				t.Fatalf("error = %q, want %q", err.Message, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+const (
+	// HealthPingTimeout bounds each background health ping
+	HealthPingTimeout = 2 * time.Second
+	// HealthRetryInterval is how often an unhealthy upstream is re-probed
+	HealthRetryInterval = 5 * time.Second
+)
+
+// Healthy reports whether the upstream MongoDB is believed to be reachable.
+// It starts out true and only flips once a connection attempt or ping finds no reachable server.
+func (c *Client) Healthy() bool {
+	return !c.unhealthy.Load()
+}
+
+// IsConnected reports whether a MongoDB connection is currently open
+func (c *Client) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client != nil
+}
+
+// markHealthy records a successful round trip to MongoDB
+func (c *Client) markHealthy() {
+	if c.unhealthy.CompareAndSwap(true, false) {
+		log.Println("MongoDB upstream recovered")
+	}
+}
+
+// markUnhealthy records a failed round trip and starts the recovery probe
+func (c *Client) markUnhealthy(err error) {
+	if c.unhealthy.CompareAndSwap(false, true) {
+		log.Printf("MongoDB upstream unhealthy: %v", c.redactError(err))
+		go c.monitorRecovery()
+	}
+}
+
+// unreachable reports whether err, from connecting or pinging under ctx, shows that no
+// MongoDB server could be selected or the network failed. A caller that went away or whose
+// own deadline ran out first says nothing about the server.
+func unreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var selection topology.ServerSelectionError
+	return errors.As(err, &selection) || mongo.IsNetworkError(err)
+}
+
+// pingConnection pings the current connection, if any, and updates the health flag
+func (c *Client) pingConnection() {
+	c.mu.RLock()
+	client := c.client
+	c.mu.RUnlock()
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HealthPingTimeout)
+	defer cancel()
+
+	if err := client.Ping(ctx, nil); err != nil {
+		c.markUnhealthy(err)
+		return
+	}
+	c.markHealthy()
+}
+
+// monitorRecovery probes MongoDB until it becomes reachable again
+func (c *Client) monitorRecovery() {
+	ticker := time.NewTicker(HealthRetryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if c.Healthy() {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), HealthPingTimeout)
+		err := c.Ping(ctx)
+		cancel()
+		if err == nil {
+			c.markHealthy()
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+func TestUnreachable(t *testing.T) {
+	live := context.Background()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"server selection timeout", live, topology.ServerSelectionError{Wrapped: topology.ErrServerSelectionTimeout}, true},
+		{"network error", live, mongo.CommandError{Code: 6, Name: "HostUnreachable", Labels: []string{"NetworkError"}}, true},
+		{"caller cancelled", cancelled, topology.ServerSelectionError{Wrapped: context.Canceled}, false},
+		{"caller deadline", live, fmt.Errorf("ping: %w", topology.ServerSelectionError{Wrapped: context.DeadlineExceeded}), false},
+		{"authentication failed", live, mongo.CommandError{Code: 18, Name: "AuthenticationFailed"}, false},
+		{"invalid URI", live, errors.New("error parsing uri: scheme must be \"mongodb\" or \"mongodb+srv\""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unreachable(tt.ctx, tt.err); got != tt.want {
+				t.Fatalf("unreachable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureConnectionHealth(t *testing.T) {
+	// Nothing listens on port 1, so server selection fails once its timeout passes
+	const uri = "mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=200&connectTimeoutMS=100"
+
+	t.Run("caller cancelled", func(t *testing.T) {
+		c := &Client{uri: uri}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := c.ensureConnection(ctx); err == nil {
+			t.Fatal("ensureConnection succeeded without a server")
+		}
+		if !c.Healthy() {
+			t.Error("a cancelled caller marked the upstream unhealthy")
+		}
+	})
+
+	t.Run("caller deadline", func(t *testing.T) {
+		c := &Client{uri: uri}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := c.ensureConnection(ctx); err == nil {
+			t.Fatal("ensureConnection succeeded without a server")
+		}
+		if !c.Healthy() {
+			t.Error("a caller's deadline marked the upstream unhealthy")
+		}
+	})
+
+	t.Run("server selection", func(t *testing.T) {
+		c := &Client{uri: uri}
+		if err := c.ensureConnection(context.Background()); err == nil {
+			t.Fatal("ensureConnection succeeded without a server")
+		}
+		if c.Healthy() {
+			t.Error("failed server selection left the upstream healthy")
+		}
+	})
+}
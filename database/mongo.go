@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -16,6 +17,8 @@ const (
 	ConnectionTimeout = 5 * time.Minute
 	// ConnectionCheckInterval is how often to check for stale connections
 	ConnectionCheckInterval = 1 * time.Minute
+	// MaxKeepAlive is the longest a single KeepAlive call may hold the connection open
+	MaxKeepAlive = 1 * time.Hour
 )
 
 // Client wraps the MongoDB client with dynamic connection management
@@ -23,10 +26,13 @@ type Client struct {
 	uri          string
 	client       *mongo.Client
 	lastUsed     time.Time
+	keepUntil    time.Time // Idle cleanup is suppressed until this time
+	pins         int       // Number of active PinConnection calls
 	mu           sync.RWMutex
 	connectionMu sync.Mutex // Protects connection creation to prevent race conditions
 	stopCleanup  chan struct{}
 	cleanupMu    sync.Mutex // Protects cleanup goroutine lifecycle
+	unhealthy    atomic.Bool
 }
 
 // NewClient creates a new MongoDB client with dynamic connection management
@@ -77,10 +83,27 @@ func (c *Client) ensureConnection(ctx context.Context) error {
 	clientOptions := options.Client().ApplyURI(c.uri)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
+		// Driver errors about the connection string may quote it, credentials included
+		err = c.redactError(err)
+		if unreachable(ctx, err) {
+			c.markUnhealthy(err)
+		}
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	// Connect is lazy, so verify the server is actually reachable before handing it out
+	if err := client.Ping(ctx, nil); err != nil {
+		err = c.redactError(err)
+		if unreachable(ctx, err) {
+			c.markUnhealthy(err)
+		}
+		disconnectCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		client.Disconnect(disconnectCtx)
+		cancel()
 		return fmt.Errorf("failed to connect to MongoDB: %w", err)
-	} else {
-		log.Println("Connected to MongoDB")
 	}
+	c.markHealthy()
+	log.Printf("Connected to MongoDB at %s", RedactURI(c.uri))
 
 	// Update state with new connection
 	c.mu.Lock()
@@ -137,11 +160,14 @@ func (c *Client) cleanupStaleConnections() {
 		select {
 		case <-ticker.C:
 			log.Println("Checking for stale connections")
+			c.pingConnection()
+
 			c.mu.Lock()
 			timeSinceLastUse := time.Since(c.lastUsed)
 			hasConnection := c.client != nil
+			held := c.pins > 0 || time.Now().Before(c.keepUntil)
 
-			if hasConnection && timeSinceLastUse > ConnectionTimeout {
+			if hasConnection && !held && timeSinceLastUse > ConnectionTimeout {
 				// Connection is stale, close it
 				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 				if c.client != nil {
@@ -164,6 +190,40 @@ func (c *Client) cleanupStaleConnections() {
 	}
 }
 
+// PinConnection prevents the idle cleanup from closing the connection until Unpin is called.
+// Pins are counted, so every PinConnection must be paired with exactly one Unpin.
+func (c *Client) PinConnection() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pins++
+}
+
+// Unpin releases a pin taken by PinConnection. The idle timeout restarts from the moment
+// the last pin is released so the connection isn't closed immediately afterwards.
+func (c *Client) Unpin() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pins > 0 {
+		c.pins--
+	}
+	c.lastUsed = time.Now()
+}
+
+// KeepAlive suppresses the idle cleanup for the given duration (capped at MaxKeepAlive).
+// Calls only ever extend the current keep-alive window, never shorten it.
+func (c *Client) KeepAlive(d time.Duration) {
+	if d > MaxKeepAlive {
+		d = MaxKeepAlive
+	}
+	until := time.Now().Add(d)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until.After(c.keepUntil) {
+		c.keepUntil = until
+	}
+}
+
 // GetClient returns the MongoDB client (deprecated, use GetConnection instead)
 func (c *Client) GetClient() *mongo.Client {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -205,6 +265,67 @@ func (c *Client) ListCollections(ctx context.Context, dbName string) ([]string,
 	return collections, nil
 }
 
+// Collection types reported by ListCollectionTypes
+const (
+	CollectionTypeCollection = "collection"
+	CollectionTypeView       = "view"
+	CollectionTypeTimeseries = "timeseries"
+)
+
+// CollectionInfo is a collection name with its type
+type CollectionInfo struct {
+	Name string
+	Type string // CollectionTypeCollection, CollectionTypeView or CollectionTypeTimeseries
+}
+
+// ListCollectionTypes lists the collections in the specified database with their type.
+// A non-empty collType keeps only collections of that type; MongoDB applies the filter.
+func (c *Client) ListCollectionTypes(ctx context.Context, dbName, collType string) ([]CollectionInfo, error) {
+	client, err := c.GetConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := map[string]interface{}{}
+	if collType != "" {
+		filter["type"] = collType
+	}
+	specs, err := client.Database(dbName).ListCollectionSpecifications(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	collections := make([]CollectionInfo, len(specs))
+	for i, spec := range specs {
+		collections[i] = CollectionInfo{Name: spec.Name, Type: spec.Type}
+	}
+	return collections, nil
+}
+
+// ValidateDatabase checks that a database is accessible by listing its collections.
+// MongoDB lists a database that does not exist as empty rather than failing, so an
+// empty database must also appear in the database list.
+func (c *Client) ValidateDatabase(ctx context.Context, dbName string) error {
+	collections, err := c.ListCollections(ctx, dbName)
+	if err != nil {
+		return err
+	}
+	if len(collections) > 0 {
+		return nil
+	}
+
+	databases, err := c.ListDatabases(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range databases {
+		if name == dbName {
+			return nil
+		}
+	}
+	return fmt.Errorf("database %q does not exist", dbName)
+}
+
 // GetCollection returns a collection from the specified database
 func (c *Client) GetCollection(dbName, collectionName string) (*mongo.Collection, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -227,8 +348,14 @@ func (c *Client) Close(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// Close always wins over pins and keep-alive windows
+	c.pins = 0
+	c.keepUntil = time.Time{}
+
 	if c.client != nil {
-		return c.client.Disconnect(ctx)
+		err := c.client.Disconnect(ctx)
+		c.client = nil
+		return err
 	}
 	return nil
 }
@@ -239,5 +366,5 @@ func (c *Client) Ping(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	return client.Ping(ctx, nil)
+	return c.redactError(client.Ping(ctx, nil))
 }
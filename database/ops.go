@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CurrentOps returns the active client operations reported by the currentOp command.
+// Idle connections, internal threads and operations on the admin, config and local
+// databases are filtered out by the server.
+func (c *Client) CurrentOps(ctx context.Context) ([]bson.M, error) {
+	client, err := c.GetConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	command := bson.D{
+		{Key: "currentOp", Value: 1},
+		{Key: "active", Value: true},
+		{Key: "op", Value: bson.M{"$ne": "none"}},
+		{Key: "ns", Value: bson.M{"$not": primitive.Regex{Pattern: `^(admin|config|local)\.`}}},
+	}
+
+	var result struct {
+		InProg []bson.M `bson:"inprog"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, command).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to run currentOp: %w", err)
+	}
+	return result.InProg, nil
+}
+
+// KillOp asks the server to terminate an operation. opid is a number on mongod and a
+// "shard:opid" string on mongos. The operation stops at its next interrupt point.
+func (c *Client) KillOp(ctx context.Context, opid interface{}) error {
+	client, err := c.GetConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	command := bson.D{{Key: "killOp", Value: 1}, {Key: "op", Value: opid}}
+	if err := client.Database("admin").RunCommand(ctx, command).Err(); err != nil {
+		return fmt.Errorf("failed to run killOp: %w", err)
+	}
+	return nil
+}
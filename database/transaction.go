@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTransaction runs fn in a multi-document transaction on a new session. The driver
+// retries fn on transient transaction errors, so fn must be safe to run more than once.
+// Transactions need a replica set or sharded cluster; a standalone server rejects them.
+func (c *Client) WithTransaction(ctx context.Context, fn func(sc mongo.SessionContext) (interface{}, error)) (interface{}, error) {
+	client, err := c.GetConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	return session.WithTransaction(ctx, fn)
+}
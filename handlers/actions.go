@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DataAPIAction describes one Data API action. The same list registers the action
+// routes and builds the action catalog, so the catalog cannot drift from the routes.
+type DataAPIAction struct {
+	Name    string           // Action name, the last segment of the route
+	Write   bool             // Whether the action mutates data and needs API_SECRET
+	Handler echo.HandlerFunc // Handler serving the action
+	Request interface{}      // Zero value of the request body type, described by the catalog
+}
+
+// Actions lists every Data API action, reads first, in registration order
+func (h *DataAPIHandler) Actions() []DataAPIAction {
+	return []DataAPIAction{
+		{Name: "findOne", Handler: h.FindOne, Request: FindOneRequest{}},
+		{Name: "exists", Handler: h.Exists, Request: ExistsRequest{}},
+		{Name: "find", Handler: h.Find, Request: FindRequest{}},
+		{Name: "multiFind", Handler: h.MultiFind, Request: MultiFindRequest{}},
+		{Name: "aggregate", Handler: h.Aggregate, Request: AggregateRequest{}},
+		{Name: "distinct", Handler: h.Distinct, Request: DistinctRequest{}},
+		{Name: "timeBucket", Handler: h.TimeBucket, Request: TimeBucketRequest{}},
+		{Name: "summarize", Handler: h.Summarize, Request: SummarizeRequest{}},
+
+		{Name: "insertOne", Write: true, Handler: h.InsertOne, Request: InsertOneRequest{}},
+		{Name: "insertMany", Write: true, Handler: h.InsertMany, Request: InsertManyRequest{}},
+		{Name: "updateOne", Write: true, Handler: h.UpdateOne, Request: UpdateOneRequest{}},
+		{Name: "updateMany", Write: true, Handler: h.UpdateMany, Request: UpdateManyRequest{}},
+		{Name: "updateBulk", Write: true, Handler: h.UpdateBulk, Request: UpdateBulkRequest{}},
+		{Name: "deleteOne", Write: true, Handler: h.DeleteOne, Request: DeleteOneRequest{}},
+		{Name: "deleteMany", Write: true, Handler: h.DeleteMany, Request: DeleteManyRequest{}},
+		{Name: "transaction", Write: true, Handler: h.Transaction, Request: TransactionRequest{}},
+	}
+}
+
+// ActionField describes one field of an action's request body
+type ActionField struct {
+	Name     string        `json:"name" example:"filter"`                   // JSON field name
+	Type     string        `json:"type" example:"object"`                   // string, integer, number, boolean, object or array
+	Required bool          `json:"required" example:"false"`                // Whether the field must be present
+	Items    string        `json:"items,omitempty" example:"object"`        // Element type, for arrays
+	Fields   []ActionField `json:"fields,omitempty"`                        // Fields of each element or nested object, when they are fixed
+	Example  string        `json:"example,omitempty" example:"activeUsers"` // Example value, when one is documented
+}
+
+// ActionDescription describes one Data API action in the catalog
+type ActionDescription struct {
+	Name   string        `json:"name" example:"find"`   // Action name, posted to /v1/data-api/action/{name}
+	Access string        `json:"access" example:"read"` // read (API_SECRET or READONLY_API_SECRET) or write (API_SECRET only)
+	Fields []ActionField `json:"fields"`                // Request body fields
+}
+
+// ListActions godoc
+//
+//	@Summary		List Data API actions
+//	@Description	Returns a machine-readable catalog of the Data API actions with their request fields, types and whether each field is required. Derived from the same registry that registers the action routes.
+//	@Tags			data-api
+//	@Produce		json
+//	@Success		200	{object}	map[string][]ActionDescription	"Action catalog"
+//	@Router			/v1/data-api/actions [get]
+func (h *DataAPIHandler) ListActions(c echo.Context) error {
+	actions := h.Actions()
+	catalog := make([]ActionDescription, len(actions))
+	for i, action := range actions {
+		access := "read"
+		if action.Write {
+			access = "write"
+		}
+		catalog[i] = ActionDescription{
+			Name:   action.Name,
+			Access: access,
+			Fields: describeFields(reflect.TypeOf(action.Request)),
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"actions": catalog,
+	})
+}
+
+// describeFields lists the JSON fields of a request struct. Embedded structs such as
+// baseRequest contribute their fields in place; fields without omitempty are required.
+func describeFields(t reflect.Type) []ActionField {
+	fields := []ActionField{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, describeFields(sf.Type)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		field := ActionField{
+			Name:     name,
+			Required: !strings.Contains(options, "omitempty"),
+			Example:  sf.Tag.Get("example"),
+		}
+		if swaggerType := sf.Tag.Get("swaggertype"); swaggerType != "" {
+			// Declared types win, e.g. interface{} filters documented as objects
+			field.Type, field.Items, _ = strings.Cut(swaggerType, ",")
+		} else {
+			field.Type, field.Items, field.Fields = describeType(sf.Type)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// describeType maps a Go type to its JSON type, the element type of arrays and the
+// fields of structs (directly or as array elements)
+func describeType(t reflect.Type) (typ, items string, fields []ActionField) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string", "", nil
+	case reflect.Bool:
+		return "boolean", "", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", "", nil
+	case reflect.Float32, reflect.Float64:
+		return "number", "", nil
+	case reflect.Slice, reflect.Array:
+		items, _, fields = describeType(t.Elem())
+		return "array", items, fields
+	case reflect.Struct:
+		return "object", "", describeFields(t)
+	default:
+		return "object", "", nil
+	}
+}
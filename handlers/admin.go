@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CurrentOp summarizes one operation running on MongoDB
+type CurrentOp struct {
+	OpID        interface{} `json:"opid" swaggertype:"string" example:"12345"` // Operation ID to pass to kill; a number on mongod, "shard:opid" on mongos
+	Namespace   string      `json:"ns" example:"mydb.users"`                   // Database and collection the operation runs on
+	Op          string      `json:"op" example:"query"`                        // Operation type: query, getmore, insert, update, remove, command
+	SecsRunning int64       `json:"secs_running" example:"42"`                 // Seconds the operation has been running
+}
+
+// CurrentOpsResponse represents the response for listing active operations
+type CurrentOpsResponse struct {
+	Operations []CurrentOp `json:"operations"`        // Active operations, longest-running first
+	Count      int         `json:"count" example:"3"` // Number of operations
+}
+
+// CurrentOps godoc
+//
+//	@Summary		List active MongoDB operations
+//	@Description	Runs currentOp and returns the active operations outside the admin, config and local databases, longest-running first. Requires ADMIN_API_SECRET.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	CurrentOpsResponse	"Successfully listed operations"
+//	@Failure		401	{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403	{object}	map[string]string	"Forbidden - not the admin api-key, or the MongoDB user lacks the inprog privilege"
+//	@Failure		500	{object}	map[string]string	"Internal server error"
+//	@Router			/v1/admin/current-ops [get]
+func (h *MongoHandler) CurrentOps(c echo.Context) error {
+	ctx, cancel := operationContext(h.cfg, "currentOps", 10*time.Second)
+	defer cancel()
+
+	inprog, err := h.dbClient.CurrentOps(ctx)
+	if err != nil {
+		if isUnauthorized(err) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "The proxy's MongoDB user is not authorized to list operations (requires the inprog privilege): " + err.Error(),
+			})
+		}
+		return serverError(c, err, err.Error())
+	}
+
+	operations := make([]CurrentOp, 0, len(inprog))
+	for _, op := range inprog {
+		ns, _ := op["ns"].(string)
+		opType, _ := op["op"].(string)
+		operations = append(operations, CurrentOp{
+			OpID:        op["opid"],
+			Namespace:   ns,
+			Op:          opType,
+			SecsRunning: int64(toFloat64(op["secs_running"])),
+		})
+	}
+	sort.SliceStable(operations, func(i, j int) bool {
+		return operations[i].SecsRunning > operations[j].SecsRunning
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"operations": operations,
+		"count":      len(operations),
+	})
+}
+
+// KillOp godoc
+//
+//	@Summary		Kill a MongoDB operation
+//	@Description	Runs killOp for an operation listed by current-ops. The operation stops at its next interrupt point; MongoDB reports success even when the opid no longer exists. Requires ADMIN_API_SECRET.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			opid	path		string				true	"Operation ID: a number on mongod, shard:opid on mongos"	example("12345")
+//	@Success		200		{object}	map[string]interface{}	"Kill requested"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid opid"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - not the admin api-key, or the MongoDB user lacks the killop privilege"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/admin/current-ops/{opid} [delete]
+func (h *MongoHandler) KillOp(c echo.Context) error {
+	opid, ok := parseOpID(c.Param("opid"))
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "opid must be a number, or shard:number on mongos",
+		})
+	}
+
+	ctx, cancel := operationContext(h.cfg, "killOp", 10*time.Second)
+	defer cancel()
+
+	if err := h.dbClient.KillOp(ctx, opid); err != nil {
+		if isUnauthorized(err) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "The proxy's MongoDB user is not authorized to kill operations (requires the killop privilege): " + err.Error(),
+			})
+		}
+		return serverError(c, err, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"opid":    opid,
+		"message": "Kill requested",
+	})
+}
+
+// parseOpID accepts a mongod opid ("12345") or a mongos one ("shard01:12345")
+func parseOpID(raw string) (interface{}, bool) {
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n, true
+	}
+	shard, n, found := strings.Cut(raw, ":")
+	if !found || shard == "" {
+		return nil, false
+	}
+	if _, err := strconv.ParseUint(n, 10, 64); err != nil {
+		return nil, false
+	}
+	return raw, true
+}
+
+// isUnauthorized reports whether MongoDB refused an operation for lack of privileges
+func isUnauthorized(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(unauthorizedCode)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"mongodb-go-proxy/config"
+	"mongodb-go-proxy/database"
+)
+
+// secretFingerprint identifies a secret without revealing it: the first 12 hex digits of
+// its SHA-256, enough to tell which of several known keys is configured. Unset secrets
+// stay empty.
+func secretFingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// EffectiveConfig renders the configuration keyed by environment variable, with the api
+// secrets replaced by fingerprints and the credentials stripped from the MongoDB URIs. Values are
+// the ones in effect after defaults were applied.
+func EffectiveConfig(cfg *config.Config) map[string]interface{} {
+	timeouts := make(map[string]string, len(cfg.Timeouts))
+	for action, timeout := range cfg.Timeouts {
+		timeouts[action] = timeout.String()
+	}
+
+	keyLimits := make(map[string]interface{}, len(cfg.KeyLimits))
+	for tier, limits := range cfg.KeyLimits {
+		keyLimits[tier] = map[string]interface{}{
+			"maxLimit":            limits.MaxLimit,
+			"maxSkip":             limits.MaxSkip,
+			"maxAggregateResults": limits.MaxAggregateResults,
+			"maxInflight":         limits.MaxInflight,
+			"reject":              limits.Reject,
+		}
+	}
+
+	transforms := make(map[string]interface{}, len(cfg.WriteTransforms))
+	for namespace, fields := range cfg.WriteTransforms {
+		rendered := make(map[string][]interface{}, len(fields))
+		for field, steps := range fields {
+			for _, step := range steps {
+				if step.Op == config.TransformDefault {
+					rendered[field] = append(rendered[field], map[string]interface{}{step.Op: step.Value})
+				} else {
+					rendered[field] = append(rendered[field], step.Op)
+				}
+			}
+		}
+		transforms[namespace] = rendered
+	}
+
+	return map[string]interface{}{
+		"MONGO_URI":                     database.RedactURI(cfg.MongoURI),
+		"MONGO_URI_FALLBACK":            database.RedactURI(cfg.MongoURIFallback),
+		"API_SECRET":                    secretFingerprint(cfg.APISecret),
+		"READONLY_API_SECRET":           secretFingerprint(cfg.ReadOnlyAPISecret),
+		"ADMIN_API_SECRET":              secretFingerprint(cfg.AdminAPISecret),
+		"PORT":                          cfg.ServerPort,
+		"MONGO_DATABASE":                cfg.Database,
+		"VALIDATE_DEFAULT_DB":           cfg.ValidateDefaultDatabase,
+		"HIDDEN_DATABASES":              cfg.HiddenDatabases,
+		"ALLOWED_DATABASES":             cfg.AllowedDatabases,
+		"TIMEOUTS":                      timeouts,
+		"LATENCY_WARNING_PERCENT":       cfg.LatencyWarningPercent,
+		"SLOW_QUERY_THRESHOLD":          cfg.SlowQueryThreshold.String(),
+		"EXPLAIN_SLOW_QUERIES":          cfg.ExplainSlowQueries,
+		"MAX_DISTINCT_VALUES":           cfg.MaxDistinctValues,
+		"MAX_AGGREGATE_RESULTS":         cfg.MaxAggregateResults,
+		"MAX_RETURN_IDS":                cfg.MaxReturnIDs,
+		"MAX_FILTER_OPS":                cfg.MaxFilterOps,
+		"MAX_FILTER_DEPTH":              cfg.MaxFilterDepth,
+		"AGGREGATE_WRITE_ALLOWLIST":     cfg.AggregateWriteAllowlist,
+		"AGGREGATE_STAGE_CAPS":          cfg.AggregateStageCaps,
+		"COALESCE_READS":                cfg.CoalesceReads,
+		"STRICT_COLLECTIONS":            cfg.StrictCollections,
+		"SORT_TIEBREAKER":               cfg.SortTiebreaker,
+		"ECHO_NAMESPACE":                cfg.EchoNamespace,
+		"UTC_DATES":                     cfg.UTCDates,
+		"READ_ONLY_MODE":                cfg.ReadOnlyMode,
+		"DELETE_CONFIRMATION":           cfg.DeleteConfirmation,
+		"DELETE_CONFIRMATION_TTL":       cfg.DeleteConfirmationTTL.String(),
+		"REQUIRE_FILTER_ON_DESTRUCTIVE": cfg.RequireFilterOnDestructive,
+		"LIST_EXCLUDED_FIELDS":          cfg.ListExcludedFields,
+		"DEFAULTS":                      cfg.Defaults,
+		"WRITE_TRANSFORMS":              transforms,
+		"ID_FORMATS":                    cfg.IDFormats,
+		"WRITABLE_FIELDS":               cfg.WritableFields,
+		"WRITABLE_FIELDS_MODE":          cfg.WritableFieldsMode,
+		"SAVED_FILTERS":                 cfg.SavedFilters,
+		"KEY_LIMITS":                    keyLimits,
+		"ROW_FILTERS":                   cfg.RowFilters,
+		"KEY_MAX_INFLIGHT":              cfg.KeyMaxInflight,
+		"VERSION_FIELD":                 cfg.VersionField,
+		"METRICS_NAMESPACES":            cfg.MetricsNamespaces,
+		"METHOD_OVERRIDE_METHODS":       cfg.MethodOverrideMethods,
+		"MAX_INFLIGHT":                  cfg.MaxInflight,
+		"USAGE_ACCOUNTING":              cfg.UsageAccounting,
+		"SHUTDOWN_TIMEOUT_MS":           cfg.ShutdownTimeout.Milliseconds(),
+		"MONGO_CLOSE_TIMEOUT_MS":        cfg.MongoCloseTimeout.Milliseconds(),
+		"MAX_IMPORT_JOBS":               cfg.MaxImportJobs,
+		"IMPORT_SPOOL_DIR":              cfg.ImportSpoolDir,
+		"IMPORT_JOB_TTL":                cfg.ImportJobTTL.String(),
+		"CIRCUIT_BREAKER_THRESHOLD":     cfg.CircuitBreakerThreshold,
+		"CIRCUIT_BREAKER_WINDOW":        cfg.CircuitBreakerWindow.String(),
+		"CIRCUIT_BREAKER_COOLDOWN":      cfg.CircuitBreakerCooldown.String(),
+		"DEGRADED_ERROR_RATE":           cfg.DegradedErrorRate,
+		"ERROR_RATE_WINDOW":             cfg.ErrorRateWindow.String(),
+		"ERROR_RATE_MIN_REQUESTS":       cfg.ErrorRateMinRequests,
+	}
+}
+
+// Config godoc
+//
+//	@Summary		Show the effective configuration
+//	@Description	Returns the configuration in effect, keyed by environment variable and with defaults applied. API secrets are replaced by SHA-256 fingerprints and MONGO_URI and MONGO_URI_FALLBACK have their credentials removed. Requires ADMIN_API_SECRET.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]interface{}	"Effective configuration"
+//	@Failure		401	{object}	map[string]string		"Unauthorized - missing or invalid api-key"
+//	@Failure		403	{object}	map[string]string		"Forbidden - not the admin api-key"
+//	@Router			/v1/admin/config [get]
+func (h *MongoHandler) Config(c echo.Context) error {
+	return c.JSON(http.StatusOK, EffectiveConfig(h.cfg))
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// affectedIDs collects the _id of the documents matching filter with a projection-only
+// find, for writes that return their affected ids. It runs just before the write, so a
+// document changed in between is reported wrongly. At most max ids are returned (0 for no
+// cap) and truncated is set when more documents matched.
+func affectedIDs(ctx context.Context, collection *mongo.Collection, filter bson.M, max int, comment string) ([]interface{}, bool, error) {
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1}).SetComment(comment)
+	if max > 0 {
+		findOptions.SetLimit(int64(max) + 1)
+	}
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := []interface{}{}
+	for cursor.Next(ctx) {
+		if max > 0 && len(ids) == max {
+			return ids, true, nil
+		}
+		var document struct {
+			ID interface{} `bson:"_id"`
+		}
+		if err := cursor.Decode(&document); err != nil {
+			return nil, false, err
+		}
+		if oid, ok := document.ID.(primitive.ObjectID); ok {
+			document.ID = oid.Hex()
+		}
+		ids = append(ids, document.ID)
+	}
+	return ids, false, cursor.Err()
+}
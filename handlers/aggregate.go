@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/config"
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// AggregateRequest represents the request for aggregate action
+//
+//	@Description	Request body for aggregate action. Pipeline is an array of MongoDB aggregation stages (Extended JSON).
+type AggregateRequest struct {
+	baseRequest
+	Pipeline []json.RawMessage `json:"pipeline" swaggertype:"array,object"` // Aggregation stages (required). Example: [{"$match":{"status":"active"}},{"$group":{"_id":"$country","n":{"$sum":1}}}]
+	Skip     *int64            `json:"skip,omitempty" example:"0"`          // Documents to skip, appended as a $skip stage after the pipeline (optional)
+	Limit    *int64            `json:"limit,omitempty" example:"100"`       // Documents to return, appended as a $limit stage after the pipeline (optional, 0 for none)
+}
+
+// AggregateResponse represents the response for aggregate action
+type AggregateResponse struct {
+	Documents []map[string]interface{} `json:"documents" swaggertype:"array,object"` // Pipeline output
+	Truncated bool                     `json:"truncated" example:"false"`            // True when output was cut at MAX_AGGREGATE_RESULTS
+	Capped    bool                     `json:"capped,omitempty" example:"true"`      // True when a stage size was lowered to its AGGREGATE_STAGE_CAPS entry
+	Skip      *int64                   `json:"skip,omitempty" example:"0"`           // Documents skipped, after KEY_LIMITS caps (only when skip was given)
+	Limit     *int64                   `json:"limit,omitempty" example:"100"`        // Documents returned at most, after caps (only when a $limit was appended)
+}
+
+// Aggregate godoc
+//
+//	@Summary		Run an aggregation pipeline
+//	@Description	Runs an aggregation pipeline on the specified collection. When the pipeline does not end with $limit, the output is capped at MAX_AGGREGATE_RESULTS and truncated is set if the cap was hit. $out and $merge are only allowed with API_SECRET, outside READ_ONLY_MODE, into targets listed in AGGREGATE_WRITE_ALLOWLIST for the source collection. $sample, $limit and $bucketAuto sizes above their AGGREGATE_STAGE_CAPS entry are lowered to the cap and capped is set. Top-level skip and limit are appended as $skip and $limit stages after the pipeline.
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		AggregateRequest	true	"Aggregate request"
+//	@Success		200		{object}	AggregateResponse	"Successfully ran pipeline"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid pipeline"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials, or $merge/$out with the read-only api-key, in read-only mode or into a target not allowlisted"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/data-api/action/aggregate [post]
+func (h *DataAPIHandler) Aggregate(c echo.Context) error {
+	var req AggregateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if req.Database == "" || req.Collection == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "database and collection are required",
+		})
+	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "aggregate", req.Database, req.Collection)
+
+	if req.Pipeline == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "pipeline is required",
+		})
+	}
+
+	pipeline, err := buildPipeline(req.Pipeline)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid pipeline: " + err.Error(),
+		})
+	}
+
+	// $merge and $out are only allowed into targets allowlisted for this source collection
+	targets, err := pipelineWriteTargets(pipeline, req.Database)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid pipeline: " + err.Error(),
+		})
+	}
+	// aggregate is a read action, so a writing pipeline must itself hold the write key
+	if len(targets) > 0 {
+		if h.cfg.ReadOnlyMode {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "The proxy is in read-only mode",
+			})
+		}
+		if auth.KeyTier(c) != config.KeyTierFull {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Invalid api-key. Pipelines with $merge or $out require full API access.",
+			})
+		}
+	}
+	source := req.Database + "." + req.Collection
+	for _, target := range targets {
+		if !h.cfg.AllowsAggregateWrite(source, target) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Writing to " + target + " is not allowed from aggregate pipelines on " + source,
+			})
+		}
+	}
+
+	pipeline, err = scopePipeline(h.cfg, c, pipeline)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid pipeline: " + err.Error(),
+		})
+	}
+
+	// Top-level skip and limit page the output. They are appended after the client's
+	// stages, so they see the pipeline's final order, and get the api-key's caps like find.
+	if (req.Skip != nil || req.Limit != nil) && len(targets) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "skip and limit cannot be combined with $merge or $out",
+		})
+	}
+	limits := keyLimits(h.cfg, c)
+	if req.Skip != nil {
+		if *req.Skip < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "skip cannot be negative",
+			})
+		}
+		skip, err := capSkip(limits, *req.Skip)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		req.Skip = &skip
+		if skip > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$skip", Value: skip}})
+		}
+	}
+	var limitStage bson.D
+	if req.Limit != nil {
+		if *req.Limit < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "limit cannot be negative",
+			})
+		}
+		if *req.Limit > 0 {
+			limit, err := capLimit(limits, *req.Limit)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": err.Error(),
+				})
+			}
+			limitStage = bson.D{{Key: "$limit", Value: limit}}
+			pipeline = append(pipeline, limitStage)
+		}
+	}
+
+	// Lower pathological $sample/$limit/$bucketAuto sizes before the server sees them
+	stagesCapped := capStages(h.cfg, pipeline)
+
+	// Cap the output unless the client bounded it with a terminal $limit. One extra
+	// document is requested so we can tell whether the cap actually cut anything off.
+	// Writing pipelines return nothing and must end with $merge/$out, so they are left alone.
+	maxResults := aggregateResultCap(h.cfg, c)
+	limitOutput := maxResults > 0 && len(targets) == 0 && !endsWithLimit(pipeline)
+	if limitOutput {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(maxResults) + 1}})
+	}
+
+	ctx, cancel := operationContext(h.cfg, "aggregate", 30*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	results := []bson.M{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	truncated := false
+	if limitOutput && len(results) > maxResults {
+		results = results[:maxResults]
+		truncated = true
+	}
+	accountDocuments(c, h.cfg, results...)
+
+	response := map[string]interface{}{
+		"documents": results,
+		"truncated": truncated,
+	}
+	if stagesCapped {
+		response["capped"] = true
+	}
+	if req.Skip != nil {
+		response["skip"] = *req.Skip
+	}
+	if limitStage != nil {
+		// Read back from the stage, which AGGREGATE_STAGE_CAPS may have lowered
+		response["limit"] = limitStage[0].Value
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// buildPipeline decodes Extended JSON stages, preserving key order within each stage
+func buildPipeline(stages []json.RawMessage) ([]bson.D, error) {
+	pipeline := make([]bson.D, 0, len(stages))
+	for i, raw := range stages {
+		var stage bson.D
+		if err := bson.UnmarshalExtJSON(raw, false, &stage); err != nil {
+			return nil, fmt.Errorf("stage %d: %w", i, err)
+		}
+		if len(stage) != 1 {
+			return nil, fmt.Errorf("stage %d: must contain exactly one operator", i)
+		}
+		if name := stageName(stage); name == "" || name[0] != '$' {
+			return nil, fmt.Errorf("stage %d: operator must start with '$'", i)
+		}
+		pipeline = append(pipeline, stage)
+	}
+	return pipeline, nil
+}
+
+// stageName returns the operator of a pipeline stage, e.g. "$match"
+func stageName(stage bson.D) string {
+	if len(stage) == 0 {
+		return ""
+	}
+	return stage[0].Key
+}
+
+// endsWithLimit reports whether the last stage of a pipeline is $limit
+func endsWithLimit(pipeline []bson.D) bool {
+	return len(pipeline) > 0 && stageName(pipeline[len(pipeline)-1]) == "$limit"
+}
+
+// boundedStages maps each stage AGGREGATE_STAGE_CAPS can cap to the field holding its
+// size; "" means the stage value itself is the size
+var boundedStages = map[string]string{
+	"$sample":     "size",
+	"$limit":      "",
+	"$bucketAuto": "buckets",
+}
+
+// capStages lowers the size of bounded stages above their AGGREGATE_STAGE_CAPS entry,
+// including in the sub-pipelines of $facet, $lookup and $unionWith. Stages are rewritten
+// in place; it reports whether any was.
+func capStages(cfg *config.Config, pipeline []bson.D) bool {
+	capped := false
+	for _, stage := range pipeline {
+		name := stageName(stage)
+		if field, ok := boundedStages[name]; ok {
+			if limit := cfg.StageCap(name); limit > 0 && capStageSize(stage, field, limit) {
+				capped = true
+			}
+		}
+		for _, nested := range subPipelines(stage) {
+			if capStages(cfg, nested) {
+				capped = true
+			}
+		}
+	}
+	return capped
+}
+
+// capStageSize replaces the size of one stage with limit when it is larger. Non-numeric
+// sizes are left for the server to reject.
+func capStageSize(stage bson.D, field string, limit int64) bool {
+	if field == "" {
+		if toFloat64(stage[0].Value) > float64(limit) {
+			stage[0].Value = limit
+			return true
+		}
+		return false
+	}
+
+	spec, ok := stage[0].Value.(bson.D)
+	if !ok {
+		return false
+	}
+	for i := range spec {
+		if spec[i].Key == field && toFloat64(spec[i].Value) > float64(limit) {
+			spec[i].Value = limit
+			return true
+		}
+	}
+	return false
+}
+
+// subPipelines returns the pipelines nested in a $facet, $lookup or $unionWith stage. The
+// stages share memory with the outer pipeline, so rewriting them rewrites it.
+func subPipelines(stage bson.D) [][]bson.D {
+	spec, ok := stage[0].Value.(bson.D)
+	if !ok {
+		return nil
+	}
+
+	var nested []bson.A
+	switch stageName(stage) {
+	case "$facet":
+		for _, facet := range spec {
+			if stages, ok := facet.Value.(bson.A); ok {
+				nested = append(nested, stages)
+			}
+		}
+	case "$lookup", "$unionWith":
+		if stages, ok := lookupValue(spec, "pipeline").(bson.A); ok {
+			nested = append(nested, stages)
+		}
+	}
+
+	pipelines := make([][]bson.D, 0, len(nested))
+	for _, stages := range nested {
+		pipeline := make([]bson.D, 0, len(stages))
+		for _, item := range stages {
+			if stage, ok := item.(bson.D); ok && len(stage) > 0 {
+				pipeline = append(pipeline, stage)
+			}
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+	return pipelines
+}
+
+// pipelineWriteTargets returns the namespaces ("db.collection") written by $out and $merge
+// stages anywhere in the pipeline, including sub-pipelines of $facet, $lookup and $unionWith
+func pipelineWriteTargets(pipeline []bson.D, database string) ([]string, error) {
+	var targets []string
+	for _, stage := range pipeline {
+		var err error
+		if targets, err = collectWriteTargets(stage, database, targets); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// collectWriteTargets walks a decoded pipeline value and appends every $out/$merge target
+func collectWriteTargets(value interface{}, database string, targets []string) ([]string, error) {
+	var err error
+	switch v := value.(type) {
+	case bson.D:
+		for _, elem := range v {
+			if elem.Key == "$out" || elem.Key == "$merge" {
+				target, err := writeTarget(elem.Key, elem.Value, database)
+				if err != nil {
+					return nil, err
+				}
+				targets = append(targets, target)
+				continue
+			}
+			if targets, err = collectWriteTargets(elem.Value, database, targets); err != nil {
+				return nil, err
+			}
+		}
+	case bson.A:
+		for _, item := range v {
+			if targets, err = collectWriteTargets(item, database, targets); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return targets, nil
+}
+
+// writeTarget resolves the namespace of a $out or $merge stage. Both accept a collection
+// name or a {db, coll} document; $merge nests it under "into".
+func writeTarget(operator string, spec interface{}, database string) (string, error) {
+	into := spec
+	if doc, ok := spec.(bson.D); ok && operator == "$merge" {
+		into = lookupValue(doc, "into")
+	}
+
+	switch t := into.(type) {
+	case string:
+		if t != "" {
+			return database + "." + t, nil
+		}
+	case bson.D:
+		db, _ := lookupValue(t, "db").(string)
+		coll, _ := lookupValue(t, "coll").(string)
+		if db == "" {
+			db = database
+		}
+		if coll != "" {
+			return db + "." + coll, nil
+		}
+	}
+	return "", fmt.Errorf("%s must name a target collection", operator)
+}
+
+// lookupValue returns the value of key in doc, or nil if it is not present
+func lookupValue(doc bson.D, key string) interface{} {
+	for _, elem := range doc {
+		if elem.Key == key {
+			return elem.Value
+		}
+	}
+	return nil
+}
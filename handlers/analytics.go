@@ -0,0 +1,492 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/metrics"
+)
+
+// timeBucketFormats maps supported granularities to their $dateToString format
+var timeBucketFormats = map[string]string{
+	"hour":  "%Y-%m-%dT%H:00:00Z",
+	"day":   "%Y-%m-%d",
+	"month": "%Y-%m",
+}
+
+const (
+	// defaultDistinctSampleSize is the $sample size of an approximate distinct
+	defaultDistinctSampleSize = 10000
+	// maxDistinctSampleSize caps the sampleSize of an approximate distinct
+	maxDistinctSampleSize = 100000
+)
+
+// TimeBucketRequest represents the request for timeBucket action
+//
+//	@Description	Request body for timeBucket action. Counts documents grouped by a truncated date field.
+type TimeBucketRequest struct {
+	baseRequest
+	DateField   string      `json:"dateField" example:"createdAt"`                 // Date field to bucket on (required)
+	Granularity string      `json:"granularity" example:"day"`                     // Bucket size: hour, day or month (required)
+	Filter      interface{} `json:"filter,omitempty" swaggertype:"object"`         // MongoDB filter query (optional). Example: {"status":"active"}
+	From        string      `json:"from,omitempty" example:"2024-01-01T00:00:00Z"` // Inclusive range start, RFC 3339 (optional)
+	To          string      `json:"to,omitempty" example:"2024-02-01T00:00:00Z"`   // Exclusive range end, RFC 3339 (optional)
+}
+
+// TimeBucket represents a single date bucket and its document count
+type TimeBucket struct {
+	Bucket string `json:"bucket" example:"2024-01-15"` // Formatted bucket start
+	Count  int64  `json:"count" example:"42"`          // Number of documents in the bucket
+}
+
+// TimeBucketResponse represents the response for timeBucket action
+type TimeBucketResponse struct {
+	Buckets []TimeBucket `json:"buckets"` // Buckets in chronological order
+}
+
+// TimeBucket godoc
+//
+//	@Summary		Count documents per date bucket
+//	@Description	Groups documents by hour, day or month of a date field and returns the count per bucket
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		TimeBucketRequest	true	"Time bucket request"
+//	@Success		200		{object}	TimeBucketResponse	"Successfully computed buckets"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid granularity, date range or filter"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/data-api/action/timeBucket [post]
+func (h *DataAPIHandler) TimeBucket(c echo.Context) error {
+	var req TimeBucketRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if req.Database == "" || req.Collection == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "database and collection are required",
+		})
+	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "timeBucket", req.Database, req.Collection)
+
+	if req.DateField == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "dateField is required",
+		})
+	}
+
+	format, ok := timeBucketFormats[req.Granularity]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "granularity must be one of hour, day, month",
+		})
+	}
+
+	filter, err := h.buildFilter(req.Filter)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid filter: " + err.Error(),
+		})
+	}
+	filter = scopeFilter(h.cfg, c, filter)
+
+	// Restrict to the requested date range
+	dateRange := bson.M{}
+	var from, to time.Time
+	if req.From != "" {
+		if from, err = time.Parse(time.RFC3339, req.From); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid from: " + err.Error(),
+			})
+		}
+		dateRange["$gte"] = from
+	}
+	if req.To != "" {
+		if to, err = time.Parse(time.RFC3339, req.To); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid to: " + err.Error(),
+			})
+		}
+		dateRange["$lt"] = to
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "from must be before to",
+		})
+	}
+	if len(dateRange) > 0 {
+		filter = bson.M{"$and": bson.A{filter, bson.M{req.DateField: dateRange}}}
+	}
+
+	ctx, cancel := operationContext(h.cfg, "timeBucket", 30*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   format,
+				"date":     "$" + req.DateField,
+				"timezone": "UTC",
+			}},
+			"count": bson.M{"$sum": 1},
+		}},
+		// Documents without a usable date produce a null bucket
+		bson.M{"$match": bson.M{"_id": bson.M{"$ne": nil}}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID    string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	buckets := make([]TimeBucket, len(rows))
+	for i, row := range rows {
+		buckets[i] = TimeBucket{Bucket: row.ID, Count: row.Count}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"buckets": buckets,
+	})
+}
+
+// DistinctRequest represents the request for distinct action
+//
+//	@Description	Request body for distinct action. Returns the distinct values of a field, optionally sorted and limited.
+type DistinctRequest struct {
+	baseRequest
+	Field  string      `json:"field" example:"status"`                // Field to collect distinct values for (required)
+	Filter interface{} `json:"filter,omitempty" swaggertype:"object"` // MongoDB filter query (optional). Example: {"active":true}
+	Sort   string      `json:"sort,omitempty" example:"asc"`          // Sort order of the values: asc or desc (optional, default: unsorted)
+	Limit  *int64      `json:"limit,omitempty" example:"50"`          // Maximum number of values to return (optional)
+
+	Approximate bool   `json:"approximate,omitempty" example:"false"` // Collect values from a random sample instead of scanning every match (optional)
+	SampleSize  *int64 `json:"sampleSize,omitempty" example:"10000"`  // Documents to sample when approximate is set (optional, default 10000, max 100000)
+}
+
+// DistinctResponse represents the response for distinct action
+type DistinctResponse struct {
+	Values    []interface{} `json:"values" swaggertype:"array,string"` // Distinct values
+	Count     int           `json:"count" example:"3"`                 // Number of values returned
+	Truncated bool          `json:"truncated" example:"false"`         // True when values were dropped by limit or MAX_DISTINCT_VALUES
+
+	Approximate bool  `json:"approximate,omitempty" example:"true"` // True when values come from a sample and may be incomplete
+	SampleSize  int64 `json:"sampleSize,omitempty" example:"10000"` // Number of documents sampled
+}
+
+// Distinct godoc
+//
+//	@Summary		Get distinct values of a field
+//	@Description	Returns the distinct values of a field across documents matching the filter. Values can be sorted and limited; results are capped by MAX_DISTINCT_VALUES. With approximate set, values are collected from a $sample of matching documents, which is much faster on large collections but may miss rare values.
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		DistinctRequest		true	"Distinct request"
+//	@Success		200		{object}	DistinctResponse	"Successfully retrieved distinct values"
+//	@Failure		400		{object}	map[string]string	"Bad request - missing field or invalid sort, limit, sampleSize or filter"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/data-api/action/distinct [post]
+func (h *DataAPIHandler) Distinct(c echo.Context) error {
+	var req DistinctRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if req.Database == "" || req.Collection == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "database and collection are required",
+		})
+	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "distinct", req.Database, req.Collection)
+
+	if req.Field == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "field is required",
+		})
+	}
+
+	if req.Sort != "" && req.Sort != "asc" && req.Sort != "desc" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "sort must be asc or desc",
+		})
+	}
+
+	if req.Limit != nil && *req.Limit <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "limit must be positive",
+		})
+	}
+
+	sampleSize := int64(defaultDistinctSampleSize)
+	if req.SampleSize != nil {
+		if !req.Approximate {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "sampleSize requires approximate",
+			})
+		}
+		if *req.SampleSize <= 0 || *req.SampleSize > maxDistinctSampleSize {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("sampleSize must be between 1 and %d", maxDistinctSampleSize),
+			})
+		}
+		sampleSize = *req.SampleSize
+	}
+
+	filter, err := h.buildFilter(req.Filter)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid filter: " + err.Error(),
+		})
+	}
+	filter = scopeFilter(h.cfg, c, filter)
+
+	ctx, cancel := operationContext(h.cfg, "distinct", 30*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	var values []interface{}
+	if req.Approximate {
+		values, err = sampledDistinct(ctx, collection, req.Field, filter, sampleSize, operationComment(c))
+	} else {
+		values, err = collection.Distinct(ctx, req.Field, filter, options.Distinct().SetComment(operationComment(c)))
+	}
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	// Distinct doesn't sort server-side, so order the values here
+	if req.Sort != "" {
+		sortDistinctValues(values, req.Sort == "desc")
+	}
+
+	truncated := false
+	if maxValues := h.cfg.MaxDistinctValues; maxValues > 0 && len(values) > maxValues {
+		log.Printf("distinct on %s.%s.%s returned %d values, capping at %d", req.Database, req.Collection, req.Field, len(values), maxValues)
+		values = values[:maxValues]
+		truncated = true
+	}
+	if req.Limit != nil && int64(len(values)) > *req.Limit {
+		values = values[:*req.Limit]
+		truncated = true
+	}
+
+	response := map[string]interface{}{
+		"values":    values,
+		"count":     len(values),
+		"truncated": truncated,
+	}
+	if req.Approximate {
+		response["approximate"] = true
+		response["sampleSize"] = sampleSize
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// sampledDistinct collects the distinct values of field among a random sample of the
+// documents matching filter. Array values are unwound, as with the distinct command.
+func sampledDistinct(ctx context.Context, collection *mongo.Collection, field string, filter bson.M, sampleSize int64, comment string) ([]interface{}, error) {
+	pipeline := []bson.M{}
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$sample": bson.M{"size": sampleSize}},
+		bson.M{"$project": bson.M{"_id": 0, "value": "$" + field}},
+		bson.M{"$unwind": "$value"},
+		bson.M{"$group": bson.M{"_id": nil, "values": bson.M{"$addToSet": "$value"}}},
+	)
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetComment(comment))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Values []interface{} `bson:"values"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []interface{}{}, nil
+	}
+	return rows[0].Values, nil
+}
+
+// sortDistinctValues orders values the way MongoDB sorts mixed types: by BSON type
+// (null, numbers, strings, documents, arrays, binary data, ObjectIds, booleans, dates,
+// timestamps, regular expressions) and then by value within a type
+func sortDistinctValues(values []interface{}, descending bool) {
+	sort.SliceStable(values, func(i, j int) bool {
+		if descending {
+			return lessDistinctValue(values[j], values[i])
+		}
+		return lessDistinctValue(values[i], values[j])
+	})
+}
+
+// lessDistinctValue compares two distinct values using the ordering of sortDistinctValues
+func lessDistinctValue(a, b interface{}) bool {
+	rankA, rankB := distinctValueRank(a), distinctValueRank(b)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+
+	switch rankA {
+	case 2:
+		return distinctNumber(a) < distinctNumber(b)
+	case 3:
+		return distinctString(a) < distinctString(b)
+	case 6:
+		// MongoDB compares binary data by length, then subtype, then bytes
+		x, y := a.(primitive.Binary), b.(primitive.Binary)
+		if len(x.Data) != len(y.Data) {
+			return len(x.Data) < len(y.Data)
+		}
+		if x.Subtype != y.Subtype {
+			return x.Subtype < y.Subtype
+		}
+		return bytes.Compare(x.Data, y.Data) < 0
+	case 7:
+		x, y := a.(primitive.ObjectID), b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:]) < 0
+	case 8:
+		return !a.(bool) && b.(bool)
+	case 9:
+		return distinctDate(a) < distinctDate(b)
+	case 10:
+		x, y := a.(primitive.Timestamp), b.(primitive.Timestamp)
+		return x.T < y.T || x.T == y.T && x.I < y.I
+	case 4, 5, 11:
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	default:
+		return false
+	}
+}
+
+// distinctValueRank gives the position of a value's type in MongoDB's BSON comparison order
+func distinctValueRank(v interface{}) int {
+	switch v.(type) {
+	case primitive.MinKey:
+		return 0
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.D, bson.M, map[string]interface{}:
+		return 4
+	case bson.A, []interface{}:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime, time.Time:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.MaxKey:
+		return 12
+	default:
+		return 11
+	}
+}
+
+// distinctNumber converts a numeric distinct value, including Decimal128, to float64
+func distinctNumber(v interface{}) float64 {
+	if d, ok := v.(primitive.Decimal128); ok {
+		f, _ := strconv.ParseFloat(d.String(), 64)
+		return f
+	}
+	return toFloat64(v)
+}
+
+// distinctString returns the text of a string or symbol distinct value
+func distinctString(v interface{}) string {
+	if symbol, ok := v.(primitive.Symbol); ok {
+		return string(symbol)
+	}
+	return v.(string)
+}
+
+// distinctDate returns a date distinct value as milliseconds since the epoch
+func distinctDate(v interface{}) int64 {
+	if t, ok := v.(time.Time); ok {
+		return int64(primitive.NewDateTimeFromTime(t))
+	}
+	return int64(v.(primitive.DateTime))
+}
+
+// toFloat64 converts the numeric types returned by the driver to float64
+func toFloat64(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSortDistinctValues(t *testing.T) {
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.FixedZone("", 9*3600))
+	mar := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	early, _ := primitive.ObjectIDFromHex("65a1b2c3d4e5f6a7b8c9d0e1")
+	late, _ := primitive.ObjectIDFromHex("65b000000000000000000000")
+	half, _ := primitive.ParseDecimal128("0.5")
+
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   []interface{}
+	}{
+		{"numbers of mixed types", []interface{}{int64(10), 2.5, int32(3), half}, []interface{}{half, 2.5, int32(3), int64(10)}},
+		{"dates by instant, not by text",
+			[]interface{}{primitive.NewDateTimeFromTime(mar), feb, primitive.NewDateTimeFromTime(jan)},
+			[]interface{}{primitive.NewDateTimeFromTime(jan), feb, primitive.NewDateTimeFromTime(mar)}},
+		{"ObjectIds by value", []interface{}{late, early}, []interface{}{early, late}},
+		{"booleans", []interface{}{true, false}, []interface{}{false, true}},
+		{"timestamps", []interface{}{primitive.Timestamp{T: 2, I: 1}, primitive.Timestamp{T: 1, I: 9}, primitive.Timestamp{T: 2, I: 0}},
+			[]interface{}{primitive.Timestamp{T: 1, I: 9}, primitive.Timestamp{T: 2, I: 0}, primitive.Timestamp{T: 2, I: 1}}},
+		{"binary by length, subtype, then bytes",
+			[]interface{}{primitive.Binary{Subtype: 0, Data: []byte{1, 2}}, primitive.Binary{Subtype: 4, Data: []byte{0}}, primitive.Binary{Subtype: 0, Data: []byte{9}}, primitive.Binary{Subtype: 0, Data: []byte{1, 1}}},
+			[]interface{}{primitive.Binary{Subtype: 0, Data: []byte{9}}, primitive.Binary{Subtype: 4, Data: []byte{0}}, primitive.Binary{Subtype: 0, Data: []byte{1, 1}}, primitive.Binary{Subtype: 0, Data: []byte{1, 2}}}},
+		{"mixed types in BSON order",
+			[]interface{}{primitive.NewDateTimeFromTime(jan), true, late, primitive.Binary{Data: []byte{1}}, bson.A{int32(1)}, bson.D{{Key: "a", Value: int32(1)}}, "b", primitive.Timestamp{T: 1}, int32(1), nil},
+			[]interface{}{nil, int32(1), "b", bson.D{{Key: "a", Value: int32(1)}}, bson.A{int32(1)}, primitive.Binary{Data: []byte{1}}, late, true, primitive.NewDateTimeFromTime(jan), primitive.Timestamp{T: 1}}},
+		{"strings and symbols together", []interface{}{"b", primitive.Symbol("a"), "c"}, []interface{}{primitive.Symbol("a"), "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := append([]interface{}{}, tt.values...)
+			sortDistinctValues(values, false)
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Fatalf("ascending = %v, want %v", values, tt.want)
+			}
+
+			sortDistinctValues(values, true)
+			for i, want := range tt.want {
+				if got := values[len(values)-1-i]; !reflect.DeepEqual(got, want) {
+					t.Fatalf("descending = %v, want the ascending order reversed", values)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/singleflight"
+)
+
+// readCoalescer lets identical concurrent single-document reads share one MongoDB round
+// trip. Nothing is cached: once the shared call returns, its key is forgotten, so errors
+// only reach the requests that were already waiting on that call.
+type readCoalescer struct {
+	enabled bool
+	group   singleflight.Group
+}
+
+// findOne runs FindOne, joining an identical call already in flight when coalescing is
+// enabled. A shared document may be handed to several requests and must not be modified.
+// timeout bounds a shared call, which runs detached from the ctx of the request that
+// started it.
+func (r *readCoalescer) findOne(ctx context.Context, collection *mongo.Collection, filter interface{}, opts *options.FindOneOptions, timeout time.Duration) (bson.M, error) {
+	find := func(ctx context.Context) (bson.M, error) {
+		var doc bson.M
+		err := collection.FindOne(ctx, filter, opts).Decode(&doc)
+		return doc, err
+	}
+
+	if !r.enabled {
+		return find(ctx)
+	}
+
+	key, err := readKey(collection, filter, opts)
+	if err != nil {
+		// An unkeyable query still works, it just isn't shared
+		return find(ctx)
+	}
+	return r.do(ctx, key, timeout, find)
+}
+
+// do runs find once for all concurrent callers with the same key. The shared call must not
+// fail every joined request when the one that started it gives up, so it runs under a
+// context without that caller's cancellation and with a timeout of its own. Each caller
+// still stops waiting when its own ctx ends.
+func (r *readCoalescer) do(ctx context.Context, key string, timeout time.Duration, find func(ctx context.Context) (bson.M, error)) (bson.M, error) {
+	results := r.group.DoChan(key, func() (interface{}, error) {
+		shared, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		return find(shared)
+	})
+
+	select {
+	case res := <-results:
+		return res.Val.(bson.M), res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// readKey identifies a FindOne by client, namespace, filter, sort and projection. The client
+// keeps a read sent to the fallback from joining the same read on the primary. Documents
+// are canonicalized so key order in the request does not matter; values keep their BSON types.
+func readKey(collection *mongo.Collection, filter interface{}, opts *options.FindOneOptions) (string, error) {
+	query := bson.D{
+		{Key: "client", Value: fmt.Sprintf("%p", collection.Database().Client())},
+		{Key: "ns", Value: collection.Database().Name() + "." + collection.Name()},
+		{Key: "filter", Value: canonicalValue(filter)},
+	}
+	if opts != nil {
+		query = append(query,
+			bson.E{Key: "sort", Value: opts.Sort},
+			bson.E{Key: "projection", Value: canonicalValue(opts.Projection)},
+		)
+	}
+
+	raw, err := bson.MarshalExtJSON(query, true, false)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestReadCoalescerOutlivesFirstCaller cancels the request that started a shared read while
+// another one waits on it. The shared call keeps running and answers the one still waiting.
+func TestReadCoalescerOutlivesFirstCaller(t *testing.T) {
+	r := &readCoalescer{enabled: true}
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	find := func(ctx context.Context) (bson.M, error) {
+		calls.Add(1)
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return bson.M{"_id": 1}, nil
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := r.do(firstCtx, "key", time.Minute, find)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan bson.M, 1)
+	go func() {
+		doc, err := r.do(context.Background(), "key", time.Minute, find)
+		if err != nil {
+			t.Errorf("joined read: %v", err)
+		}
+		second <- doc
+	}()
+	// Give the second read time to join the call in flight
+	time.Sleep(20 * time.Millisecond)
+
+	cancelFirst()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first read = %v, want it to stop at its own cancellation", err)
+	}
+	close(release)
+
+	if doc := <-second; doc["_id"] != 1 {
+		t.Fatalf("joined read got %v, want the shared document", doc)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("find ran %d times, want once", n)
+	}
+}
+
+func TestReadCoalescerSharedTimeout(t *testing.T) {
+	r := &readCoalescer{enabled: true}
+	find := func(ctx context.Context) (bson.M, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if _, err := r.do(context.Background(), "key", 10*time.Millisecond, find); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the shared call to end at its timeout", err)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	connect := func() *mongo.Client {
+		// Connect does not reach out to the server, so no MongoDB is needed
+		client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+		if err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		t.Cleanup(func() { client.Disconnect(context.Background()) })
+		return client
+	}
+	primary, fallback := connect(), connect()
+
+	key := func(client *mongo.Client, filter bson.M) string {
+		k, err := readKey(client.Database("mydb").Collection("users"), filter, options.FindOne())
+		if err != nil {
+			t.Fatalf("readKey: %v", err)
+		}
+		return k
+	}
+	filter := bson.M{"status": "active", "age": bson.M{"$gte": 30, "$lt": 40}}
+
+	if key(primary, filter) != key(primary, filter) {
+		t.Error("the same read on the same client got different keys")
+	}
+	if key(primary, filter) == key(fallback, filter) {
+		t.Error("the same read on the primary and the fallback share a key")
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// pendingDelete is a deleteMany waiting for its confirmation token
+type pendingDelete struct {
+	binding string
+	expires time.Time
+}
+
+// deleteConfirmations holds the tokens issued by the first call of a two-phase deleteMany.
+// A token is bound to the namespace and filter it was issued for, expires after ttl and
+// can be redeemed once. Tokens live in memory, so they do not survive a restart and are
+// not shared between proxy instances.
+type deleteConfirmations struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	pending map[string]pendingDelete
+}
+
+// issue creates a token for binding, dropping expired ones on the way
+func (d *deleteConfirmations) issue(binding string) (string, time.Time, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(b[:])
+
+	now := time.Now()
+	expires := now.Add(d.ttl)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pending == nil {
+		d.pending = make(map[string]pendingDelete)
+	}
+	for t, p := range d.pending {
+		if now.After(p.expires) {
+			delete(d.pending, t)
+		}
+	}
+	d.pending[token] = pendingDelete{binding: binding, expires: expires}
+	return token, expires, nil
+}
+
+// redeem consumes token if it is unexpired and was issued for binding. A token presented
+// with a different filter is left in place, so a mistaken call does not burn it.
+func (d *deleteConfirmations) redeem(token, binding string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.pending[token]
+	if !ok || p.binding != binding {
+		return false
+	}
+	delete(d.pending, token)
+	return !time.Now().After(p.expires)
+}
+
+// deleteBinding identifies a deleteMany by namespace and filter. The filter is
+// canonicalized so key order does not matter; values keep their BSON types.
+func deleteBinding(database, collection string, filter bson.M) (string, error) {
+	query := bson.D{
+		{Key: "ns", Value: database + "." + collection},
+		{Key: "filter", Value: canonicalValue(filter)},
+	}
+
+	raw, err := bson.MarshalExtJSON(query, true, false)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"mongodb-go-proxy/config"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// LatencyWarningHeader flags a read that used most of its timeout
+const LatencyWarningHeader = "X-Latency-Warning"
+
+// operationStartKey is the context key under which operationContext records when the
+// timeout of an operation started
+type operationStartKey struct{}
+
+// operationContext creates the context for a MongoDB operation. The timeout comes from
+// the TIMEOUTS entry for the action when configured, otherwise from the handler's fallback.
+func operationContext(cfg *config.Config, action string, fallback time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.WithValue(context.Background(), operationStartKey{}, time.Now())
+	return context.WithTimeout(ctx, cfg.Timeout(action, fallback))
+}
+
+// flagSlowRead sets X-Latency-Warning when the read running under ctx, a context from
+// operationContext, has used more than LATENCY_WARNING_PERCENT of its timeout. It must
+// run before the response is written.
+func flagSlowRead(ctx context.Context, c echo.Context, cfg *config.Config) {
+	if cfg.LatencyWarningPercent <= 0 {
+		return
+	}
+	started, ok := ctx.Value(operationStartKey{}).(time.Time)
+	deadline, hasDeadline := ctx.Deadline()
+	if !ok || !hasDeadline {
+		return
+	}
+	budget := deadline.Sub(started)
+	if time.Since(started)*100 > budget*time.Duration(cfg.LatencyWarningPercent) {
+		c.Response().Header().Set(LatencyWarningHeader, "true")
+	}
+}
+
+// streamContext is operationContext for streaming endpoints. The operation is also
+// cancelled as soon as the client of c disconnects, so a batch or cursor feeding the
+// stream stops promptly instead of running until the next write to the client fails.
+// Every streaming handler should use it for the MongoDB work behind its response.
+func streamContext(c echo.Context, cfg *config.Config, action string, fallback time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := operationContext(cfg, action, fallback)
+	stop := context.AfterFunc(c.Request().Context(), cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// operationComment is the comment attached to the MongoDB operations of a request: its
+// X-Request-ID, so profiler entries and currentOp can be matched with the request log
+func operationComment(c echo.Context) string {
+	return auth.RequestID(c)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"mongodb-go-proxy/config"
+)
+
+// TestStreamContextCancelledOnDisconnect starts a streamed response, drops the client
+// connection mid-stream and expects the operation context to be cancelled long before
+// its timeout
+func TestStreamContextCancelledOnDisconnect(t *testing.T) {
+	cfg := &config.Config{}
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	e := echo.New()
+	e.GET("/stream", func(c echo.Context) error {
+		ctx, cancel := streamContext(c, cfg, "insertStream", time.Minute)
+		defer cancel()
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+		res.WriteHeader(http.StatusOK)
+		res.Write([]byte("{\"done\":false}\n"))
+		res.Flush()
+		close(started)
+
+		// Stands in for a batch or cursor still running when the client leaves
+		select {
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+		case <-time.After(5 * time.Second):
+			cancelled <- nil
+		}
+		return nil
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	reqCtx, disconnect := context.WithCancel(context.Background())
+	defer disconnect()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+"/stream", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer res.Body.Close()
+
+	<-started
+	disconnect()
+
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("operation context error = %v, want %v after the client disconnected", err, context.Canceled)
+	}
+}
+
+// TestStreamContextKeepsTimeout checks the operation still gets its own timeout while the
+// client stays connected
+func TestStreamContextKeepsTimeout(t *testing.T) {
+	cfg := &config.Config{Timeouts: map[string]time.Duration{"insertStream": 20 * time.Millisecond}}
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+
+	ctx, cancel := streamContext(c, cfg, "insertStream", time.Minute)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			t.Fatalf("operation context error = %v, want %v", ctx.Err(), context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the TIMEOUTS entry for the action was not applied")
+	}
+}
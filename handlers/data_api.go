@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -11,18 +13,30 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"mongodb-go-proxy/config"
 	"mongodb-go-proxy/database"
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
 )
 
 // DataAPIHandler handles MongoDB Data API format requests
 type DataAPIHandler struct {
-	dbClient *database.Client
+	dbClient      *database.Client
+	cfg           *config.Config
+	reads         *readCoalescer
+	clients       readClients
+	confirmations *deleteConfirmations
 }
 
-// NewDataAPIHandler creates a new Data API handler
-func NewDataAPIHandler(dbClient *database.Client) *DataAPIHandler {
+// NewDataAPIHandler creates a new Data API handler. fallbackClient serves find and
+// findOne when dbClient cannot and may be nil.
+func NewDataAPIHandler(dbClient, fallbackClient *database.Client, cfg *config.Config) *DataAPIHandler {
 	return &DataAPIHandler{
-		dbClient: dbClient,
+		dbClient:      dbClient,
+		cfg:           cfg,
+		reads:         &readCoalescer{enabled: cfg.CoalesceReads},
+		clients:       readClients{cfg: cfg, primary: dbClient, fallback: fallbackClient},
+		confirmations: &deleteConfirmations{ttl: cfg.DeleteConfirmationTTL},
 	}
 }
 
@@ -46,6 +60,7 @@ type InsertOneRequest struct {
 type InsertManyRequest struct {
 	baseRequest
 	Documents []map[string]interface{} `json:"documents" swaggertype:"array,object"` // Array of documents to insert (required). Example: [{"name":"John"},{"name":"Jane"}]
+	Ordered   *bool                    `json:"ordered,omitempty" example:"true"`     // Stop at the first failed document (default true). Set false to insert every valid document
 }
 
 // FindOneRequest represents the request for findOne action
@@ -55,7 +70,7 @@ type FindOneRequest struct {
 	baseRequest
 	Filter     interface{} `json:"filter,omitempty" swaggertype:"object"`     // MongoDB filter query (optional). Example: {"name":"John"}
 	Sort       interface{} `json:"sort,omitempty" swaggertype:"object"`       // Sort criteria (optional). Example: {"name":1}
-	Projection interface{} `json:"projection,omitempty" swaggertype:"object"` // Fields to include/exclude (optional). Example: {"name":1,"age":1}
+	Projection interface{} `json:"projection,omitempty" swaggertype:"object"` // Fields to include/exclude (optional). Supports $slice. Example: {"name":1,"comments":{"$slice":[0,10]}}
 }
 
 // FindRequest represents the request for find action
@@ -63,11 +78,38 @@ type FindOneRequest struct {
 //	@Description	Request body for find action. Filter, sort, and projection are MongoDB query objects.
 type FindRequest struct {
 	baseRequest
-	Filter     interface{} `json:"filter,omitempty" swaggertype:"object"`     // MongoDB filter query (optional). Example: {"name":"John"}
-	Sort       interface{} `json:"sort,omitempty" swaggertype:"object"`       // Sort criteria (optional). Example: {"name":1}
-	Limit      *int64      `json:"limit,omitempty" example:"100"`             // Maximum number of documents to return (optional, default: 100)
-	Skip       *int64      `json:"skip,omitempty" example:"0"`                // Number of documents to skip (optional, default: 0)
-	Projection interface{} `json:"projection,omitempty" swaggertype:"object"` // Fields to include/exclude (optional). Example: {"name":1,"age":1}
+	Filter      interface{}            `json:"filter,omitempty" swaggertype:"object"`       // MongoDB filter query (optional). Example: {"name":"John"}
+	Sort        interface{}            `json:"sort,omitempty" swaggertype:"object"`         // Sort criteria (optional). Example: {"name":1}
+	Limit       *int64                 `json:"limit,omitempty" example:"100"`               // Maximum number of documents to return (optional, default: 100)
+	Skip        *int64                 `json:"skip,omitempty" example:"0"`                  // Number of documents to skip (optional, default: 0)
+	Projection  interface{}            `json:"projection,omitempty" swaggertype:"object"`   // Fields to include/exclude (optional). Supports $slice. Example: {"name":1,"comments":{"$slice":[0,10]}}
+	SavedFilter string                 `json:"savedFilter,omitempty" example:"activeUsers"` // Name of a SAVED_FILTERS entry to run instead of filter (optional)
+	Params      map[string]interface{} `json:"params,omitempty" swaggertype:"object"`       // Values for the saved filter's {"$param": "name"} placeholders. Example: {"plan":"pro"}
+}
+
+// MultiFindQuery describes a single collection query within a multiFind request
+type MultiFindQuery struct {
+	Collection string      `json:"collection" example:"orders"`               // Collection name (required, unique within the request)
+	Filter     interface{} `json:"filter,omitempty" swaggertype:"object"`     // MongoDB filter query (optional). Example: {"userId":"42"}
+	Sort       interface{} `json:"sort,omitempty" swaggertype:"object"`       // Sort criteria (optional). Example: {"createdAt":-1}
+	Limit      *int64      `json:"limit,omitempty" example:"10"`              // Maximum number of documents to return (optional, default: 100)
+	Projection interface{} `json:"projection,omitempty" swaggertype:"object"` // Fields to include/exclude (optional). Example: {"total":1}
+}
+
+// MultiFindRequest represents the request for multiFind action
+//
+//	@Description	Request body for multiFind action. Runs several find queries against collections of the same database concurrently.
+type MultiFindRequest struct {
+	Database string           `json:"database" example:"mydb"` // Database name (required)
+	Queries  []MultiFindQuery `json:"queries"`                 // Queries to run (required, at most 10)
+}
+
+// ExistsRequest represents the request for exists action
+//
+//	@Description	Request body for exists action. Filter is a MongoDB query object.
+type ExistsRequest struct {
+	baseRequest
+	Filter interface{} `json:"filter,omitempty" swaggertype:"object"` // MongoDB filter query (optional). Example: {"email":"john@example.com"}
 }
 
 // UpdateOneRequest represents the request for updateOne action
@@ -75,8 +117,12 @@ type FindRequest struct {
 //	@Description	Request body for updateOne action. Filter is a MongoDB query object. Update is a MongoDB update document (use $set, $unset, etc.).
 type UpdateOneRequest struct {
 	baseRequest
-	Filter interface{} `json:"filter" swaggertype:"object"` // MongoDB filter query (required). Example: {"_id":"507f1f77bcf86cd799439011"}
-	Update interface{} `json:"update" swaggertype:"object"` // Update document (required). Example: {"$set":{"name":"Jane"}}
+	Filter          interface{} `json:"filter" swaggertype:"object"`               // MongoDB filter query (required). Example: {"_id":"507f1f77bcf86cd799439011"}
+	Update          interface{} `json:"update" swaggertype:"object"`               // Update document (required). Example: {"$set":{"name":"Jane"}}
+	ReturnDocument  bool        `json:"returnDocument,omitempty" example:"false"`  // Return the updated document instead of modifiedCount
+	ReturnVersion   bool        `json:"returnVersion,omitempty" example:"false"`   // Return the VERSION_FIELD value of the updated document instead of modifiedCount
+	Projection      interface{} `json:"projection,omitempty" swaggertype:"object"` // Fields of the returned document (only with returnDocument). Example: {"name":1}
+	DescribeChanges bool        `json:"describeChanges,omitempty" example:"false"` // Return an updateDescription computed from the update operators when a document changed
 }
 
 // UpdateManyRequest represents the request for updateMany action
@@ -84,8 +130,11 @@ type UpdateOneRequest struct {
 //	@Description	Request body for updateMany action. Filter is a MongoDB query object. Update is a MongoDB update document (use $set, $unset, etc.).
 type UpdateManyRequest struct {
 	baseRequest
-	Filter interface{} `json:"filter" swaggertype:"object"` // MongoDB filter query (required). Example: {"status":"active"}
-	Update interface{} `json:"update" swaggertype:"object"` // Update document (required). Example: {"$set":{"status":"inactive"}}
+	Filter          interface{} `json:"filter" swaggertype:"object"`               // MongoDB filter query (required). Example: {"status":"active"}
+	Update          interface{} `json:"update" swaggertype:"object"`               // Update document (required). Example: {"$set":{"status":"inactive"}}
+	ConfirmAll      bool        `json:"confirmAll,omitempty" example:"false"`      // Must be true to update every document with an empty filter when REQUIRE_FILTER_ON_DESTRUCTIVE is enabled
+	DescribeChanges bool        `json:"describeChanges,omitempty" example:"false"` // Return an updateDescription computed from the update operators when documents changed
+	ReturnIds       bool        `json:"returnIds,omitempty" example:"false"`       // Return the _id of the matched documents, collected just before the update (at most MAX_RETURN_IDS)
 }
 
 // DeleteOneRequest represents the request for deleteOne action
@@ -101,47 +150,93 @@ type DeleteOneRequest struct {
 //	@Description	Request body for deleteMany action. Filter is a MongoDB query object.
 type DeleteManyRequest struct {
 	baseRequest
-	Filter interface{} `json:"filter" swaggertype:"object"` // MongoDB filter query (required). Example: {"status":"deleted"}
+	Filter       interface{} `json:"filter" swaggertype:"object"`                                       // MongoDB filter query (required). Example: {"status":"deleted"}
+	ConfirmAll   bool        `json:"confirmAll,omitempty" example:"false"`                              // Must be true to delete every document with an empty filter when REQUIRE_FILTER_ON_DESTRUCTIVE is enabled
+	ConfirmToken string      `json:"confirmToken,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015"` // Token from the first call, required to delete when DELETE_CONFIRMATION is enabled
+	ReturnIds    bool        `json:"returnIds,omitempty" example:"false"`                               // Return the _id of the matched documents, collected just before the delete (at most MAX_RETURN_IDS)
 }
 
 // Response structs for Swagger documentation
 
 // InsertOneResponse represents the response for insertOne action
 type InsertOneResponse struct {
-	InsertedID string `json:"insertedId" example:"507f1f77bcf86cd799439011"` // The ID of the inserted document
+	InsertedID   string `json:"insertedId" example:"507f1f77bcf86cd799439011"` // The ID of the inserted document
+	Acknowledged *bool  `json:"acknowledged,omitempty" example:"false"`        // false when the write was sent without acknowledgement (w=0, status 202)
 }
 
 // InsertManyResponse represents the response for insertMany action
 type InsertManyResponse struct {
-	InsertedIDs []string `json:"insertedIds" example:"[\"507f1f77bcf86cd799439011\",\"507f1f77bcf86cd799439012\"]"` // Array of IDs of inserted documents
+	InsertedIDs  []string `json:"insertedIds" example:"[\"507f1f77bcf86cd799439011\",\"507f1f77bcf86cd799439012\"]"` // IDs of the inserted documents, in the order of the documents array
+	Acknowledged *bool    `json:"acknowledged,omitempty" example:"false"`                                            // false when the write was sent without acknowledgement (w=0, status 202)
+}
+
+// InsertManyPartialResponse represents the response for an insertMany where some documents were rejected
+type InsertManyPartialResponse struct {
+	Error             string       `json:"error" example:"2 of 10 documents could not be inserted"` // Summary of the failure
+	InsertedCount     int          `json:"insertedCount" example:"8"`                               // Number of documents that were inserted
+	InsertedIDs       []*string    `json:"insertedIds"`                                             // One entry per input document, in order: its ID if inserted, else null
+	WriteErrors       []WriteError `json:"writeErrors"`                                             // One entry per rejected document
+	StoppedAt         *WriteError  `json:"stoppedAt,omitempty"`                                     // With ordered, the document the insert stopped at; an import resumes from its index
+	WriteConcernError string       `json:"writeConcernError,omitempty"`                             // Set when the write concern could not be satisfied
 }
 
 // FindOneResponse represents the response for findOne action
 type FindOneResponse struct {
-	Document map[string]interface{} `json:"document" swaggertype:"object"` // The found document, or null if not found
+	Document map[string]interface{} `json:"document" swaggertype:"object"`         // The found document, or null if not found
+	ServedBy string                 `json:"servedBy,omitempty" example:"fallback"` // "fallback" when MONGO_URI_FALLBACK answered
 }
 
 // FindResponse represents the response for find action
 type FindResponse struct {
-	Documents  []map[string]interface{} `json:"documents" swaggertype:"array,object"` // Array of found documents
-	Count      int                      `json:"count" example:"10"`                   // Number of documents returned
-	TotalCount *int64                   `json:"totalCount,omitempty" example:"100"`   // Total number of documents matching the filter (optional)
-	Skip       *int64                   `json:"skip,omitempty" example:"0"`           // Number of documents skipped (optional)
-	Limit      *int64                   `json:"limit,omitempty" example:"100"`        // Maximum number of documents returned (optional)
+	Documents  []map[string]interface{} `json:"documents,omitempty" swaggertype:"array,object"` // Array of found documents (omitted with pluck)
+	Values     []interface{}            `json:"values,omitempty" swaggertype:"array,string"`    // Plucked field values, only with pluck
+	Count      int                      `json:"count" example:"10"`                             // Number of documents returned
+	TotalCount *int64                   `json:"totalCount,omitempty" example:"100"`             // Total number of documents matching the filter (optional)
+	Skip       *int64                   `json:"skip,omitempty" example:"0"`                     // Number of documents skipped (optional)
+	Limit      *int64                   `json:"limit,omitempty" example:"100"`                  // Maximum number of documents returned (optional)
+	Query      map[string]interface{}   `json:"query,omitempty" swaggertype:"object"`           // Normalized query, only when echoQuery=true
+	Partial    bool                     `json:"partial,omitempty" example:"true"`               // Set with allowPartialResults: documents may be missing from unavailable shards
+	ServedBy   string                   `json:"servedBy,omitempty" example:"fallback"`          // "fallback" when MONGO_URI_FALLBACK answered
+}
+
+// MultiFindResponse represents the response for multiFind action
+type MultiFindResponse struct {
+	Results map[string][]map[string]interface{} `json:"results" swaggertype:"object"` // Documents keyed by collection name
+}
+
+// ExistsResponse represents the response for exists action
+type ExistsResponse struct {
+	Exists bool `json:"exists" example:"true"` // Whether any document matches the filter
 }
 
 // UpdateOneResponse represents the response for updateOne action
 type UpdateOneResponse struct {
-	MatchedCount  int64  `json:"matchedCount" example:"1"`                                // Number of documents matched
-	ModifiedCount int64  `json:"modifiedCount" example:"1"`                               // Number of documents modified
-	UpsertedID    string `json:"upsertedId,omitempty" example:"507f1f77bcf86cd799439011"` // ID of upserted document (if upsert occurred)
+	MatchedCount      int64              `json:"matchedCount" example:"1"`                                // Number of documents matched
+	ModifiedCount     int64              `json:"modifiedCount" example:"1"`                               // Number of documents modified
+	UpsertedID        string             `json:"upsertedId,omitempty" example:"507f1f77bcf86cd799439011"` // ID of upserted document (if upsert occurred)
+	UpdateDescription *UpdateDescription `json:"updateDescription,omitempty"`                             // What the update wrote, only with describeChanges and when a document changed
+}
+
+// UpdateOneDocumentResponse represents the response for updateOne action with returnDocument set
+type UpdateOneDocumentResponse struct {
+	MatchedCount int64                  `json:"matchedCount" example:"1"`      // Number of documents matched
+	Document     map[string]interface{} `json:"document" swaggertype:"object"` // The document after the update, or null if nothing matched
+}
+
+// UpdateOneVersionResponse represents the response for updateOne action with returnVersion set
+type UpdateOneVersionResponse struct {
+	MatchedCount int64       `json:"matchedCount" example:"1"`                                    // Number of documents matched
+	Version      interface{} `json:"version" swaggertype:"string" example:"2024-01-15T10:30:00Z"` // VERSION_FIELD of the document after the update, or null if nothing matched or the field is missing
 }
 
 // UpdateManyResponse represents the response for updateMany action
 type UpdateManyResponse struct {
-	MatchedCount  int64  `json:"matchedCount" example:"5"`                                // Number of documents matched
-	ModifiedCount int64  `json:"modifiedCount" example:"5"`                               // Number of documents modified
-	UpsertedID    string `json:"upsertedId,omitempty" example:"507f1f77bcf86cd799439011"` // ID of upserted document (if upsert occurred)
+	MatchedCount         int64              `json:"matchedCount" example:"5"`                                 // Number of documents matched
+	ModifiedCount        int64              `json:"modifiedCount" example:"5"`                                // Number of documents modified
+	UpsertedID           string             `json:"upsertedId,omitempty" example:"507f1f77bcf86cd799439011"`  // ID of upserted document (if upsert occurred)
+	UpdateDescription    *UpdateDescription `json:"updateDescription,omitempty"`                              // What the update wrote to each modified document, only with describeChanges
+	AffectedIds          []string           `json:"affectedIds,omitempty" example:"507f1f77bcf86cd799439011"` // _id of the documents that matched just before the update, only with returnIds
+	AffectedIdsTruncated bool               `json:"affectedIdsTruncated,omitempty" example:"false"`           // True when more documents matched than MAX_RETURN_IDS
 }
 
 // DeleteOneResponse represents the response for deleteOne action
@@ -151,7 +246,17 @@ type DeleteOneResponse struct {
 
 // DeleteManyResponse represents the response for deleteMany action
 type DeleteManyResponse struct {
-	DeletedCount int64 `json:"deletedCount" example:"5"` // Number of documents deleted
+	DeletedCount         int64    `json:"deletedCount" example:"5"`                                 // Number of documents deleted
+	AffectedIds          []string `json:"affectedIds,omitempty" example:"507f1f77bcf86cd799439011"` // _id of the documents that matched just before the delete, only with returnIds
+	AffectedIdsTruncated bool     `json:"affectedIdsTruncated,omitempty" example:"false"`           // True when more documents matched than MAX_RETURN_IDS
+}
+
+// DeleteManyConfirmationResponse represents the first response of a two-phase deleteMany
+type DeleteManyConfirmationResponse struct {
+	DryRun       bool   `json:"dryRun" example:"true"`                                   // Always true: nothing was deleted yet
+	DeletedCount int64  `json:"deletedCount" example:"5"`                                // Number of documents the delete would remove now
+	ConfirmToken string `json:"confirmToken" example:"9f86d081884c7d659a2feaa0c55ad015"` // Token to send back with the same filter to run the delete
+	ExpiresAt    string `json:"expiresAt" example:"2024-01-15T10:31:00Z"`                // When the token stops being accepted
 }
 
 // InsertOne godoc
@@ -164,9 +269,11 @@ type DeleteManyResponse struct {
 //	@Security		ApiKeyAuth
 //	@Param			request	body		InsertOneRequest	true	"Insert one document request"
 //	@Success		200		{object}	InsertOneResponse	"Successfully inserted document"
+//	@Success		202		{object}	InsertOneResponse	"Sent without acknowledgement (w=0); acknowledged is false"
 //	@Failure		400		{object}	map[string]string	"Bad request - missing required fields or invalid JSON"
 //	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
 //	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		409		{object}	map[string]string	"Conflict - duplicate key on a unique index"
 //	@Failure		500		{object}	map[string]string	"Internal server error"
 //	@Router			/v1/data-api/action/insertOne [post]
 func (h *DataAPIHandler) InsertOne(c echo.Context) error {
@@ -182,6 +289,13 @@ func (h *DataAPIHandler) InsertOne(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "insertOne", req.Database, req.Collection)
 
 	if req.Document == nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -189,44 +303,71 @@ func (h *DataAPIHandler) InsertOne(c echo.Context) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "insertOne", 10*time.Second)
 	defer cancel()
 
 	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
+		return serverError(c, err, "Failed to get collection: "+err.Error())
 	}
 
 	// Convert document to bson.M
-	docBytes, err := bson.Marshal(req.Document)
+	doc, err := extJSONDocument(req.Document)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid document: " + err.Error(),
 		})
 	}
-
-	var doc bson.M
-	if err := bson.Unmarshal(docBytes, &doc); err != nil {
+	if err := restrictDocument(h.cfg, req.Database, req.Collection, doc); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid document format: " + err.Error(),
+			"error": err.Error(),
 		})
 	}
-
-	result, err := collection.InsertOne(ctx, doc)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
+	applyDefaults(h.cfg, req.Database, req.Collection, doc, time.Now())
+	applyTransforms(h.cfg, req.Database, req.Collection, doc)
+	if err := scopeDocument(h.cfg, c, doc); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
 		})
 	}
+	if err := assignID(h.cfg, req.Database, req.Collection, doc); err != nil {
+		return serverError(c, err, "Failed to generate _id: "+err.Error())
+	}
+
+	if isDryRun(c) {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":        true,
+			"insertedCount": 1,
+		})
+	}
+
+	result, err := collection.InsertOne(ctx, doc, options.InsertOne().SetComment(operationComment(c)))
+	acknowledged := !unacknowledged(err)
+	if err != nil && acknowledged {
+		auth.RecordUpstreamError(c, err)
+		status, message := writeFailure(err)
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
 
 	// Convert ObjectID to string for JSON response
-	insertedID := result.InsertedID
+	insertedID := doc["_id"]
+	if result != nil {
+		insertedID = result.InsertedID
+	}
 	if oid, ok := insertedID.(primitive.ObjectID); ok {
 		insertedID = oid.Hex()
 	}
 
+	// A w=0 write returns before the server applied it, so it is only accepted
+	if !acknowledged {
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"insertedId":   insertedID,
+			"acknowledged": false,
+		})
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"insertedId": insertedID,
 	})
@@ -242,12 +383,30 @@ func (h *DataAPIHandler) InsertOne(c echo.Context) error {
 //	@Security		ApiKeyAuth
 //	@Param			request	body		InsertManyRequest	true	"Insert many documents request"
 //	@Success		200		{object}	InsertManyResponse	"Successfully inserted documents"
+//	@Success		202		{object}	InsertManyResponse	"Sent without acknowledgement (w=0); acknowledged is false"
+//	@Success		207		{object}	InsertManyPartialResponse	"Some documents were rejected by MongoDB"
 //	@Failure		400		{object}	map[string]string	"Bad request - missing required fields or invalid JSON"
 //	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
 //	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
 //	@Failure		500		{object}	map[string]string	"Internal server error"
 //	@Router			/v1/data-api/action/insertMany [post]
 func (h *DataAPIHandler) InsertMany(c echo.Context) error {
+	return h.insertMany(c, func(dbName, collectionName string) (documentsInserter, error) {
+		collection, err := h.dbClient.GetCollection(dbName, collectionName)
+		if err != nil {
+			return nil, err
+		}
+		return collection, nil
+	})
+}
+
+// documentsInserter is the part of *mongo.Collection that InsertMany writes through
+type documentsInserter interface {
+	InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error)
+}
+
+// insertMany serves InsertMany, inserting through the collection getCollection returns
+func (h *DataAPIHandler) insertMany(c echo.Context, getCollection func(dbName, collectionName string) (documentsInserter, error)) error {
 	var req InsertManyRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -260,6 +419,13 @@ func (h *DataAPIHandler) InsertMany(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "insertMany", req.Database, req.Collection)
 
 	if len(req.Documents) == 0 {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -267,49 +433,99 @@ func (h *DataAPIHandler) InsertMany(c echo.Context) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := operationContext(h.cfg, "insertMany", 30*time.Second)
 	defer cancel()
 
-	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	collection, err := getCollection(req.Database, req.Collection)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
+		return serverError(c, err, "Failed to get collection: "+err.Error())
 	}
 
 	var docs []interface{}
+	now := time.Now()
 	for _, doc := range req.Documents {
-		docBytes, err := bson.Marshal(doc)
+		bsonDoc, err := extJSONDocument(doc)
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{
 				"error": "Invalid document: " + err.Error(),
 			})
 		}
-
-		var bsonDoc bson.M
-		if err := bson.Unmarshal(docBytes, &bsonDoc); err != nil {
+		if err := restrictDocument(h.cfg, req.Database, req.Collection, bsonDoc); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		applyDefaults(h.cfg, req.Database, req.Collection, bsonDoc, now)
+		applyTransforms(h.cfg, req.Database, req.Collection, bsonDoc)
+		if err := scopeDocument(h.cfg, c, bsonDoc); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid document format: " + err.Error(),
+				"error": err.Error(),
 			})
 		}
+		if err := assignID(h.cfg, req.Database, req.Collection, bsonDoc); err != nil {
+			return serverError(c, err, "Failed to generate _id: "+err.Error())
+		}
 		docs = append(docs, bsonDoc)
 	}
 
-	result, err := collection.InsertMany(ctx, docs)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
+	if isDryRun(c) {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":        true,
+			"insertedCount": len(docs),
 		})
 	}
 
-	// Convert ObjectIDs to strings
-	insertedIds := make([]interface{}, len(result.InsertedIDs))
-	for i, id := range result.InsertedIDs {
-		if oid, ok := id.(primitive.ObjectID); ok {
-			insertedIds[i] = oid.Hex()
-		} else {
-			insertedIds[i] = id
+	ordered := req.Ordered == nil || *req.Ordered
+	result, err := collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(ordered).SetComment(operationComment(c)))
+	acknowledged := !unacknowledged(err)
+	if err != nil && acknowledged {
+		writeErrors, writeConcernError, ok := bulkWriteErrors(err)
+		if !ok || result == nil {
+			return serverError(c, err, err.Error())
+		}
+
+		// Report which documents made it in so clients can retry only the failed ones.
+		// insertedIds stays positional, with null where a document was rejected or, after
+		// an ordered insert stopped, never attempted.
+		succeeded := succeededIndexes(len(docs), writeErrors, ordered)
+
+		response := map[string]interface{}{
+			"error":         fmt.Sprintf("%d of %d documents could not be inserted", len(docs)-len(succeeded), len(docs)),
+			"insertedCount": len(succeeded),
+			"insertedIds":   positionalInsertedIDs(result.InsertedIDs, succeeded),
+			"writeErrors":   writeErrors,
+		}
+		// An ordered insert stops at its first error: everything before it went in and
+		// nothing after it was attempted, so that index is where an import resumes
+		if ordered && len(writeErrors) > 0 {
+			response["stoppedAt"] = firstWriteError(writeErrors)
+		}
+		if writeConcernError != "" {
+			response["writeConcernError"] = writeConcernError
+			if len(writeErrors) == 0 {
+				response["error"] = "Write concern error: " + writeConcernError
+			}
 		}
+		return c.JSON(http.StatusMultiStatus, response)
+	}
+
+	// Convert ObjectIDs to strings. The driver assigns InsertedIDs by input position
+	// before sending, so the order matches documents even for unordered inserts.
+	var ids []interface{}
+	if result != nil {
+		ids = result.InsertedIDs
+	}
+	insertedIds := make([]interface{}, len(ids))
+	for i, id := range ids {
+		insertedIds[i] = insertedIDValue(id)
+	}
+
+	// A w=0 write returns before the server applied it, so it is only accepted
+	if !acknowledged {
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"insertedIds":  insertedIds,
+			"acknowledged": false,
+		})
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -325,11 +541,14 @@ func (h *DataAPIHandler) InsertMany(c echo.Context) error {
 //	@Accept			json
 //	@Produce		json
 //	@Security		ApiKeyAuth
-//	@Param			request	body		FindOneRequest		true	"Find one document request"
+//	@Param			request		body		FindOneRequest		true	"Find one document request"
+//	@Param			omitNull	query		bool				false	"Recursively drop keys whose value is null from the returned document"	default(false)
+//	@Param			omitEmpty	query		bool				false	"With omitNull, also drop empty strings and empty arrays"	default(false)
 //	@Success		200		{object}	FindOneResponse		"Successfully found document"
 //	@Failure		400		{object}	map[string]string	"Bad request - invalid filter, sort, or projection"
 //	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
 //	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		404		{object}	map[string]string	"Not found - collection does not exist (STRICT_COLLECTIONS)"
 //	@Failure		500		{object}	map[string]string	"Internal server error"
 //	@Router			/v1/data-api/action/findOne [post]
 func (h *DataAPIHandler) FindOne(c echo.Context) error {
@@ -345,25 +564,23 @@ func (h *DataAPIHandler) FindOne(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
 		})
 	}
 
+	metrics.Track(c, "findOne", req.Database, req.Collection)
+
 	filter, err := h.buildFilter(req.Filter)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid filter: " + err.Error(),
 		})
 	}
+	filter = scopeFilter(h.cfg, c, filter)
 
-	findOptions := options.FindOne()
+	findOptions := options.FindOne().SetComment(operationComment(c))
 	if req.Sort != nil {
 		sort, err := h.buildSort(req.Sort)
 		if err != nil {
@@ -390,20 +607,107 @@ func (h *DataAPIHandler) FindOne(c echo.Context) error {
 	}
 
 	var result bson.M
-	err = collection.FindOne(ctx, filter, findOptions).Decode(&result)
+	target, err := h.clients.read("findOne", 10*time.Second, req.Database, req.Collection, func(ctx context.Context, collection *mongo.Collection) error {
+		var err error
+		result, err = h.reads.findOne(ctx, collection, filter, findOptions, h.cfg.Timeout("findOne", 10*time.Second))
+		return err
+	})
+	defer target.cancel()
+	flagSlowRead(target.ctx, c, h.cfg)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return c.JSON(http.StatusOK, map[string]interface{}{
+			missing, err := strictMissing(target.ctx, h.cfg, target.collection)
+			if err != nil {
+				return serverError(c, err, err.Error())
+			}
+			if missing {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": missingCollectionMessage(target.collection),
+				})
+			}
+			accountDocuments(c, h.cfg)
+			response := map[string]interface{}{
 				"document": nil,
-			})
+			}
+			markServedBy(c, target, response, "servedBy")
+			return c.JSON(http.StatusOK, response)
 		}
-		return c.JSON(http.StatusInternalServerError, map[string]string{
+		return serverError(c, err, err.Error())
+	}
+
+	accountDocuments(c, h.cfg, result)
+	if queryFlag(c, "omitNull") {
+		result = omitNulls(result, queryFlag(c, "omitEmpty"))
+	}
+
+	response := map[string]interface{}{
+		"document": result,
+	}
+	markServedBy(c, target, response, "servedBy")
+	return c.JSON(http.StatusOK, response)
+}
+
+// Exists godoc
+//
+//	@Summary		Check whether a document exists
+//	@Description	Reports whether any document matches the filter without fetching it
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		ExistsRequest		true	"Exists request"
+//	@Success		200		{object}	ExistsResponse		"Successfully checked existence"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid filter"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/data-api/action/exists [post]
+func (h *DataAPIHandler) Exists(c echo.Context) error {
+	var req ExistsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if req.Database == "" || req.Collection == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "database and collection are required",
+		})
+	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
 		})
 	}
 
+	metrics.Track(c, "exists", req.Database, req.Collection)
+
+	ctx, cancel := operationContext(h.cfg, "exists", 10*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	filter, err := h.buildFilter(req.Filter)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid filter: " + err.Error(),
+		})
+	}
+	filter = scopeFilter(h.cfg, c, filter)
+
+	// Counting with a limit of 1 stops at the first match and never transfers the document
+	count, err := collection.CountDocuments(ctx, filter, options.Count().SetLimit(1).SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"document": result,
+		"exists": count > 0,
 	})
 }
 
@@ -415,11 +719,19 @@ func (h *DataAPIHandler) FindOne(c echo.Context) error {
 //	@Accept			json
 //	@Produce		json
 //	@Security		ApiKeyAuth
-//	@Param			request	body		FindRequest			true	"Find documents request"
+//	@Param			request		body		FindRequest			true	"Find documents request"
+//	@Param			echoQuery	query		bool				false	"Include the normalized query in the response"	default(false)
+//	@Param			pluck		query		string				false	"Return only this field's values as a flat values array instead of documents"	example("email")
+//	@Param			pluckNulls	query		bool				false	"With pluck, return null for documents missing the field instead of skipping them"	default(false)
+//	@Param			stats		query		bool				false	"Include execution statistics (runs an explain)"	default(false)
+//	@Param			allowPartialResults	query	bool			false	"On sharded clusters, return results from the shards that respond instead of failing when one is unavailable. Results may be incomplete; totalCount is omitted"	default(false)
+//	@Param			omitNull	query		bool				false	"Recursively drop keys whose value is null from the returned documents"	default(false)
+//	@Param			omitEmpty	query		bool				false	"With omitNull, also drop empty strings and empty arrays"	default(false)
 //	@Success		200		{object}	FindResponse		"Successfully found documents"
 //	@Failure		400		{object}	map[string]string	"Bad request - invalid filter, sort, limit, skip, or projection"
 //	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
 //	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		404		{object}	map[string]string	"Not found - collection does not exist (STRICT_COLLECTIONS)"
 //	@Failure		500		{object}	map[string]string	"Internal server error"
 //	@Router			/v1/data-api/action/find [post]
 func (h *DataAPIHandler) Find(c echo.Context) error {
@@ -435,25 +747,72 @@ func (h *DataAPIHandler) Find(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	metrics.Track(c, "find", req.Database, req.Collection)
 
-	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	var filter bson.M
+	var err error
+	if req.SavedFilter != "" {
+		if req.Filter != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "filter and savedFilter cannot be combined",
+			})
+		}
+		filter, err = savedFilter(h.cfg, req.SavedFilter, req.Params)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid saved filter: " + err.Error(),
+			})
+		}
+	} else {
+		if req.Params != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "params requires savedFilter",
+			})
+		}
+		filter, err = h.buildFilter(req.Filter)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid filter: " + err.Error(),
+			})
+		}
+	}
+	filter = scopeFilter(h.cfg, c, filter)
+
+	// Apply the api-key's KEY_LIMITS caps; clamped values are reported back in the response
+	limits := keyLimits(h.cfg, c)
+	var requestedLimit, requestedSkip int64
+	if req.Limit != nil {
+		requestedLimit = *req.Limit
+	}
+	if req.Skip != nil {
+		requestedSkip = *req.Skip
+	}
+	cappedLimit, err := capLimit(limits, requestedLimit)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
 		})
 	}
-
-	filter, err := h.buildFilter(req.Filter)
+	cappedSkip, err := capSkip(limits, requestedSkip)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid filter: " + err.Error(),
+			"error": err.Error(),
 		})
 	}
+	if cappedLimit != requestedLimit {
+		req.Limit = &cappedLimit
+	}
+	if cappedSkip != requestedSkip {
+		req.Skip = &cappedSkip
+	}
 
-	findOptions := options.Find()
+	findOptions := options.Find().SetComment(operationComment(c))
 
 	// Add limit
 	if req.Limit != nil && *req.Limit > 0 {
@@ -466,8 +825,9 @@ func (h *DataAPIHandler) Find(c echo.Context) error {
 	}
 
 	// Add sort support
+	var sort bson.D
 	if req.Sort != nil {
-		sort, err := h.buildSort(req.Sort)
+		sort, err = h.buildSort(req.Sort)
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{
 				"error": "Invalid sort: " + err.Error(),
@@ -478,38 +838,97 @@ func (h *DataAPIHandler) Find(c echo.Context) error {
 		}
 	}
 
+	pluck := c.QueryParam("pluck")
+	if pluck != "" {
+		if err := validateFieldPath(pluck); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid pluck: " + err.Error(),
+			})
+		}
+		if req.Projection != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "pluck cannot be combined with projection",
+			})
+		}
+	}
+
 	// Add projection support
+	var projection bson.M
 	if req.Projection != nil {
-		projection, err := h.buildProjection(req.Projection)
+		projection, err = h.buildProjection(req.Projection)
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{
 				"error": "Invalid projection: " + err.Error(),
 			})
 		}
-		if projection != nil {
-			findOptions.SetProjection(projection)
-		}
+	}
+	projection = listProjection(h.cfg, req.Database, req.Collection, projection)
+	if pluck != "" {
+		projection = pluckProjection(pluck)
+	}
+	if projection != nil {
+		findOptions.SetProjection(projection)
 	}
 
-	cursor, err := collection.Find(ctx, filter, findOptions)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+	partial := queryFlag(c, "allowPartialResults")
+	if partial {
+		findOptions.SetAllowPartialResults(true)
 	}
-	defer cursor.Close(ctx)
 
 	var results []bson.M
-	if err := cursor.All(ctx, &results); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+	var elapsed time.Duration
+	target, err := h.clients.read("find", 30*time.Second, req.Database, req.Collection, func(ctx context.Context, collection *mongo.Collection) error {
+		started := time.Now()
+		cursor, err := collection.Find(ctx, filter, findOptions)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		if err := cursor.All(ctx, &results); err != nil {
+			return err
+		}
+		elapsed = time.Since(started)
+		return nil
+	})
+	defer target.cancel()
+	flagSlowRead(target.ctx, c, h.cfg)
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	// The stats explain and the total count go to the deployment that answered
+	ctx, collection := target.ctx, target.collection
+	if len(results) == 0 {
+		missing, err := strictMissing(ctx, h.cfg, collection)
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		if missing {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": missingCollectionMessage(collection),
+			})
+		}
+	}
+
+	// Stripping copies the documents, so it only runs when asked for
+	if pluck == "" && queryFlag(c, "omitNull") {
+		results = omitNullsAll(results, queryFlag(c, "omitEmpty"))
 	}
 
 	response := map[string]interface{}{
 		"documents": results,
 		"count":     len(results),
 	}
+	markServedBy(c, target, response, "servedBy")
+	if partial {
+		response["partial"] = true
+	}
+	if pluck != "" {
+		values := pluckValues(results, pluck, queryFlag(c, "pluckNulls"))
+		delete(response, "documents")
+		response["values"] = values
+		response["count"] = len(values)
+	}
 	if req.Skip != nil {
 		response["skip"] = *req.Skip
 	}
@@ -517,8 +936,52 @@ func (h *DataAPIHandler) Find(c echo.Context) error {
 		response["limit"] = *req.Limit
 	}
 
+	var limit, skip int64
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if req.Skip != nil && *req.Skip > 0 {
+		skip = *req.Skip
+	}
+	query := slowFind{
+		action: "find", collection: collection, filter: filter, sort: sort,
+		projection: projection, limit: limit, skip: skip,
+	}
+	logSlowFind(c, h.cfg, elapsed, query)
+	accountFind(c, h.cfg, query, results)
+
+	// Echo the normalized query so clients can derive stable cache keys
+	if queryFlag(c, "echoQuery") {
+		echoed, err := queryEcho(filter, sort, projection, limit, skip)
+		if err != nil {
+			return serverError(c, err, "Failed to encode query: "+err.Error())
+		}
+		response["query"] = echoed
+	}
+
+	// Execution statistics cost an explain round trip, so they are opt-in
+	if queryFlag(c, "stats") {
+		stats := map[string]interface{}{
+			"elapsedMillis": elapsed.Milliseconds(),
+		}
+		if explained, err := explainFind(ctx, collection, filter, sort, projection, limit, skip); err != nil {
+			stats["explainError"] = err.Error()
+		} else {
+			stats["docsExamined"] = explained.DocsExamined
+			stats["keysExamined"] = explained.KeysExamined
+			stats["nReturned"] = explained.Returned
+			stats["executionTimeMillis"] = explained.ExecutionMillis
+		}
+		response["stats"] = stats
+	}
+
+	// Counting would wait on the shards a partial read chose not to wait for
+	if partial {
+		return c.JSON(http.StatusOK, response)
+	}
+
 	// Get total count for the filter (for pagination info)
-	totalCount, err := collection.CountDocuments(ctx, filter)
+	totalCount, err := collection.CountDocuments(ctx, filter, options.Count().SetComment(operationComment(c)))
 	if err != nil {
 		// If count fails, still return documents but without totalCount
 		return c.JSON(http.StatusOK, response)
@@ -529,16 +992,190 @@ func (h *DataAPIHandler) Find(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// maxMultiFindQueries caps the number of queries a single multiFind request may run
+const maxMultiFindQueries = 10
+
+// MultiFind godoc
+//
+//	@Summary		Find documents in multiple collections
+//	@Description	Runs several find queries against collections of the same database concurrently and returns the documents keyed by collection name
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		MultiFindRequest	true	"Multi find request"
+//	@Success		200		{object}	MultiFindResponse	"Successfully found documents"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid or duplicate queries"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/data-api/action/multiFind [post]
+func (h *DataAPIHandler) MultiFind(c echo.Context) error {
+	var req MultiFindRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if req.Database == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "database is required",
+		})
+	}
+	if err := auth.ValidateDatabaseName(req.Database); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "multiFind", req.Database, "*")
+
+	if len(req.Queries) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "queries array is required and cannot be empty",
+		})
+	}
+
+	if len(req.Queries) > maxMultiFindQueries {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "queries cannot contain more than 10 entries",
+		})
+	}
+
+	// Validate and build every query up front so a bad spec fails the whole request
+	type preparedQuery struct {
+		collection string
+		filter     bson.M
+		options    *options.FindOptions
+	}
+	limits := keyLimits(h.cfg, c)
+	prepared := make([]preparedQuery, len(req.Queries))
+	seen := make(map[string]bool, len(req.Queries))
+	for i, q := range req.Queries {
+		if q.Collection == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "collection is required for every query",
+			})
+		}
+		if err := auth.ValidateCollectionName(req.Database, q.Collection); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if seen[q.Collection] {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Duplicate collection in queries: " + q.Collection,
+			})
+		}
+		seen[q.Collection] = true
+
+		filter, err := h.buildFilter(q.Filter)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid filter for " + q.Collection + ": " + err.Error(),
+			})
+		}
+		filter = scopeFilter(h.cfg, c, filter)
+
+		limit := int64(100)
+		if q.Limit != nil && *q.Limit > 0 {
+			limit = *q.Limit
+		}
+		limit, err = capLimit(limits, limit)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid limit for " + q.Collection + ": " + err.Error(),
+			})
+		}
+		findOptions := options.Find().SetLimit(limit).SetComment(operationComment(c))
+		if q.Sort != nil {
+			sort, err := h.buildSort(q.Sort)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid sort for " + q.Collection + ": " + err.Error(),
+				})
+			}
+			if len(sort) > 0 {
+				findOptions.SetSort(sort)
+			}
+		}
+		var projection bson.M
+		if q.Projection != nil {
+			projection, err = h.buildProjection(q.Projection)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid projection for " + q.Collection + ": " + err.Error(),
+				})
+			}
+		}
+		if projection = listProjection(h.cfg, req.Database, q.Collection, projection); projection != nil {
+			findOptions.SetProjection(projection)
+		}
+
+		prepared[i] = preparedQuery{collection: q.Collection, filter: filter, options: findOptions}
+	}
+
+	ctx, cancel := operationContext(h.cfg, "multiFind", 30*time.Second)
+	defer cancel()
+
+	results := make([][]bson.M, len(prepared))
+	errs := make([]error, len(prepared))
+	var wg sync.WaitGroup
+	for i, q := range prepared {
+		wg.Add(1)
+		go func(i int, q preparedQuery) {
+			defer wg.Done()
+
+			collection, err := h.dbClient.GetCollection(req.Database, q.collection)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			cursor, err := collection.Find(ctx, q.filter, q.options)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer cursor.Close(ctx)
+
+			docs := []bson.M{}
+			if err := cursor.All(ctx, &docs); err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = docs
+		}(i, q)
+	}
+	wg.Wait()
+	flagSlowRead(ctx, c, h.cfg)
+
+	response := make(map[string]interface{}, len(prepared))
+	for i, q := range prepared {
+		if errs[i] != nil {
+			return serverError(c, errs[i], q.collection+": "+errs[i].Error())
+		}
+		response[q.collection] = results[i]
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"results": response,
+	})
+}
+
 // UpdateOne godoc
 //
 //	@Summary		Update a single document
-//	@Description	Updates a single document matching the filter criteria
+//	@Description	Updates a single document matching the filter criteria. With returnDocument set, the response carries the updated document (with the optional projection applied) instead of modifiedCount. With returnVersion set, it carries the VERSION_FIELD value of the updated document, for later optimistic-concurrency checks. With describeChanges set, it carries an updateDescription computed from the update operators.
 //	@Tags			data-api
 //	@Accept			json
 //	@Produce		json
 //	@Security		ApiKeyAuth
 //	@Param			request	body		UpdateOneRequest	true	"Update one document request"
 //	@Success		200		{object}	UpdateOneResponse	"Successfully updated document"
+//	@Success		200		{object}	UpdateOneDocumentResponse	"Successfully updated document (returnDocument)"
+//	@Success		200		{object}	UpdateOneVersionResponse	"Successfully updated document (returnVersion)"
 //	@Failure		400		{object}	map[string]string	"Bad request - missing required fields or invalid JSON"
 //	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
 //	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
@@ -557,6 +1194,13 @@ func (h *DataAPIHandler) UpdateOne(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "updateOne", req.Database, req.Collection)
 
 	if req.Filter == nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -570,14 +1214,18 @@ func (h *DataAPIHandler) UpdateOne(c echo.Context) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if req.Projection != nil && !req.ReturnDocument {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "projection requires returnDocument",
+		})
+	}
+
+	ctx, cancel := operationContext(h.cfg, "updateOne", 10*time.Second)
 	defer cancel()
 
 	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
+		return serverError(c, err, "Failed to get collection: "+err.Error())
 	}
 
 	filter, err := h.buildFilter(req.Filter)
@@ -586,6 +1234,7 @@ func (h *DataAPIHandler) UpdateOne(c echo.Context) error {
 			"error": "Invalid filter: " + err.Error(),
 		})
 	}
+	filter = scopeFilter(h.cfg, c, filter)
 
 	update, err := h.buildUpdate(req.Update)
 	if err != nil {
@@ -593,13 +1242,75 @@ func (h *DataAPIHandler) UpdateOne(c echo.Context) error {
 			"error": "Invalid update: " + err.Error(),
 		})
 	}
-
-	result, err := collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
+	if err := restrictUpdate(h.cfg, req.Database, req.Collection, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err := guardRowFilterUpdate(h.cfg, c, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
 		})
 	}
+	transformUpdate(h.cfg, req.Database, req.Collection, update)
+
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 1, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":       true,
+			"matchedCount": count,
+		})
+	}
+
+	if req.ReturnDocument || req.ReturnVersion {
+		updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After).SetComment(operationComment(c))
+		if !req.ReturnDocument {
+			// Only the version is needed, so leave the rest of the document on the server
+			updateOptions.SetProjection(pluckProjection(h.cfg.VersionField))
+		} else if req.Projection != nil {
+			projection, err := h.buildProjection(req.Projection)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid projection: " + err.Error(),
+				})
+			}
+			if projection != nil {
+				updateOptions.SetProjection(projection)
+			}
+		}
+
+		var document bson.M
+		err := collection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&document)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return serverError(c, err, err.Error())
+		}
+
+		response := map[string]interface{}{
+			"matchedCount": 1,
+		}
+		if err == mongo.ErrNoDocuments {
+			response["matchedCount"] = 0
+		}
+		if req.ReturnDocument {
+			response["document"] = document
+		}
+		if req.ReturnVersion {
+			version, _ := lookupPath(document, h.cfg.VersionField)
+			response["version"] = version
+		}
+		if req.DescribeChanges && err == nil {
+			response["updateDescription"] = describeUpdate(update, false).camelCase()
+		}
+		return c.JSON(http.StatusOK, response)
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update, options.Update().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
 
 	response := map[string]interface{}{
 		"matchedCount":  result.MatchedCount,
@@ -615,20 +1326,24 @@ func (h *DataAPIHandler) UpdateOne(c echo.Context) error {
 		response["upsertedId"] = upsertedID
 	}
 
+	if req.DescribeChanges && (result.ModifiedCount > 0 || result.UpsertedID != nil) {
+		response["updateDescription"] = describeUpdate(update, result.UpsertedID != nil).camelCase()
+	}
+
 	return c.JSON(http.StatusOK, response)
 }
 
 // UpdateMany godoc
 //
 //	@Summary		Update multiple documents
-//	@Description	Updates multiple documents matching the filter criteria
+//	@Description	Updates multiple documents matching the filter criteria. With describeChanges set, the response carries an updateDescription computed from the update operators. With returnIds set, it carries the _id of the documents matched just before the update.
 //	@Tags			data-api
 //	@Accept			json
 //	@Produce		json
 //	@Security		ApiKeyAuth
 //	@Param			request	body		UpdateManyRequest	true	"Update many documents request"
 //	@Success		200		{object}	UpdateManyResponse	"Successfully updated documents"
-//	@Failure		400		{object}	map[string]string	"Bad request - missing required fields or invalid JSON"
+//	@Failure		400		{object}	map[string]string	"Bad request - missing required fields, invalid JSON, or empty filter without confirmAll"
 //	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
 //	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
 //	@Failure		500		{object}	map[string]string	"Internal server error"
@@ -646,6 +1361,13 @@ func (h *DataAPIHandler) UpdateMany(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "updateMany", req.Database, req.Collection)
 
 	if req.Filter == nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -659,14 +1381,12 @@ func (h *DataAPIHandler) UpdateMany(c echo.Context) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "updateMany", 10*time.Second)
 	defer cancel()
 
 	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
+		return serverError(c, err, "Failed to get collection: "+err.Error())
 	}
 
 	filter, err := h.buildFilter(req.Filter)
@@ -676,24 +1396,65 @@ func (h *DataAPIHandler) UpdateMany(c echo.Context) error {
 		})
 	}
 
+	if !destructiveFilterAllowed(h.cfg, filter, req.ConfirmAll) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": errEmptyDestructiveFilter,
+		})
+	}
+	filter = scopeFilter(h.cfg, c, filter)
+
 	update, err := h.buildUpdate(req.Update)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid update: " + err.Error(),
 		})
 	}
-
-	result, err := collection.UpdateMany(ctx, filter, update)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
+	if err := restrictUpdate(h.cfg, req.Database, req.Collection, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err := guardRowFilterUpdate(h.cfg, c, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
 		})
 	}
+	transformUpdate(h.cfg, req.Database, req.Collection, update)
+
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 0, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":       true,
+			"matchedCount": count,
+		})
+	}
+
+	var ids []interface{}
+	var idsTruncated bool
+	if req.ReturnIds {
+		if ids, idsTruncated, err = affectedIDs(ctx, collection, filter, h.cfg.MaxReturnIDs, operationComment(c)); err != nil {
+			return serverError(c, err, "Failed to collect affected ids: "+err.Error())
+		}
+	}
+
+	result, err := collection.UpdateMany(ctx, filter, update, options.Update().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
 
 	response := map[string]interface{}{
 		"matchedCount":  result.MatchedCount,
 		"modifiedCount": result.ModifiedCount,
 	}
+	if req.ReturnIds {
+		response["affectedIds"] = ids
+		if idsTruncated {
+			response["affectedIdsTruncated"] = true
+		}
+	}
 
 	// Add upsertedId if document was upserted
 	if result.UpsertedID != nil {
@@ -704,6 +1465,10 @@ func (h *DataAPIHandler) UpdateMany(c echo.Context) error {
 		response["upsertedId"] = upsertedID
 	}
 
+	if req.DescribeChanges && (result.ModifiedCount > 0 || result.UpsertedID != nil) {
+		response["updateDescription"] = describeUpdate(update, result.UpsertedID != nil).camelCase()
+	}
+
 	return c.JSON(http.StatusOK, response)
 }
 
@@ -735,6 +1500,13 @@ func (h *DataAPIHandler) DeleteOne(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "deleteOne", req.Database, req.Collection)
 
 	if req.Filter == nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -742,14 +1514,12 @@ func (h *DataAPIHandler) DeleteOne(c echo.Context) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "deleteOne", 10*time.Second)
 	defer cancel()
 
 	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
+		return serverError(c, err, "Failed to get collection: "+err.Error())
 	}
 
 	filter, err := h.buildFilter(req.Filter)
@@ -758,14 +1528,24 @@ func (h *DataAPIHandler) DeleteOne(c echo.Context) error {
 			"error": "Invalid filter: " + err.Error(),
 		})
 	}
+	filter = scopeFilter(h.cfg, c, filter)
 
-	result, err := collection.DeleteOne(ctx, filter)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 1, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":       true,
+			"deletedCount": count,
 		})
 	}
 
+	result, err := collection.DeleteOne(ctx, filter, options.Delete().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"deletedCount": result.DeletedCount,
 	})
@@ -774,14 +1554,15 @@ func (h *DataAPIHandler) DeleteOne(c echo.Context) error {
 // DeleteMany godoc
 //
 //	@Summary		Delete multiple documents
-//	@Description	Deletes multiple documents matching the filter criteria
+//	@Description	Deletes multiple documents matching the filter criteria. With DELETE_CONFIRMATION enabled, a call without confirmToken deletes nothing and returns the matching count with a short-lived token; repeating the call with the same filter and that token runs the delete. With returnIds set, the response carries the _id of the documents matched just before the delete.
 //	@Tags			data-api
 //	@Accept			json
 //	@Produce		json
 //	@Security		ApiKeyAuth
 //	@Param			request	body		DeleteManyRequest	true	"Delete many documents request"
 //	@Success		200		{object}	DeleteManyResponse	"Successfully deleted documents"
-//	@Failure		400		{object}	map[string]string	"Bad request - missing required fields or invalid JSON"
+//	@Success		200		{object}	DeleteManyConfirmationResponse	"Confirmation required (DELETE_CONFIRMATION, no confirmToken)"
+//	@Failure		400		{object}	map[string]string	"Bad request - missing required fields, invalid JSON, empty filter without confirmAll, or invalid confirmToken"
 //	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
 //	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
 //	@Failure		500		{object}	map[string]string	"Internal server error"
@@ -799,6 +1580,13 @@ func (h *DataAPIHandler) DeleteMany(c echo.Context) error {
 			"error": "database and collection are required",
 		})
 	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "deleteMany", req.Database, req.Collection)
 
 	if req.Filter == nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -806,14 +1594,12 @@ func (h *DataAPIHandler) DeleteMany(c echo.Context) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "deleteMany", 10*time.Second)
 	defer cancel()
 
 	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
+		return serverError(c, err, "Failed to get collection: "+err.Error())
 	}
 
 	filter, err := h.buildFilter(req.Filter)
@@ -823,32 +1609,97 @@ func (h *DataAPIHandler) DeleteMany(c echo.Context) error {
 		})
 	}
 
-	result, err := collection.DeleteMany(ctx, filter)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
+	if !destructiveFilterAllowed(h.cfg, filter, req.ConfirmAll) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": errEmptyDestructiveFilter,
 		})
 	}
+	filter = scopeFilter(h.cfg, c, filter)
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 0, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":       true,
+			"deletedCount": count,
+		})
+	}
+
+	// Two-phase delete: the first call only counts and issues a token bound to this
+	// namespace and filter; the delete runs when the token comes back with the same filter
+	if h.cfg.DeleteConfirmation {
+		binding, err := deleteBinding(req.Database, req.Collection, filter)
+		if err != nil {
+			return serverError(c, err, "Failed to encode filter: "+err.Error())
+		}
+
+		if req.ConfirmToken == "" {
+			count, err := dryRunCount(ctx, collection, filter, 0, operationComment(c))
+			if err != nil {
+				return serverError(c, err, err.Error())
+			}
+			token, expires, err := h.confirmations.issue(binding)
+			if err != nil {
+				return serverError(c, err, "Failed to generate confirmation token: "+err.Error())
+			}
+			return c.JSON(http.StatusOK, map[string]interface{}{
+				"dryRun":       true,
+				"deletedCount": count,
+				"confirmToken": token,
+				"expiresAt":    expires.UTC().Format(time.RFC3339),
+			})
+		}
+
+		if !h.confirmations.redeem(req.ConfirmToken, binding) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "confirmToken is invalid, expired, already used, or was issued for a different filter",
+			})
+		}
+	}
+
+	var ids []interface{}
+	var idsTruncated bool
+	if req.ReturnIds {
+		if ids, idsTruncated, err = affectedIDs(ctx, collection, filter, h.cfg.MaxReturnIDs, operationComment(c)); err != nil {
+			return serverError(c, err, "Failed to collect affected ids: "+err.Error())
+		}
+	}
+
+	result, err := collection.DeleteMany(ctx, filter, options.Delete().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+
+	response := map[string]interface{}{
 		"deletedCount": result.DeletedCount,
-	})
+	}
+	if req.ReturnIds {
+		response["affectedIds"] = ids
+		if idsTruncated {
+			response["affectedIdsTruncated"] = true
+		}
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
 // Helper functions to build MongoDB query objects
 
+// buildFilter reads filter as Extended JSON, like the filter query parameter of the REST
+// routes, so binary fields, ObjectIds and dates can be matched with {"$binary"}, {"$oid"}
+// and {"$date"}
 func (h *DataAPIHandler) buildFilter(filter interface{}) (bson.M, error) {
 	if filter == nil {
 		return bson.M{}, nil
 	}
 
-	filterBytes, err := bson.Marshal(filter)
+	result, err := extJSONDocument(filter)
 	if err != nil {
 		return nil, err
 	}
 
-	var result bson.M
-	if err := bson.Unmarshal(filterBytes, &result); err != nil {
+	if err := checkFilterComplexity(h.cfg, result); err != nil {
 		return nil, err
 	}
 
@@ -870,7 +1721,7 @@ func (h *DataAPIHandler) buildSort(sort interface{}) (bson.D, error) {
 		return nil, err
 	}
 
-	return result, nil
+	return withTiebreaker(h.cfg, result), nil
 }
 
 func (h *DataAPIHandler) buildUpdate(update interface{}) (bson.M, error) {
@@ -878,24 +1729,50 @@ func (h *DataAPIHandler) buildUpdate(update interface{}) (bson.M, error) {
 		return nil, nil
 	}
 
-	updateBytes, err := bson.Marshal(update)
+	result, err := extJSONDocument(update)
 	if err != nil {
 		return nil, err
 	}
 
-	var result bson.M
-	if err := bson.Unmarshal(updateBytes, &result); err != nil {
-		return nil, err
-	}
-
 	// If update doesn't have operators like $set, $unset, etc., wrap it in $set
 	if !hasUpdateOperators(result) {
 		return bson.M{"$set": result}, nil
 	}
 
+	if err := validateArithmeticOperands(result); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
+// arithmeticOperators are the update operators that only accept numeric operands
+var arithmeticOperators = []string{"$inc", "$mul"}
+
+// validateArithmeticOperands rejects $inc and $mul operands that are not numbers, which
+// MongoDB would otherwise fail as a write error. Numbers keep the BSON type chosen by
+// extJSONDocument, so $max, $min and $mul compare and multiply the values as sent.
+func validateArithmeticOperands(update bson.M) error {
+	for _, operator := range arithmeticOperators {
+		operand, ok := update[operator]
+		if !ok {
+			continue
+		}
+		fields, ok := operand.(bson.M)
+		if !ok {
+			return fmt.Errorf("%s must be a document of field: number pairs", operator)
+		}
+		for field, value := range fields {
+			switch value.(type) {
+			case int32, int64, float64, primitive.Decimal128:
+			default:
+				return fmt.Errorf("%s: %s must be a number", operator, field)
+			}
+		}
+	}
+	return nil
+}
+
 // hasUpdateOperators checks if the update document contains MongoDB update operators
 func hasUpdateOperators(update bson.M) bool {
 	for key := range update {
@@ -922,5 +1799,12 @@ func (h *DataAPIHandler) buildProjection(projection interface{}) (bson.M, error)
 		return nil, err
 	}
 
+	if err := normalizeSlices(result); err != nil {
+		return nil, err
+	}
+	if err := checkProjectionMode(result); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
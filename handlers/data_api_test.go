@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/config"
+)
+
+// decodeJSON decodes a request body fragment the way echo's binder does
+func decodeJSON(t *testing.T, body string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		t.Fatalf("invalid test JSON %s: %v", body, err)
+	}
+	return value
+}
+
+func TestBuildUpdate(t *testing.T) {
+	decimal, _ := primitive.ParseDecimal128("0.1")
+	date := primitive.NewDateTimeFromTime(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC))
+	tests := []struct {
+		name    string
+		update  string
+		want    bson.M
+		wantErr bool
+	}{
+		{"$max keeps an int32", `{"$max": {"score": 10}}`, bson.M{"$max": bson.M{"score": int32(10)}}, false},
+		{"$max keeps an int64", `{"$max": {"views": 3000000000}}`, bson.M{"$max": bson.M{"views": int64(3000000000)}}, false},
+		{"$min keeps a double", `{"$min": {"price": 9.5}}`, bson.M{"$min": bson.M{"price": 9.5}}, false},
+		{"$min compares dates", `{"$min": {"firstSeen": {"$date": "2024-01-15T10:30:00Z"}}}`, bson.M{"$min": bson.M{"firstSeen": date}}, false},
+		{"$max compares strings", `{"$max": {"grade": "B"}}`, bson.M{"$max": bson.M{"grade": "B"}}, false},
+		{"$mul by a double", `{"$mul": {"price": 1.25}}`, bson.M{"$mul": bson.M{"price": 1.25}}, false},
+		{"$mul by a decimal", `{"$mul": {"balance": {"$numberDecimal": "0.1"}}}`, bson.M{"$mul": bson.M{"balance": decimal}}, false},
+		{"$inc and $max together", `{"$inc": {"n": 1}, "$max": {"best": 7}}`, bson.M{"$inc": bson.M{"n": int32(1)}, "$max": bson.M{"best": int32(7)}}, false},
+		{"a plain document becomes $set", `{"name": "Ann", "age": 30}`, bson.M{"$set": bson.M{"name": "Ann", "age": int32(30)}}, false},
+		{"$mul by a string", `{"$mul": {"price": "2"}}`, nil, true},
+		{"$inc by null", `{"$inc": {"n": null}}`, nil, true},
+		{"$mul not a document", `{"$mul": 2}`, nil, true},
+	}
+	h := &DataAPIHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.buildUpdate(decodeJSON(t, tt.update))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("buildUpdate(%s) = %v, want an error", tt.update, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildUpdate(%s): %v", tt.update, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("buildUpdate(%s) = %#v, want %#v", tt.update, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildUpdateNil(t *testing.T) {
+	got, err := (&DataAPIHandler{}).buildUpdate(nil)
+	if got != nil || err != nil {
+		t.Fatalf("buildUpdate(nil) = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestBuildProjection(t *testing.T) {
+	tests := []struct {
+		name       string
+		projection string
+		want       bson.M
+		wantErr    bool
+	}{
+		{"inclusion without _id", `{"name": 1, "_id": 0}`, bson.M{"name": float64(1), "_id": float64(0)}, false},
+		{"$slice count becomes an integer", `{"comments": {"$slice": 5}, "title": 1}`, bson.M{"comments": bson.M{"$slice": int64(5)}, "title": float64(1)}, false},
+		{"mixed", `{"name": 1, "password": 0}`, nil, true},
+		{"mixed dotted paths", `{"address.city": 1, "address.zip": 0}`, nil, true},
+		{"invalid $slice", `{"comments": {"$slice": [1, 0]}}`, nil, true},
+	}
+	h := &DataAPIHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.buildProjection(decodeJSON(t, tt.projection))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("buildProjection(%s) = %v, want an error", tt.projection, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildProjection(%s): %v", tt.projection, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("buildProjection(%s) = %#v, want %#v", tt.projection, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFilterMatchesBinaryFromResponse(t *testing.T) {
+	uuid := primitive.Binary{Subtype: 0x04, Data: []byte{0x3b, 0x24, 0x1f, 0x6c, 0x0d, 0x4e, 0x4a, 0x8e, 0x9f, 0x3e, 0x6f, 0x13, 0x64, 0x2c, 0x7a, 0x01}}
+	token := primitive.Binary{Subtype: 0x00, Data: []byte("secret-token")}
+
+	// A document as findOne returns it, decoded by the client
+	raw, err := json.Marshal(extJSONValue(bson.M{"_id": uuid, "token": token}, false))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		t.Fatalf("unmarshal %s: %v", raw, err)
+	}
+
+	h := &DataAPIHandler{cfg: &config.Config{}}
+	tests := []struct {
+		name   string
+		filter map[string]interface{}
+		want   bson.M
+	}{
+		{"equality", map[string]interface{}{"_id": document["_id"]}, bson.M{"_id": uuid}},
+		{"$in", map[string]interface{}{"token": map[string]interface{}{"$in": []interface{}{document["token"]}}}, bson.M{"token": bson.M{"$in": bson.A{token}}}},
+		{"two fields", map[string]interface{}{"_id": document["_id"], "token": document["token"]}, bson.M{"_id": uuid, "token": token}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.buildFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("buildFilter: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("buildFilter = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// duplicateInMiddle fails an insertMany on its third document with a duplicate key. Like the
+// driver, it reports the _id of every document, attempted or not, alongside the error.
+type duplicateInMiddle struct{}
+
+func (duplicateInMiddle) InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	ids := make([]interface{}, len(documents))
+	for i, doc := range documents {
+		ids[i] = doc.(bson.M)["_id"]
+	}
+	err := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Index: 2, Code: duplicateKeyCode, Message: duplicateEmail}},
+	}}
+	return &mongo.InsertManyResult{InsertedIDs: ids}, err
+}
+
+func TestInsertManyDuplicateInMiddle(t *testing.T) {
+	stoppedAt := map[string]interface{}{"index": float64(2), "code": float64(duplicateKeyCode), "message": duplicateEmail}
+	tests := []struct {
+		name          string
+		ordered       string
+		insertedCount float64
+		insertedIDs   []interface{}
+		stoppedAt     interface{}
+	}{
+		// The insert stops at the duplicate, so the documents after it were never attempted
+		{"ordered", `true`, 2, []interface{}{"a", "b", nil, nil, nil}, stoppedAt},
+		{"unordered", `false`, 4, []interface{}{"a", "b", nil, "d", "e"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"database": "mydb", "collection": "users", "ordered": ` + tt.ordered + `, "documents": [
+				{"_id": "a"}, {"_id": "b"}, {"_id": "c"}, {"_id": "d"}, {"_id": "e"}]}`
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			h := &DataAPIHandler{cfg: &config.Config{}}
+			err := h.insertMany(e.NewContext(req, rec), func(dbName, collectionName string) (documentsInserter, error) {
+				return duplicateInMiddle{}, nil
+			})
+			if err != nil {
+				t.Fatalf("InsertMany: %v", err)
+			}
+			if rec.Code != http.StatusMultiStatus {
+				t.Fatalf("status = %d, want 207 (body %s)", rec.Code, rec.Body.String())
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+			}
+			if response["insertedCount"] != tt.insertedCount {
+				t.Errorf("insertedCount = %v, want %v", response["insertedCount"], tt.insertedCount)
+			}
+			if !reflect.DeepEqual(response["insertedIds"], tt.insertedIDs) {
+				t.Errorf("insertedIds = %#v, want %#v", response["insertedIds"], tt.insertedIDs)
+			}
+			if !reflect.DeepEqual(response["stoppedAt"], tt.stoppedAt) {
+				t.Errorf("stoppedAt = %#v, want %#v", response["stoppedAt"], tt.stoppedAt)
+			}
+		})
+	}
+}
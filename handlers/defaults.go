@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+// serverTimestampToken is a DEFAULTS value replaced by the time of the insert
+const serverTimestampToken = "$$NOW"
+
+// applyDefaults sets the DEFAULTS of a collection on every top-level field the document
+// lacks. Fields the client sent, even as null, are left alone.
+func applyDefaults(cfg *config.Config, database, collection string, doc bson.M, now time.Time) {
+	for field, value := range cfg.InsertDefaults(database, collection) {
+		if _, ok := doc[field]; ok {
+			continue
+		}
+		if value == serverTimestampToken {
+			value = now
+		}
+		doc[field] = value
+	}
+}
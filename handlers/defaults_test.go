@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	cfg := &config.Config{Defaults: map[string]map[string]interface{}{
+		"mydb.users": {"status": "active", "createdAt": serverTimestampToken, "tags": bson.A{"new"}},
+	}}
+	now := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		collection string
+		doc        bson.M
+		want       bson.M
+	}{
+		{"missing fields get the defaults", "users",
+			bson.M{"name": "Ann"},
+			bson.M{"name": "Ann", "status": "active", "createdAt": now, "tags": bson.A{"new"}}},
+		{"client value wins", "users",
+			bson.M{"status": "invited", "createdAt": "2020-01-01", "tags": bson.A{}},
+			bson.M{"status": "invited", "createdAt": "2020-01-01", "tags": bson.A{}}},
+		{"explicit null wins", "users",
+			bson.M{"status": nil},
+			bson.M{"status": nil, "createdAt": now, "tags": bson.A{"new"}}},
+		{"a nested field does not count as the top-level one", "users",
+			bson.M{"profile": bson.M{"status": "x"}},
+			bson.M{"profile": bson.M{"status": "x"}, "status": "active", "createdAt": now, "tags": bson.A{"new"}}},
+		{"collections without DEFAULTS are untouched", "events",
+			bson.M{"name": "Ann"},
+			bson.M{"name": "Ann"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyDefaults(cfg, "mydb", tt.collection, tt.doc, now)
+			if !reflect.DeepEqual(tt.doc, tt.want) {
+				t.Fatalf("doc = %v, want %v", tt.doc, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsServerTimestamp(t *testing.T) {
+	cfg := &config.Config{Defaults: map[string]map[string]interface{}{
+		"mydb.users": {"createdAt": serverTimestampToken, "note": "$$NOW is only replaced as a whole value"},
+	}}
+	now := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	first, second := bson.M{}, bson.M{}
+	applyDefaults(cfg, "mydb", "users", first, now)
+	applyDefaults(cfg, "mydb", "users", second, now)
+
+	if got, ok := first["createdAt"].(time.Time); !ok || !got.Equal(now) {
+		t.Fatalf("createdAt = %#v, want the insert time as a time.Time", first["createdAt"])
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("documents of one insert got different defaults: %v and %v", first, second)
+	}
+	if first["note"] != "$$NOW is only replaced as a whole value" {
+		t.Fatalf("note = %v, want the configured string unchanged", first["note"])
+	}
+}
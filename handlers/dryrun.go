@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dryRunHeader makes write handlers validate the request and report its predicted effect without writing
+const dryRunHeader = "X-Dry-Run"
+
+// isDryRun reports whether the request asked for a dry run
+func isDryRun(c echo.Context) bool {
+	enabled, err := strconv.ParseBool(c.Request().Header.Get(dryRunHeader))
+	return err == nil && enabled
+}
+
+// dryRunCount counts the documents a write with the given filter would affect.
+// A limit of 0 counts every match; single-document operations pass 1.
+func dryRunCount(ctx context.Context, collection *mongo.Collection, filter interface{}, limit int64, comment string) (int64, error) {
+	countOptions := options.Count().SetComment(comment)
+	if limit > 0 {
+		countOptions.SetLimit(limit)
+	}
+	return collection.CountDocuments(ctx, filter, countOptions)
+}
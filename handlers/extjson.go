@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// UTCDatesHeader turns UTC date normalization on or off for one response, overriding UTC_DATES
+const UTCDatesHeader = "X-UTC-Dates"
+
+// utcDateLayout renders dates as RFC 3339 in UTC with the millisecond precision of BSON dates
+const utcDateLayout = "2006-01-02T15:04:05.000Z"
+
+// ExtJSONSerializer is echo's JSON serializer with BSON types that encoding/json cannot
+// represent faithfully rendered as canonical Extended JSON: Timestamp as {"$timestamp"}
+// and Binary (including UUIDs) as {"$binary"} with its subtype. Both forms are accepted
+// back on write, so these values survive a read-modify-write round trip. Decimal128 is
+// rendered as its exact decimal string, never as a JSON number a client would parse into
+// a float.
+//
+// With UTCDates (or the X-UTC-Dates header), dates are rendered as RFC 3339 UTC strings
+// with exactly three fractional digits, e.g. "2024-01-15T10:30:00.000Z".
+//
+// Successful responses to requests marked by the EchoNamespace middleware also carry the
+// database and collection the handler recorded with metrics.Track.
+type ExtJSONSerializer struct {
+	echo.DefaultJSONSerializer
+	UTCDates bool
+}
+
+// Serialize converts BSON values in i before encoding it as JSON
+func (s ExtJSONSerializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	utcDates := s.UTCDates
+	if enabled, err := strconv.ParseBool(c.Request().Header.Get(UTCDatesHeader)); err == nil {
+		utcDates = enabled
+	}
+	if response, ok := i.(map[string]interface{}); ok && auth.EchoesNamespace(c) {
+		i = withNamespace(c, response)
+	}
+	return s.DefaultJSONSerializer.Serialize(c, extJSONValue(i, utcDates), indent)
+}
+
+// withNamespace returns a copy of response with the database and collection of the
+// request added, without replacing keys the handler set itself. Error responses are
+// map[string]string and never reach it. multiFind spans collections and tracks "*",
+// so only its database is added.
+func withNamespace(c echo.Context, response map[string]interface{}) map[string]interface{} {
+	op, ok := metrics.Tracked(c)
+	if !ok {
+		return response
+	}
+
+	result := make(map[string]interface{}, len(response)+2)
+	for key, value := range response {
+		result[key] = value
+	}
+	if _, exists := result["database"]; !exists {
+		result["database"] = op.Database
+	}
+	if _, exists := result["collection"]; !exists && op.Collection != "*" {
+		result["collection"] = op.Collection
+	}
+	return result
+}
+
+// extJSONValue recursively replaces Timestamp and Binary values with their Extended JSON
+// form, Decimal128 values with their string form, and dates with UTC strings when
+// utcDates is set
+func extJSONValue(value interface{}, utcDates bool) interface{} {
+	switch v := value.(type) {
+	case primitive.DateTime:
+		if utcDates {
+			return v.Time().UTC().Format(utcDateLayout)
+		}
+		return value
+	case time.Time:
+		if utcDates {
+			return v.UTC().Format(utcDateLayout)
+		}
+		return value
+	case primitive.Timestamp:
+		return map[string]interface{}{
+			"$timestamp": map[string]uint32{"t": v.T, "i": v.I},
+		}
+	case primitive.Decimal128:
+		return v.String()
+	case primitive.Binary:
+		return map[string]interface{}{
+			"$binary": map[string]string{
+				"base64":  base64.StdEncoding.EncodeToString(v.Data),
+				"subType": fmt.Sprintf("%02x", v.Subtype),
+			},
+		}
+	case bson.M:
+		return extJSONMap(v, utcDates)
+	case map[string]interface{}:
+		return extJSONMap(v, utcDates)
+	case bson.D:
+		return extJSONMap(v.Map(), utcDates)
+	case bson.A:
+		return extJSONSlice(v, utcDates)
+	case []interface{}:
+		return extJSONSlice(v, utcDates)
+	case []bson.M:
+		result := make([]interface{}, len(v))
+		for i, doc := range v {
+			result[i] = extJSONMap(doc, utcDates)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// extJSONMap applies extJSONValue to every value of a document
+func extJSONMap(doc map[string]interface{}, utcDates bool) map[string]interface{} {
+	if doc == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(doc))
+	for key, value := range doc {
+		result[key] = extJSONValue(value, utcDates)
+	}
+	return result
+}
+
+// extJSONSlice applies extJSONValue to every element of an array
+func extJSONSlice(values []interface{}, utcDates bool) []interface{} {
+	if values == nil {
+		return nil
+	}
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = extJSONValue(value, utcDates)
+	}
+	return result
+}
+
+// bindDocument decodes the JSON body of a REST write into doc. c.Bind is not used because
+// it first copies the path parameters into the destination, which for a document would
+// store db, collection and id as fields, and which panics on a nil map.
+func bindDocument(c echo.Context, doc *bson.M) error {
+	return json.NewDecoder(c.Request().Body).Decode(doc)
+}
+
+// extJSONDocument converts a JSON-decoded request document to BSON, interpreting Extended
+// JSON wrappers such as {"$binary"}, {"$timestamp"}, {"$oid"} and {"$date"}. Numbers keep
+// their natural BSON type: whole numbers become int32/int64 and the rest doubles.
+func extJSONDocument(document interface{}) (bson.M, error) {
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var result bson.M
+	if err := bson.UnmarshalExtJSON(raw, false, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// roundTrip renders doc as a response would and reads the JSON back as a request document
+func roundTrip(t *testing.T, doc bson.M) bson.M {
+	t.Helper()
+	raw, err := json.Marshal(extJSONValue(doc, false))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal %s: %v", raw, err)
+	}
+	result, err := extJSONDocument(decoded)
+	if err != nil {
+		t.Fatalf("extJSONDocument(%s): %v", raw, err)
+	}
+	return result
+}
+
+func TestExtJSONRoundTrip(t *testing.T) {
+	uuid := []byte{0x3b, 0x24, 0x1f, 0x6c, 0x0d, 0x4e, 0x4a, 0x8e, 0x9f, 0x3e, 0x6f, 0x13, 0x64, 0x2c, 0x7a, 0x01}
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"UUID keeps subtype 4 and its bytes", primitive.Binary{Subtype: 0x04, Data: uuid}},
+		{"legacy UUID subtype 3", primitive.Binary{Subtype: 0x03, Data: uuid}},
+		{"generic binary", primitive.Binary{Subtype: 0x00, Data: []byte("hello")}},
+		{"user-defined subtype", primitive.Binary{Subtype: 0x80, Data: []byte{0xff, 0x00}}},
+		{"timestamp", primitive.Timestamp{T: 1700000000, I: 7}},
+		{"timestamp at the uint32 limit", primitive.Timestamp{T: 4294967295, I: 4294967295}},
+		{"binary nested in an array", bson.A{primitive.Binary{Subtype: 0x04, Data: uuid}}},
+		{"timestamp nested in a document", bson.M{"at": primitive.Timestamp{T: 1, I: 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundTrip(t, bson.M{"value": tt.value})
+			if !reflect.DeepEqual(got["value"], tt.value) {
+				t.Fatalf("round trip = %#v, want %#v", got["value"], tt.value)
+			}
+		})
+	}
+}
+
+func TestExtJSONBinaryRendering(t *testing.T) {
+	got := extJSONValue(primitive.Binary{Subtype: 0x04, Data: []byte{0x01, 0x02}}, false)
+	want := map[string]interface{}{
+		"$binary": map[string]string{"base64": "AQI=", "subType": "04"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("extJSONValue = %#v, want %#v", got, want)
+	}
+}
+
+// serialize renders response through s as echo would, with the X-UTC-Dates header if set
+func serialize(t *testing.T, s ExtJSONSerializer, header string, response interface{}) string {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(UTCDatesHeader, header)
+	}
+	rec := httptest.NewRecorder()
+	if err := s.Serialize(e.NewContext(req, rec), response, ""); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	return strings.TrimSpace(rec.Body.String())
+}
+
+func TestExtJSONSerializerUTCDates(t *testing.T) {
+	instant := time.Date(2024, 1, 15, 10, 30, 0, 120_000_000, time.UTC)
+	offset := instant.In(time.FixedZone("IST", 5*3600+1800))
+	response := map[string]interface{}{
+		"createdAt": primitive.NewDateTimeFromTime(instant),
+		"updatedAt": offset,
+		"history": bson.A{
+			primitive.NewDateTimeFromTime(instant),
+			bson.M{"at": offset, "events": bson.A{primitive.NewDateTimeFromTime(instant)}},
+		},
+		"batches": []bson.M{{"at": primitive.NewDateTimeFromTime(instant)}},
+	}
+	const utc = `"2024-01-15T10:30:00.120Z"`
+	wantUTC := `{"batches":[{"at":` + utc + `}],"createdAt":` + utc +
+		`,"history":[` + utc + `,{"at":` + utc + `,"events":[` + utc + `]}],"updatedAt":` + utc + `}`
+
+	tests := []struct {
+		name     string
+		utcDates bool
+		header   string
+		wantUTC  bool
+	}{
+		{"enabled", true, "", true},
+		{"enabled by the header", false, "true", true},
+		{"disabled by the header", true, "false", false},
+		{"invalid header keeps the default", true, "yes please", true},
+		{"disabled", false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serialize(t, ExtJSONSerializer{UTCDates: tt.utcDates}, tt.header, response)
+			if tt.wantUTC && got != wantUTC {
+				t.Fatalf("body = %s, want %s", got, wantUTC)
+			}
+			if !tt.wantUTC && strings.Contains(got, utc) {
+				t.Fatalf("body = %s, want dates left to encoding/json", got)
+			}
+		})
+	}
+}
+
+func TestExtJSONSerializerUTCDatesTruncatesToMilliseconds(t *testing.T) {
+	at := time.Date(2024, 1, 15, 10, 30, 0, 999_999_999, time.FixedZone("", -7*3600))
+	got := serialize(t, ExtJSONSerializer{UTCDates: true}, "", map[string]interface{}{"at": at})
+	if want := `{"at":"2024-01-15T17:30:00.999Z"}`; got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+}
+
+func TestExtJSONSerializerDecimal128(t *testing.T) {
+	const exact = "1234567890.123456789012345678"
+	price, err := primitive.ParseDecimal128(exact)
+	if err != nil {
+		t.Fatalf("ParseDecimal128: %v", err)
+	}
+	order := bson.M{
+		"price":  price,
+		"lines":  bson.A{bson.M{"amount": price}, price},
+		"totals": bson.D{{Key: "net", Value: price}},
+	}
+	const rendered = `{"lines":[{"amount":"` + exact + `"},"` + exact + `"],"price":"` + exact + `","totals":{"net":"` + exact + `"}}`
+
+	tests := []struct {
+		name     string
+		response interface{}
+		want     string
+	}{
+		{"nested values", order, rendered},
+		{"findOne document", map[string]interface{}{"document": order}, `{"document":` + rendered + `}`},
+		{"find documents", map[string]interface{}{"documents": []bson.M{order}}, `{"documents":[` + rendered + `]}`},
+		{"aggregate documents", map[string]interface{}{"documents": []interface{}{order}}, `{"documents":[` + rendered + `]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serialize(t, ExtJSONSerializer{}, "", tt.response); got != tt.want {
+				t.Fatalf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("written back as $numberDecimal", func(t *testing.T) {
+		doc, err := extJSONDocument(map[string]interface{}{"price": map[string]interface{}{"$numberDecimal": exact}})
+		if err != nil {
+			t.Fatalf("extJSONDocument: %v", err)
+		}
+		if doc["price"] != price {
+			t.Fatalf("price = %#v, want %s", doc["price"], exact)
+		}
+	})
+}
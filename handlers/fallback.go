@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"mongodb-go-proxy/config"
+	"mongodb-go-proxy/database"
+)
+
+// ServedByHeader names the deployment that answered a read when it was not the primary
+const ServedByHeader = "X-Served-By"
+
+// servedByFallback is the servedBy value of a read answered by MONGO_URI_FALLBACK
+const servedByFallback = "fallback"
+
+// readClients are the deployments a document read may be served from
+type readClients struct {
+	cfg      *config.Config
+	primary  *database.Client
+	fallback *database.Client // nil unless MONGO_URI_FALLBACK is set
+}
+
+// readTarget is where a read succeeded. Follow-up reads of the same request, such as a
+// total count, use its context and collection so they reach the same deployment.
+type readTarget struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	collection *mongo.Collection
+	fallback   bool
+}
+
+// read runs fn against the primary under the timeout for action. When a fallback is
+// configured and the primary is known to be down, cannot be connected to, or fails fn
+// with a network error or timeout, fn runs once more against the fallback under a fresh
+// timeout. Query errors, including ErrNoDocuments, are returned as they are. The caller
+// must call cancel on the returned target, also when err is not nil.
+func (r readClients) read(action string, timeout time.Duration, db, coll string, fn func(ctx context.Context, collection *mongo.Collection) error) (readTarget, error) {
+	if r.fallback == nil || r.primary.Healthy() {
+		target, err := r.attempt(r.primary, action, timeout, db, coll, fn)
+		if r.fallback == nil || !failoverError(err) {
+			return target, err
+		}
+		target.cancel()
+	}
+
+	target, err := r.attempt(r.fallback, action, timeout, db, coll, fn)
+	target.fallback = true
+	return target, err
+}
+
+// attempt runs fn against one deployment
+func (r readClients) attempt(client *database.Client, action string, timeout time.Duration, db, coll string, fn func(ctx context.Context, collection *mongo.Collection) error) (readTarget, error) {
+	ctx, cancel := operationContext(r.cfg, action, timeout)
+	target := readTarget{ctx: ctx, cancel: cancel}
+
+	collection, err := client.GetCollection(db, coll)
+	if err != nil {
+		return target, &connectError{err: err}
+	}
+	target.collection = collection
+	return target, fn(ctx, collection)
+}
+
+// connectError marks a read that never reached MongoDB because no connection could be made
+type connectError struct {
+	err error
+}
+
+func (e *connectError) Error() string { return "Failed to get collection: " + e.err.Error() }
+func (e *connectError) Unwrap() error { return e.err }
+
+// failoverError reports whether a read failed because the deployment could not serve it,
+// rather than because of the query itself
+func failoverError(err error) bool {
+	var connect *connectError
+	return errors.As(err, &connect) || mongo.IsNetworkError(err) || mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// markServedBy flags a response answered by the fallback in the X-Served-By header and
+// under key in the body, when the body is an envelope the handler can extend
+func markServedBy(c echo.Context, target readTarget, response map[string]interface{}, key string) {
+	if !target.fallback {
+		return
+	}
+	c.Response().Header().Set(ServedByHeader, servedByFallback)
+	if response != nil {
+		response[key] = servedByFallback
+	}
+}
+
+// FailoverRoute reports whether the route of c is a read the fallback can serve, so
+// UpstreamHealth lets it through while the primary is down: the REST document reads and
+// the find and findOne actions
+func FailoverRoute(c echo.Context) bool {
+	switch c.Request().Method {
+	case http.MethodGet:
+		switch path.Base(c.Path()) {
+		case "documents", ":id", "document":
+			return true
+		}
+	case http.MethodPost:
+		switch path.Base(c.Path()) {
+		case "find", "findOne":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// documentHashField is the field ?withHash=true adds to returned documents
+const documentHashField = "_hash"
+
+// documentHash is the hex SHA-256 of the BSON encoding of doc with its keys sorted at
+// every level, so the hash depends on the content and not on the field order MongoDB
+// stored. A stored _hash field is left out, as it is replaced in the response.
+func documentHash(doc bson.M) (string, error) {
+	content := make(bson.M, len(doc))
+	for key, value := range doc {
+		if key != documentHashField {
+			content[key] = value
+		}
+	}
+
+	raw, err := bson.Marshal(canonicalDocument(content))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// withDocumentHash returns a copy of doc with its documentHash under _hash. It copies
+// because coalesced reads share the decoded document between requests.
+func withDocumentHash(doc bson.M) (bson.M, error) {
+	hash, err := documentHash(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(bson.M, len(doc)+1)
+	for key, value := range doc {
+		result[key] = value
+	}
+	result[documentHashField] = hash
+	return result, nil
+}
+
+// withDocumentHashes applies withDocumentHash to every document of a find result
+func withDocumentHashes(docs []bson.M) ([]bson.M, error) {
+	result := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		hashed, err := withDocumentHash(doc)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = hashed
+	}
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"mongodb-go-proxy/config"
+)
+
+const (
+	// ksuidEpoch is the KSUID timestamp epoch (2014-05-13T16:53:20Z)
+	ksuidEpoch = 1400000000
+	// ksuidLength is the length of a base62-encoded KSUID
+	ksuidLength = 27
+	// base62Alphabet orders digits before letters so KSUIDs sort by time
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// errIDChanged rejects a REST update whose body names a different document than its path
+var errIDChanged = errors.New("_id in the body does not match the document ID in the path; _id cannot be changed")
+
+// assignID gives a document without _id one in the ID_FORMATS format of its collection.
+// Collections without a format are left to MongoDB, which assigns an ObjectID.
+func assignID(cfg *config.Config, database, collection string, doc bson.M) error {
+	if _, ok := doc["_id"]; ok {
+		return nil
+	}
+
+	switch cfg.IDFormat(database, collection) {
+	case config.IDFormatUUID:
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		doc["_id"] = id
+	case config.IDFormatKSUID:
+		id, err := newKSUID(time.Now())
+		if err != nil {
+			return err
+		}
+		doc["_id"] = id
+	case config.IDFormatObjectID:
+		doc["_id"] = primitive.NewObjectID()
+	}
+	return nil
+}
+
+// newUUID returns a random (version 4) UUID in its canonical string form
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32], nil
+}
+
+// newKSUID returns a KSUID: a 4-byte timestamp and 16 random bytes, base62-encoded into
+// 27 characters. Their string order follows creation time to the second.
+func newKSUID(now time.Time) (string, error) {
+	var b [20]byte
+	binary.BigEndian.PutUint32(b[:4], uint32(now.Unix()-ksuidEpoch))
+	if _, err := rand.Read(b[4:]); err != nil {
+		return "", err
+	}
+
+	n := new(big.Int).SetBytes(b[:])
+	base := big.NewInt(int64(len(base62Alphabet)))
+	digit := new(big.Int)
+	encoded := make([]byte, ksuidLength)
+	for i := ksuidLength - 1; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		encoded[i] = base62Alphabet[digit.Int64()]
+	}
+	return string(encoded), nil
+}
+
+// documentID converts the {id} path segment of a REST route to the collection's _id type:
+// a string for ID_FORMATS uuid and ksuid collections, an ObjectID otherwise
+func documentID(cfg *config.Config, database, collection, raw string) (interface{}, error) {
+	switch cfg.IDFormat(database, collection) {
+	case config.IDFormatUUID, config.IDFormatKSUID:
+		return raw, nil
+	default:
+		return primitive.ObjectIDFromHex(raw)
+	}
+}
+
+// sameDocumentID reports whether an _id sent in a REST body names the document of the
+// {id} path segment. A string is read like the path segment, so both "507f..." and
+// {"$oid": "507f..."} match an ObjectID path.
+func sameDocumentID(cfg *config.Config, database, collection string, pathID, bodyID interface{}) bool {
+	if raw, ok := bodyID.(string); ok {
+		converted, err := documentID(cfg, database, collection, raw)
+		if err != nil {
+			return false
+		}
+		bodyID = converted
+	}
+	switch bodyID.(type) {
+	case string, primitive.ObjectID:
+		return bodyID == pathID
+	default:
+		return false
+	}
+}
+
+// dropBodyID removes the _id of a REST update body, which a client may echo back from a
+// read; _id is immutable, so it must not reach $set. An _id naming another document than
+// the path is rejected with errIDChanged.
+func dropBodyID(cfg *config.Config, database, collection string, pathID interface{}, doc bson.M) error {
+	bodyID, ok := doc["_id"]
+	if !ok {
+		return nil
+	}
+	if !sameDocumentID(cfg, database, collection, pathID, bodyID) {
+		return errIDChanged
+	}
+	delete(doc, "_id")
+	return nil
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"mongodb-go-proxy/config"
+)
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ksuidPattern = regexp.MustCompile(`^[0-9A-Za-z]{27}$`)
+)
+
+func idConfig() *config.Config {
+	return &config.Config{IDFormats: map[string]string{
+		"mydb.users":  config.IDFormatUUID,
+		"mydb.events": config.IDFormatKSUID,
+		"mydb.orders": config.IDFormatObjectID,
+	}}
+}
+
+func TestNewUUID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := newUUID()
+		if err != nil {
+			t.Fatalf("newUUID: %v", err)
+		}
+		if !uuidPattern.MatchString(id) {
+			t.Fatalf("newUUID = %q, want a canonical version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("newUUID repeated %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewKSUID(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	previous := ""
+	for i := 0; i < 100; i++ {
+		id, err := newKSUID(start.Add(time.Duration(i) * time.Second))
+		if err != nil {
+			t.Fatalf("newKSUID: %v", err)
+		}
+		if !ksuidPattern.MatchString(id) {
+			t.Fatalf("newKSUID = %q, want 27 base62 characters", id)
+		}
+		if id <= previous {
+			t.Fatalf("KSUID %q of a later second sorts before %q", id, previous)
+		}
+		previous = id
+	}
+
+	first, _ := newKSUID(start)
+	second, _ := newKSUID(start)
+	if first == second {
+		t.Fatalf("two KSUIDs of the same second are both %q", first)
+	}
+}
+
+func TestAssignID(t *testing.T) {
+	cfg := idConfig()
+	tests := []struct {
+		collection string
+		check      func(id interface{}) bool
+	}{
+		{"users", func(id interface{}) bool { s, ok := id.(string); return ok && uuidPattern.MatchString(s) }},
+		{"events", func(id interface{}) bool { s, ok := id.(string); return ok && ksuidPattern.MatchString(s) }},
+		{"orders", func(id interface{}) bool { oid, ok := id.(primitive.ObjectID); return ok && !oid.IsZero() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.collection, func(t *testing.T) {
+			doc := bson.M{"name": "Ann"}
+			if err := assignID(cfg, "mydb", tt.collection, doc); err != nil {
+				t.Fatalf("assignID: %v", err)
+			}
+			if !tt.check(doc["_id"]) {
+				t.Fatalf("_id = %#v, want a generated %s id", doc["_id"], cfg.IDFormat("mydb", tt.collection))
+			}
+		})
+	}
+
+	t.Run("collections without a format are left to MongoDB", func(t *testing.T) {
+		doc := bson.M{"name": "Ann"}
+		if err := assignID(cfg, "mydb", "logs", doc); err != nil {
+			t.Fatalf("assignID: %v", err)
+		}
+		if _, ok := doc["_id"]; ok {
+			t.Fatalf("_id = %#v, want none", doc["_id"])
+		}
+	})
+
+	t.Run("a client _id is kept", func(t *testing.T) {
+		for _, id := range []interface{}{"custom", int32(7), nil} {
+			doc := bson.M{"_id": id}
+			if err := assignID(cfg, "mydb", "users", doc); err != nil {
+				t.Fatalf("assignID: %v", err)
+			}
+			if doc["_id"] != id {
+				t.Fatalf("_id = %#v, want the client's %#v", doc["_id"], id)
+			}
+		}
+	})
+}
+
+func TestDocumentID(t *testing.T) {
+	cfg := idConfig()
+	oid := primitive.NewObjectID()
+	tests := []struct {
+		name       string
+		collection string
+		raw        string
+		want       interface{}
+		wantErr    bool
+	}{
+		{"uuid collections keep the string", "users", "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01", "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01", false},
+		{"ksuid collections keep the string", "events", "2bVf1m2sC2pHh6xYf7a0oJ9kQ1r", "2bVf1m2sC2pHh6xYf7a0oJ9kQ1r", false},
+		{"objectid format", "orders", oid.Hex(), oid, false},
+		{"default is an ObjectID", "logs", oid.Hex(), oid, false},
+		{"invalid hex", "logs", "not-an-id", nil, true},
+		{"short hex", "orders", "507f1f77", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := documentID(cfg, "mydb", tt.collection, tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("documentID(%q) = %#v, want an error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("documentID(%q): %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Fatalf("documentID(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameDocumentID(t *testing.T) {
+	cfg := idConfig()
+	oid := primitive.NewObjectID()
+	tests := []struct {
+		name       string
+		collection string
+		pathID     interface{}
+		bodyID     interface{}
+		want       bool
+	}{
+		{"ObjectID from $oid", "logs", oid, oid, true},
+		{"ObjectID from a hex string", "logs", oid, oid.Hex(), true},
+		{"different ObjectID", "logs", oid, primitive.NewObjectID(), false},
+		{"invalid hex string", "logs", oid, "not-an-id", false},
+		{"uuid string", "users", "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01", "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01", true},
+		{"different uuid string", "users", "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01", "00000000-0000-4000-8000-000000000000", false},
+		{"hex string in a uuid collection stays a string", "users", oid, oid.Hex(), false},
+		{"number", "logs", oid, int32(1), false},
+		{"document", "logs", oid, bson.M{"a": 1}, false},
+		{"null", "logs", oid, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameDocumentID(cfg, "mydb", tt.collection, tt.pathID, tt.bodyID); got != tt.want {
+				t.Fatalf("sameDocumentID(%#v, %#v) = %v, want %v", tt.pathID, tt.bodyID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropBodyID(t *testing.T) {
+	cfg := idConfig()
+	oid := primitive.NewObjectID()
+	tests := []struct {
+		name       string
+		collection string
+		pathID     interface{}
+		doc        bson.M
+		want       bson.M
+		wantErr    bool
+	}{
+		{"no _id", "logs", oid, bson.M{"name": "Ann"}, bson.M{"name": "Ann"}, false},
+		{"matching ObjectID is dropped", "logs", oid, bson.M{"_id": oid, "name": "Ann"}, bson.M{"name": "Ann"}, false},
+		{"matching hex string is dropped", "logs", oid, bson.M{"_id": oid.Hex(), "name": "Ann"}, bson.M{"name": "Ann"}, false},
+		{"matching uuid is dropped", "users", "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01", bson.M{"_id": "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01"}, bson.M{}, false},
+		{"different ObjectID", "logs", oid, bson.M{"_id": primitive.NewObjectID(), "name": "Ann"}, nil, true},
+		{"different uuid", "users", "3b241f6c-0d4e-4a8e-9f3e-6f13642c7a01", bson.M{"_id": "00000000-0000-4000-8000-000000000000"}, nil, true},
+		{"null", "logs", oid, bson.M{"_id": nil}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dropBodyID(cfg, "mydb", tt.collection, tt.pathID, tt.doc)
+			if tt.wantErr {
+				if err != errIDChanged {
+					t.Fatalf("dropBodyID = %v, want errIDChanged", err)
+				}
+				if _, ok := tt.doc["_id"]; !ok {
+					t.Fatal("a rejected _id was removed from the body")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dropBodyID: %v", err)
+			}
+			if !reflect.DeepEqual(tt.doc, tt.want) {
+				t.Fatalf("body = %v, want %v", tt.doc, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateDocumentRejectsBodyID(t *testing.T) {
+	h := &MongoHandler{cfg: idConfig()}
+	oid := primitive.NewObjectID()
+	tests := []struct {
+		name      string
+		body      string
+		wantError string
+	}{
+		{"different _id", `{"_id": {"$oid": "` + primitive.NewObjectID().Hex() + `"}, "name": "Ann"}`, errIDChanged.Error()},
+		{"only the matching _id", `{"_id": "` + oid.Hex() + `"}`, "Update body must contain at least one field besides _id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("db", "collection", "id")
+			c.SetParamValues("mydb", "logs", oid.Hex())
+
+			if err := h.UpdateDocument(c); err != nil {
+				t.Fatalf("UpdateDocument: %v", err)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+			}
+			if rec.Code != http.StatusBadRequest || body["error"] != tt.wantError {
+				t.Fatalf("response = %d %s, want 400 %q", rec.Code, rec.Body.String(), tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// ImportAsync godoc
+//
+//	@Summary		Import documents in the background
+//	@Description	Accepts an NDJSON request body (one Extended JSON document per line) and imports it in the background with the batching and error reporting of Stream Insert. The body is spooled to disk before the response, which carries the job id to poll at /api/v1/jobs/{id}. At most MAX_IMPORT_JOBS imports run at once.
+//	@Tags			documents
+//	@Accept			x-ndjson
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			db			path		string				true	"Database name"							example("mydb")
+//	@Param			collection	path		string				true	"Collection name"						example("users")
+//	@Param			batchSize	query		int					false	"Documents per InsertMany (max 5000)"	default(500)
+//	@Param			documents	body		string				true	"NDJSON documents"
+//	@Success		202			{object}	ImportJobStatus		"Import accepted"
+//	@Header			202			{string}	Location			"URL of the job status"
+//	@Failure		400			{object}	map[string]string	"Bad request - invalid batch size or unreadable body"
+//	@Failure		401			{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		429			{object}	map[string]string	"Too many imports running"
+//	@Failure		500			{object}	map[string]string	"Internal server error"
+//	@Failure		503			{object}	map[string]string	"The proxy is shutting down"
+//	@Router			/v1/databases/{db}/collections/{collection}/import-async [post]
+func (h *MongoHandler) ImportAsync(c echo.Context) error {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	if dbName == "" || collectionName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Database and collection names are required",
+		})
+	}
+
+	metrics.Track(c, "importAsync", dbName, collectionName)
+
+	batchSize := streamBatchSize
+	if b := c.QueryParam("batchSize"); b != "" {
+		parsed, err := parseInt64(b)
+		if err != nil || parsed <= 0 || parsed > maxStreamBatchSize {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "batchSize must be between 1 and 5000",
+			})
+		}
+		batchSize = int(parsed)
+	}
+
+	collection, err := h.dbClient.GetCollection(dbName, collectionName)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	// The slot is taken before the upload, so a busy proxy does not receive a body it cannot import
+	job, err := h.imports.start(dbName, collectionName, isDryRun(c))
+	if errors.Is(err, errImportsStopped) {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "The proxy is shutting down; retry the import on another instance",
+		})
+	}
+	if errors.Is(err, errTooManyImports) {
+		c.Response().Header().Set("Retry-After", "60")
+		return c.JSON(http.StatusTooManyRequests, map[string]string{
+			"error": fmt.Sprintf("%d import jobs are already running; try again when one finishes", h.cfg.MaxImportJobs),
+		})
+	}
+	if err != nil {
+		return serverError(c, err, "Failed to create import job: "+err.Error())
+	}
+
+	spool, err := spoolBody(h.cfg.ImportSpoolDir, c.Request().Body)
+	if err != nil {
+		h.imports.discard(job)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to read request body: " + err.Error(),
+		})
+	}
+
+	go h.runImport(job, collection, spool, batchSize, h.cfg.RowFilter(auth.KeyTier(c)), operationComment(c))
+
+	status := job.snapshot()
+	c.Response().Header().Set(echo.HeaderLocation, "/api/v1/jobs/"+status.ID)
+	return c.JSON(http.StatusAccepted, status)
+}
+
+// ImportJob godoc
+//
+//	@Summary		Get the status of an import job
+//	@Description	Returns the progress of an import started with import-async: lines read, documents inserted, malformed lines and the error that stopped it, if any. Finished jobs are kept for IMPORT_JOB_TTL.
+//	@Tags			documents
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			id	path		string				true	"Job id"	example("9f86d081884c7d659a2feaa0c55ad015")
+//	@Success		200	{object}	ImportJobStatus		"Job status"
+//	@Failure		401	{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		404	{object}	map[string]string	"Job not found or expired"
+//	@Router			/v1/jobs/{id} [get]
+func (h *MongoHandler) ImportJob(c echo.Context) error {
+	status, ok := h.imports.get(c.Param("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Job not found",
+		})
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// spoolBody copies an import body to a temporary file in dir and rewinds it, so the
+// import can outlive the request
+func spoolBody(dir string, body io.Reader) (*os.File, error) {
+	spool, err := os.CreateTemp(dir, "import-*.ndjson")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(spool, body); err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, err
+	}
+	return spool, nil
+}
+
+// runImport inserts the spooled NDJSON lines of job and removes the spool file when done
+func (h *MongoHandler) runImport(job *importJob, collection *mongo.Collection, spool *os.File, batchSize int, rowFilter map[string]interface{}, comment string) {
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	err := h.importLines(job, collection, spool, batchSize, rowFilter, comment)
+	h.imports.finish(job, err)
+	if err != nil {
+		status := job.snapshot()
+		log.Printf("Import job %s into %s.%s failed: %v", status.ID, status.Database, status.Collection, err)
+	}
+}
+
+// importLines reads body like InsertStream and flushes it in batches, stopping at the
+// first document MongoDB rejects. Documents get the rowFilter of the api-key that started
+// the import. Each batch gets the importAsync timeout; the import as a
+// whole has none, as it no longer holds a request open. Shutdown cancels the batch in
+// flight and fails the job with errImportInterrupted.
+func (h *MongoHandler) importLines(job *importJob, collection *mongo.Collection, body io.Reader, batchSize int, rowFilter map[string]interface{}, comment string) error {
+	status := job.snapshot()
+	now := time.Now()
+
+	batch := make([]interface{}, 0, batchSize)
+	batchLines := make([]int, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if h.imports.ctx.Err() != nil {
+			return errImportInterrupted
+		}
+
+		// In a dry run every decodable line counts as inserted without touching MongoDB
+		if status.DryRun {
+			n := len(batch)
+			batch, batchLines = batch[:0], batchLines[:0]
+			job.update(func(s *ImportJobStatus) { s.Inserted += int64(n) })
+			return nil
+		}
+
+		ctx, cancel := operationContext(h.cfg, "importAsync", 30*time.Second)
+		defer cancel()
+		stop := context.AfterFunc(h.imports.ctx, cancel)
+		defer stop()
+
+		result, err := collection.InsertMany(ctx, batch, options.InsertMany().SetComment(comment))
+		n := len(batch)
+		lines := batchLines
+		batch, batchLines = batch[:0], batchLines[:0]
+		if err != nil {
+			if h.imports.ctx.Err() != nil {
+				return errImportInterrupted
+			}
+			// The insert is ordered, so the documents before the first failure are in
+			if writeErrors, _, ok := bulkWriteErrors(err); ok {
+				job.update(func(s *ImportJobStatus) {
+					s.Inserted += int64(len(succeededIndexes(n, writeErrors, true)))
+					for _, we := range writeErrors {
+						s.WriteErrors = append(s.WriteErrors, StreamLineError{Line: lines[we.Index], Code: we.Code, Error: we.Message})
+					}
+				})
+			}
+			return err
+		}
+		job.update(func(s *ImportJobStatus) { s.Inserted += int64(len(result.InsertedIDs)) })
+		return nil
+	}
+
+	line := 0
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineBytes)
+	for scanner.Scan() {
+		line++
+		job.update(func(s *ImportJobStatus) { s.Lines = line })
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var doc bson.D
+		err := bson.UnmarshalExtJSON(text, false, &doc)
+		if err == nil {
+			doc, err = prepareOrdered(h.cfg, status.Database, status.Collection, doc, rowFilter, now)
+		}
+		if err != nil {
+			job.update(func(s *ImportJobStatus) {
+				s.Failed++
+				if len(s.LineErrors) < maxStreamLineErrors {
+					s.LineErrors = append(s.LineErrors, StreamLineError{Line: line, Error: err.Error()})
+				}
+			})
+			continue
+		}
+
+		batch = append(batch, doc)
+		batchLines = append(batchLines, line)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read import body: %w", err)
+	}
+	return flush()
+}
+
+// StopImports waits for running asynchronous imports until ctx is done, then cancels the
+// rest and logs each one it interrupted. It must run before the MongoDB clients close.
+func (h *MongoHandler) StopImports(ctx context.Context) {
+	for _, status := range h.imports.shutdown(ctx) {
+		log.Printf("Import job %s into %s.%s was interrupted by shutdown after %d lines, %d documents inserted", status.ID, status.Database, status.Collection, status.Lines, status.Inserted)
+	}
+}
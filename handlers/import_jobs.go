@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Import job states
+const (
+	importRunning   = "running"
+	importCompleted = "completed"
+	importFailed    = "failed"
+)
+
+var (
+	// errTooManyImports is returned when MAX_IMPORT_JOBS imports are already running
+	errTooManyImports = errors.New("too many import jobs running")
+	// errImportsStopped is returned for imports started after shutdown began
+	errImportsStopped = errors.New("the proxy is shutting down")
+	// errImportInterrupted is the error of a job cancelled by shutdown
+	errImportInterrupted = errors.New("interrupted by shutdown")
+)
+
+// ImportJobStatus is the progress of an asynchronous import
+type ImportJobStatus struct {
+	ID          string            `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`        // Job id
+	Database    string            `json:"database" example:"mydb"`                              // Database name
+	Collection  string            `json:"collection" example:"users"`                           // Collection name
+	Status      string            `json:"status" example:"running"`                             // running, completed or failed
+	DryRun      bool              `json:"dry_run,omitempty"`                                    // True when X-Dry-Run was set and nothing is written
+	Lines       int               `json:"lines" example:"1000"`                                 // Lines read so far
+	Inserted    int64             `json:"inserted" example:"998"`                               // Documents inserted so far
+	Failed      int               `json:"failed" example:"2"`                                   // Lines that could not be decoded so far
+	LineErrors  []StreamLineError `json:"line_errors,omitempty"`                                // Malformed lines (capped at 100)
+	WriteErrors []StreamLineError `json:"write_errors,omitempty"`                               // Documents rejected by MongoDB
+	Error       string            `json:"error,omitempty"`                                      // Fatal error that stopped the import
+	StartedAt   time.Time         `json:"started_at" example:"2024-01-01T02:00:00Z"`            // When the job was accepted
+	FinishedAt  *time.Time        `json:"finished_at,omitempty" example:"2024-01-01T02:41:07Z"` // When the job completed or failed
+}
+
+// importJob is one asynchronous import. Its goroutine updates status while polls read it.
+type importJob struct {
+	mu     sync.Mutex
+	status ImportJobStatus
+}
+
+// update changes the status of the job under its lock
+func (j *importJob) update(fn func(status *ImportJobStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+}
+
+// snapshot returns a copy of the status that later updates do not change
+func (j *importJob) snapshot() ImportJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := j.status
+	status.LineErrors = append([]StreamLineError(nil), j.status.LineErrors...)
+	status.WriteErrors = append([]StreamLineError(nil), j.status.WriteErrors...)
+	return status
+}
+
+// expired reports whether the job completed or failed more than ttl ago
+func (j *importJob) expired(now time.Time, ttl time.Duration) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.FinishedAt != nil && now.Sub(*j.status.FinishedAt) > ttl
+}
+
+// importJobs holds the asynchronous imports of the process. At most max run at once, and
+// finished jobs are kept for ttl so their outcome can be polled. Jobs live in memory, so
+// a restart loses them and, behind a load balancer, polls must reach the same instance.
+// Their MongoDB work runs under ctx, which shutdown cancels.
+type importJobs struct {
+	max    int
+	ttl    time.Duration
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	jobs     map[string]*importJob
+	running  int
+	stopping bool
+	wg       sync.WaitGroup
+}
+
+// newImportJobs creates the import registry of a handler
+func newImportJobs(max int, ttl time.Duration) *importJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &importJobs{max: max, ttl: ttl, ctx: ctx, cancel: cancel}
+}
+
+// start registers a running job for a namespace, dropping expired ones on the way
+func (s *importJobs) start(database, collection string, dryRun bool) (*importJob, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	job := &importJob{status: ImportJobStatus{
+		ID:         hex.EncodeToString(b[:]),
+		Database:   database,
+		Collection: collection,
+		Status:     importRunning,
+		DryRun:     dryRun,
+		StartedAt:  now.UTC(),
+	}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping {
+		return nil, errImportsStopped
+	}
+	if s.running >= s.max {
+		return nil, errTooManyImports
+	}
+	if s.jobs == nil {
+		s.jobs = make(map[string]*importJob)
+	}
+	for id, j := range s.jobs {
+		if j.expired(now, s.ttl) {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[job.status.ID] = job
+	s.running++
+	s.wg.Add(1)
+	return job, nil
+}
+
+// finish records the outcome of job and frees its slot. A failure is stored under Error.
+func (s *importJobs) finish(job *importJob, err error) {
+	job.update(func(status *ImportJobStatus) {
+		finished := time.Now().UTC()
+		status.FinishedAt = &finished
+		status.Status = importCompleted
+		if err != nil {
+			status.Status = importFailed
+			status.Error = err.Error()
+		}
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.wg.Done()
+}
+
+// discard forgets a job that never got to run, such as when its body could not be read
+func (s *importJobs) discard(job *importJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, job.status.ID)
+	s.running--
+	s.wg.Done()
+}
+
+// get returns the status of a job unless it is unknown or expired
+func (s *importJobs) get(id string) (ImportJobStatus, bool) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok || job.expired(time.Now(), s.ttl) {
+		return ImportJobStatus{}, false
+	}
+	return job.snapshot(), true
+}
+
+// shutdown refuses new jobs and waits for the running ones until ctx is done. Jobs still
+// running then are cancelled, which stops them at their current batch, and returned once
+// they have stopped.
+func (s *importJobs) shutdown(ctx context.Context) []ImportJobStatus {
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	var running []*importJob
+	for _, job := range s.jobs {
+		if job.snapshot().Status == importRunning {
+			running = append(running, job)
+		}
+	}
+	s.mu.Unlock()
+
+	s.cancel()
+	<-done
+
+	var interrupted []ImportJobStatus
+	for _, job := range running {
+		if status := job.snapshot(); status.Status == importFailed {
+			interrupted = append(interrupted, status)
+		}
+	}
+	return interrupted
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestImportJobsShutdownWaitsForFinishedJobs(t *testing.T) {
+	jobs := newImportJobs(2, time.Hour)
+	job, err := jobs.start("mydb", "users", false)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		jobs.finish(job, nil)
+	}()
+
+	if interrupted := jobs.shutdown(context.Background()); len(interrupted) != 0 {
+		t.Fatalf("interrupted = %+v, want none", interrupted)
+	}
+	if status := job.snapshot(); status.Status != importCompleted {
+		t.Fatalf("status = %s, want %s", status.Status, importCompleted)
+	}
+	if jobs.ctx.Err() != nil {
+		t.Fatal("imports were cancelled although all finished in time")
+	}
+}
+
+func TestImportJobsShutdownCancelsRunningJobs(t *testing.T) {
+	jobs := newImportJobs(2, time.Hour)
+	quick, err := jobs.start("mydb", "users", false)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	slow, err := jobs.start("mydb", "events", false)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	jobs.finish(quick, nil)
+	go func() {
+		// Like importLines, the job only stops once its context is cancelled
+		<-jobs.ctx.Done()
+		jobs.finish(slow, errImportInterrupted)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	interrupted := jobs.shutdown(ctx)
+
+	if len(interrupted) != 1 || interrupted[0].ID != slow.snapshot().ID {
+		t.Fatalf("interrupted = %+v, want only the events job", interrupted)
+	}
+	if interrupted[0].Error != errImportInterrupted.Error() {
+		t.Errorf("error = %q, want %q", interrupted[0].Error, errImportInterrupted.Error())
+	}
+	if _, err := jobs.start("mydb", "users", false); !errors.Is(err, errImportsStopped) {
+		t.Errorf("start after shutdown: err = %v, want %v", err, errImportsStopped)
+	}
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/metrics"
+)
+
+const (
+	// defaultSelectivitySampleSize is the $sample size of an index selectivity estimate
+	defaultSelectivitySampleSize = 10000
+	// maxSelectivitySampleSize caps the sampleSize of an index selectivity estimate
+	maxSelectivitySampleSize = 100000
+	// maxIndexKeyFields is the most fields MongoDB allows in a compound index
+	maxIndexKeyFields = 32
+)
+
+// Selectivity thresholds: the share of sampled documents with distinct key values
+const (
+	highSelectivity   = 0.1
+	mediumSelectivity = 0.01
+	// dominantValueShare is the share of the sample one value may hold before it is called out
+	dominantValueShare = 0.5
+)
+
+// KeyPrefixSelectivity is the selectivity of the leading fields of a candidate index key
+type KeyPrefixSelectivity struct {
+	Fields         []string `json:"fields" example:"status"`        // Leading fields of the key
+	DistinctValues int64    `json:"distinct_values" example:"4"`    // Distinct value combinations in the sample
+	Selectivity    float64  `json:"selectivity" example:"0.0004"`   // distinct_values divided by the documents sampled
+	TopValueShare  float64  `json:"top_value_share" example:"0.71"` // Share of the sample holding the most common combination
+}
+
+// IndexSelectivityResponse represents the response for an index selectivity estimate
+type IndexSelectivityResponse struct {
+	Database       string                 `json:"database" example:"mydb"`                                                       // Database name
+	Collection     string                 `json:"collection" example:"orders"`                                                   // Collection name
+	Key            json.RawMessage        `json:"key" swaggertype:"object"`                                                      // Candidate index key, in key order. Example: {"status":1,"createdAt":-1}
+	Sampled        int64                  `json:"sampled" example:"10000"`                                                       // Documents sampled
+	EstimatedCount int64                  `json:"estimated_count" example:"2500000"`                                             // Estimated documents in the collection
+	Prefixes       []KeyPrefixSelectivity `json:"prefixes"`                                                                      // Selectivity of each leading part of the key, shortest first
+	Selectivity    float64                `json:"selectivity" example:"0.38"`                                                    // Selectivity of the whole key
+	Recommendation string                 `json:"recommendation" example:"high"`                                                 // high, medium, low, or unknown for an empty collection
+	Advice         string                 `json:"advice" example:"Selective: an equality match on this key reads few documents"` // Explanation of the recommendation
+}
+
+// selectivityFacet is the summary each $facet branch produces for one key prefix
+type selectivityFacet struct {
+	Distinct int64 `bson:"distinct"`
+	Sampled  int64 `bson:"sampled"`
+	Top      int64 `bson:"top"`
+}
+
+// IndexSelectivity godoc
+//
+//	@Summary		Estimate the selectivity of a candidate index
+//	@Description	Samples a collection with $sample and counts the distinct values of a candidate index key, and of each of its leading parts, with $group. Returns the ratio of distinct values to sampled documents and a recommendation, to judge an index before building it on a large collection. Requires ADMIN_API_SECRET.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			db			path		string						true	"Database name"														example("mydb")
+//	@Param			collection	path		string						true	"Collection name"													example("orders")
+//	@Param			key			query		string						true	"Candidate index key (JSON object of field: 1 or -1)"				example("{\"status\":1,\"createdAt\":-1}")
+//	@Param			sampleSize	query		int							false	"Documents to sample (max 100000)"									default(10000)
+//	@Success		200			{object}	IndexSelectivityResponse	"Successfully estimated selectivity"
+//	@Failure		400			{object}	map[string]string			"Bad request - invalid namespace, key or sampleSize"
+//	@Failure		401			{object}	map[string]string			"Unauthorized - missing or invalid api-key"
+//	@Failure		403			{object}	map[string]string			"Forbidden - not the admin api-key"
+//	@Failure		500			{object}	map[string]string			"Internal server error"
+//	@Router			/v1/admin/databases/{db}/collections/{collection}/index-selectivity [get]
+func (h *MongoHandler) IndexSelectivity(c echo.Context) error {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	if dbName == "" || collectionName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Database and collection names are required",
+		})
+	}
+	if err := validateNamespace(dbName, collectionName); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	var key bson.D
+	if err := bson.UnmarshalExtJSON([]byte(c.QueryParam("key")), false, &key); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid key JSON: " + err.Error(),
+		})
+	}
+	if err := validateIndexKey(key); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid key: " + err.Error(),
+		})
+	}
+
+	sampleSize := int64(defaultSelectivitySampleSize)
+	if s := c.QueryParam("sampleSize"); s != "" {
+		parsed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || parsed <= 0 || parsed > maxSelectivitySampleSize {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("sampleSize must be between 1 and %d", maxSelectivitySampleSize),
+			})
+		}
+		sampleSize = parsed
+	}
+
+	metrics.Track(c, "indexSelectivity", dbName, collectionName)
+
+	ctx, cancel := operationContext(h.cfg, "indexSelectivity", 30*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(dbName, collectionName)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	estimated, err := collection.EstimatedDocumentCount(ctx, options.EstimatedDocumentCount().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+
+	cursor, err := collection.Aggregate(ctx, selectivityPipeline(key, sampleSize), options.Aggregate().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var facets []map[string][]selectivityFacet
+	if err := cursor.All(ctx, &facets); err != nil {
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	keyJSON, err := bson.MarshalExtJSON(key, false, false)
+	if err != nil {
+		return serverError(c, err, "Failed to encode index key: "+err.Error())
+	}
+
+	response := IndexSelectivityResponse{
+		Database:       dbName,
+		Collection:     collectionName,
+		Key:            keyJSON,
+		EstimatedCount: estimated,
+		Prefixes:       make([]KeyPrefixSelectivity, len(key)),
+	}
+	for i := range key {
+		prefix := KeyPrefixSelectivity{Fields: make([]string, i+1)}
+		for j := 0; j <= i; j++ {
+			prefix.Fields[j] = key[j].Key
+		}
+		// An empty sample yields no group, so the facet is empty
+		if len(facets) > 0 && len(facets[0][strconv.Itoa(i)]) > 0 {
+			summary := facets[0][strconv.Itoa(i)][0]
+			response.Sampled = summary.Sampled
+			prefix.DistinctValues = summary.Distinct
+			prefix.Selectivity = float64(summary.Distinct) / float64(summary.Sampled)
+			prefix.TopValueShare = float64(summary.Top) / float64(summary.Sampled)
+		}
+		response.Prefixes[i] = prefix
+	}
+
+	whole := response.Prefixes[len(key)-1]
+	response.Selectivity = whole.Selectivity
+	response.Recommendation, response.Advice = selectivityAdvice(response.Sampled, whole)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// validateIndexKey accepts a key of 1 to 32 distinct field paths with directions 1 or -1.
+// Text, hashed and geo keys are not estimated.
+func validateIndexKey(key bson.D) error {
+	if len(key) == 0 {
+		return fmt.Errorf("key must name at least one field")
+	}
+	if len(key) > maxIndexKeyFields {
+		return fmt.Errorf("key has more than %d fields", maxIndexKeyFields)
+	}
+	seen := make(map[string]bool, len(key))
+	for _, elem := range key {
+		if err := validateFieldPath(elem.Key); err != nil {
+			return err
+		}
+		if seen[elem.Key] {
+			return fmt.Errorf("field %s appears twice", elem.Key)
+		}
+		seen[elem.Key] = true
+		if direction := toFloat64(elem.Value); direction != 1 && direction != -1 {
+			return fmt.Errorf("direction of %s must be 1 or -1", elem.Key)
+		}
+	}
+	return nil
+}
+
+// selectivityPipeline samples the collection once and, in one $facet branch per leading
+// part of key, groups the sample by that part's values and summarizes the groups: how
+// many there are, how many documents they hold, and the size of the largest.
+func selectivityPipeline(key bson.D, sampleSize int64) mongo.Pipeline {
+	facets := bson.D{}
+	for i := range key {
+		groupKey := bson.D{}
+		for j := 0; j <= i; j++ {
+			// Field paths may be dotted, which group keys may not
+			groupKey = append(groupKey, bson.E{Key: "k" + strconv.Itoa(j), Value: "$" + key[j].Key})
+		}
+		facets = append(facets, bson.E{Key: strconv.Itoa(i), Value: bson.A{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: groupKey},
+				{Key: "n", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: nil},
+				{Key: "distinct", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "sampled", Value: bson.D{{Key: "$sum", Value: "$n"}}},
+				{Key: "top", Value: bson.D{{Key: "$max", Value: "$n"}}},
+			}}},
+		}})
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$sample", Value: bson.D{{Key: "size", Value: sampleSize}}}},
+		{{Key: "$facet", Value: facets}},
+	}
+}
+
+// selectivityAdvice turns the selectivity of the whole key into a recommendation
+func selectivityAdvice(sampled int64, whole KeyPrefixSelectivity) (string, string) {
+	if sampled == 0 {
+		return "unknown", "The collection is empty, so there is nothing to estimate"
+	}
+
+	var recommendation, advice string
+	switch {
+	case whole.Selectivity >= highSelectivity:
+		recommendation = "high"
+		advice = "Selective: an equality match on this key reads few documents, so the index is likely worth creating"
+	case whole.Selectivity >= mediumSelectivity:
+		recommendation = "medium"
+		advice = "Moderately selective: the index helps queries that match on it, more so with a more selective field added to the key"
+	default:
+		recommendation = "low"
+		advice = "Low selectivity: each value matches a large share of the documents, so the index saves little over a collection scan; add a more selective field or consider a partial index"
+	}
+	if whole.TopValueShare >= dominantValueShare {
+		advice += fmt.Sprintf(". One value holds %.0f%% of the sampled documents, and queries on it gain little from the index", whole.TopValueShare*100)
+	}
+	return recommendation, advice
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/metrics"
+)
+
+// unauthorizedCode is the MongoDB error code for an operation the user lacks privileges for
+const unauthorizedCode = 13
+
+// IndexStatsEntry reports how often one index has been used
+type IndexStatsEntry struct {
+	Name  string          `json:"name" example:"email_1"`                             // Index name
+	Key   json.RawMessage `json:"key" swaggertype:"object"`                           // Index key specification, in key order. Example: {"email":1}
+	Ops   int64           `json:"ops" example:"1523"`                                 // Number of operations that used the index since `since`
+	Since time.Time       `json:"since" example:"2024-01-15T10:30:00Z"`               // When counting started (server start or index creation)
+	Host  string          `json:"host,omitempty" example:"mongo-0.example.com:27017"` // Server that reported the statistics
+}
+
+// IndexStatsResponse represents the response for collection index statistics
+type IndexStatsResponse struct {
+	Database   string            `json:"database" example:"mydb"`    // Database name
+	Collection string            `json:"collection" example:"users"` // Collection name
+	Indexes    []IndexStatsEntry `json:"indexes"`                    // Usage statistics per index
+	Count      int               `json:"count" example:"3"`          // Number of entries
+}
+
+// indexStatsResult is one document returned by the $indexStats stage
+type indexStatsResult struct {
+	Name     string `bson:"name"`
+	Key      bson.D `bson:"key"`
+	Host     string `bson:"host"`
+	Accesses struct {
+		Ops   int64     `bson:"ops"`
+		Since time.Time `bson:"since"`
+	} `bson:"accesses"`
+}
+
+// IndexStats godoc
+//
+//	@Summary		Get index usage statistics
+//	@Description	Runs $indexStats on a collection and returns how often each index was used and since when, to find unused indexes. On replica sets and sharded clusters, each entry comes from the server that answered, so counts are per server.
+//	@Tags			collections
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			db			path		string				true	"Database name"		example("mydb")
+//	@Param			collection	path		string				true	"Collection name"	example("users")
+//	@Success		200			{object}	IndexStatsResponse	"Successfully retrieved index statistics"
+//	@Failure		400			{object}	map[string]string	"Bad request - missing database or collection"
+//	@Failure		401			{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403			{object}	map[string]string	"Forbidden - the MongoDB user lacks the indexStats privilege"
+//	@Failure		500			{object}	map[string]string	"Internal server error"
+//	@Router			/v1/databases/{db}/collections/{collection}/index-stats [get]
+func (h *MongoHandler) IndexStats(c echo.Context) error {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	if dbName == "" || collectionName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Database and collection names are required",
+		})
+	}
+
+	metrics.Track(c, "indexStats", dbName, collectionName)
+
+	ctx, cancel := operationContext(h.cfg, "indexStats", 10*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(dbName, collectionName)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{{{Key: "$indexStats", Value: bson.D{}}}}, options.Aggregate().SetComment(operationComment(c)))
+	if err != nil {
+		// The proxy's MongoDB user needs the indexStats action; say so instead of a bare 500
+		if isUnauthorized(err) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "The proxy's MongoDB user is not authorized to read index statistics (requires the indexStats privilege): " + err.Error(),
+			})
+		}
+		return serverError(c, err, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var results []indexStatsResult
+	if err := cursor.All(ctx, &results); err != nil {
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	indexes := make([]IndexStatsEntry, 0, len(results))
+	for _, result := range results {
+		// Marshal the key as Extended JSON so compound keys keep their order
+		key, err := bson.MarshalExtJSON(result.Key, false, false)
+		if err != nil {
+			return serverError(c, err, "Failed to encode index key: "+err.Error())
+		}
+		indexes = append(indexes, IndexStatsEntry{
+			Name:  result.Name,
+			Key:   key,
+			Ops:   result.Accesses.Ops,
+			Since: result.Accesses.Since,
+			Host:  result.Host,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"database":   dbName,
+		"collection": collectionName,
+		"indexes":    indexes,
+		"count":      len(indexes),
+	})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// inventoryWorkers bounds how many databases are listed concurrently
+	inventoryWorkers = 8
+	// maxInventoryDatabases caps how many databases one inventory call lists
+	maxInventoryDatabases = 200
+	// inventoryTruncatedHeader is set when more databases matched than maxInventoryDatabases
+	inventoryTruncatedHeader = "X-Inventory-Truncated"
+)
+
+// InventoryEntry represents one database and its collections in the inventory
+type InventoryEntry struct {
+	Database    string   `json:"database" example:"mydb"`                     // Database name
+	Collections []string `json:"collections" example:"[\"posts\",\"users\"]"` // Collection names, sorted
+}
+
+// Inventory godoc
+//
+//	@Summary		List every database with its collections
+//	@Description	Returns all databases (excluding HIDDEN_DATABASES) and their collections in one call. At most 200 databases are listed; narrow larger clusters with the db prefix filter.
+//	@Tags			databases
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			db	query		string				false	"Only include databases whose name starts with this prefix"	example("tenant_")
+//	@Success		200	{array}		InventoryEntry		"Successfully retrieved inventory"
+//	@Header			200	{string}	X-Inventory-Truncated	"Set to true when more than 200 databases matched"
+//	@Failure		401	{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		500	{object}	map[string]string	"Internal server error"
+//	@Router			/v1/inventory [get]
+func (h *MongoHandler) Inventory(c echo.Context) error {
+	prefix := c.QueryParam("db")
+
+	ctx, cancel := operationContext(h.cfg, "inventory", 30*time.Second)
+	defer cancel()
+
+	names, err := h.dbClient.ListDatabases(ctx)
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+
+	databases := make([]string, 0, len(names))
+	for _, name := range names {
+		if !h.cfg.IsHiddenDatabase(name) && h.cfg.IsAllowedDatabase(name) && strings.HasPrefix(name, prefix) {
+			databases = append(databases, name)
+		}
+	}
+	sort.Strings(databases)
+	if len(databases) > maxInventoryDatabases {
+		databases = databases[:maxInventoryDatabases]
+		c.Response().Header().Set(inventoryTruncatedHeader, "true")
+	}
+
+	inventory := make([]InventoryEntry, len(databases))
+	errs := make([]error, len(databases))
+	sem := make(chan struct{}, inventoryWorkers)
+	var wg sync.WaitGroup
+	for i, name := range databases {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			collections, err := h.dbClient.ListCollections(ctx, name)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if collections == nil {
+				collections = []string{}
+			}
+			sort.Strings(collections)
+			inventory[i] = InventoryEntry{Database: name, Collections: collections}
+		}(i, name)
+	}
+	wg.Wait()
+	flagSlowRead(ctx, c, h.cfg)
+
+	for i, name := range databases {
+		if errs[i] != nil {
+			return serverError(c, errs[i], name+": "+errs[i].Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, inventory)
+}
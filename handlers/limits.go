@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+
+	"mongodb-go-proxy/config"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// keyLimits returns the KEY_LIMITS caps of the api-key that authenticated the request
+func keyLimits(cfg *config.Config, c echo.Context) config.KeyLimits {
+	return cfg.LimitsFor(auth.KeyTier(c))
+}
+
+// capLimit applies a key's MaxLimit to a find limit. A limit of 0 or less means no limit,
+// so it always gets the cap; a larger limit is clamped, or rejected when the key says so.
+func capLimit(limits config.KeyLimits, limit int64) (int64, error) {
+	if limits.MaxLimit <= 0 || (limit > 0 && limit <= limits.MaxLimit) {
+		return limit, nil
+	}
+	if limit > limits.MaxLimit && limits.Reject {
+		return 0, fmt.Errorf("limit cannot exceed %d for this api-key", limits.MaxLimit)
+	}
+	return limits.MaxLimit, nil
+}
+
+// capSkip applies a key's MaxSkip to a find skip, clamping or rejecting larger values
+func capSkip(limits config.KeyLimits, skip int64) (int64, error) {
+	if limits.MaxSkip <= 0 || skip <= limits.MaxSkip {
+		return skip, nil
+	}
+	if limits.Reject {
+		return 0, fmt.Errorf("skip cannot exceed %d for this api-key", limits.MaxSkip)
+	}
+	return limits.MaxSkip, nil
+}
+
+// aggregateResultCap returns the output cap of aggregate and summarize: the key's
+// MaxAggregateResults when set, MAX_AGGREGATE_RESULTS otherwise (0 for no cap)
+func aggregateResultCap(cfg *config.Config, c echo.Context) int {
+	if limit := keyLimits(cfg, c).MaxAggregateResults; limit > 0 {
+		return limit
+	}
+	return cfg.MaxAggregateResults
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -12,18 +15,30 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"mongodb-go-proxy/config"
 	"mongodb-go-proxy/database"
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
 )
 
 // MongoHandler handles MongoDB proxy operations
 type MongoHandler struct {
 	dbClient *database.Client
+	cfg      *config.Config
+	reads    *readCoalescer
+	clients  readClients
+	imports  *importJobs
 }
 
-// NewMongoHandler creates a new MongoDB handler
-func NewMongoHandler(dbClient *database.Client) *MongoHandler {
+// NewMongoHandler creates a new MongoDB handler. fallbackClient serves document reads
+// when dbClient cannot and may be nil.
+func NewMongoHandler(dbClient, fallbackClient *database.Client, cfg *config.Config) *MongoHandler {
 	return &MongoHandler{
 		dbClient: dbClient,
+		cfg:      cfg,
+		reads:    &readCoalescer{enabled: cfg.CoalesceReads},
+		clients:  readClients{cfg: cfg, primary: dbClient, fallback: fallbackClient},
+		imports:  newImportJobs(cfg.MaxImportJobs, cfg.ImportJobTTL),
 	}
 }
 
@@ -37,42 +52,77 @@ type ListDatabasesResponse struct {
 
 // ListCollectionsResponse represents the response for listing collections
 type ListCollectionsResponse struct {
-	Database    string   `json:"database" example:"mydb"`                     // Database name
-	Collections []string `json:"collections" example:"[\"users\",\"posts\"]"` // List of collection names
-	Count       int      `json:"count" example:"2"`                           // Number of collections
+	Database    string            `json:"database" example:"mydb"`                            // Database name
+	Collections []string          `json:"collections" example:"[\"users\",\"posts\"]"`        // List of collection names
+	Types       map[string]string `json:"types" example:"users:collection,active_users:view"` // Type of each listed collection: collection, view or timeseries
+	Count       int               `json:"count" example:"2"`                                  // Number of collections
 }
 
 // FindDocumentsResponse represents the response for finding documents
 type FindDocumentsResponse struct {
-	Database   string                   `json:"database" example:"mydb"`              // Database name
-	Collection string                   `json:"collection" example:"users"`           // Collection name
-	Documents  []map[string]interface{} `json:"documents" swaggertype:"array,object"` // Array of found documents
-	Count      int                      `json:"count" example:"10"`                   // Number of documents returned
-	TotalCount int64                    `json:"total_count" example:"100"`            // Total number of documents matching the filter
+	Database   string                   `json:"database" example:"mydb"`                        // Database name
+	Collection string                   `json:"collection" example:"users"`                     // Collection name
+	Documents  []map[string]interface{} `json:"documents,omitempty" swaggertype:"array,object"` // Array of found documents (omitted with pluck)
+	Values     []interface{}            `json:"values,omitempty" swaggertype:"array,string"`    // Plucked field values, only with pluck
+	Count      int                      `json:"count" example:"10"`                             // Number of documents returned
+	TotalCount int64                    `json:"total_count" example:"100"`                      // Total number of documents matching the filter (omitted with allowPartialResults)
+	Query      map[string]interface{}   `json:"query,omitempty" swaggertype:"object"`           // Normalized query, only when echoQuery=true
+	Partial    bool                     `json:"partial,omitempty" example:"true"`               // Set with allowPartialResults: documents may be missing from unavailable shards
+	ServedBy   string                   `json:"served_by,omitempty" example:"fallback"`         // "fallback" when MONGO_URI_FALLBACK answered
 }
 
 // FindOneDocumentResponse represents the response for finding one document
 type FindOneDocumentResponse struct {
-	Database   string                 `json:"database" example:"mydb"`       // Database name
-	Collection string                 `json:"collection" example:"users"`    // Collection name
-	Document   map[string]interface{} `json:"document" swaggertype:"object"` // The found document
+	Database   string                 `json:"database" example:"mydb"`                // Database name
+	Collection string                 `json:"collection" example:"users"`             // Collection name
+	Document   map[string]interface{} `json:"document" swaggertype:"object"`          // The found document
+	ServedBy   string                 `json:"served_by,omitempty" example:"fallback"` // "fallback" when MONGO_URI_FALLBACK answered
 }
 
 // InsertDocumentResponse represents the response for inserting a document
 type InsertDocumentResponse struct {
-	Database   string                 `json:"database" example:"mydb"`                        // Database name
-	Collection string                 `json:"collection" example:"users"`                     // Collection name
-	InsertedID string                 `json:"inserted_id" example:"507f1f77bcf86cd799439011"` // The ID of the inserted document
-	Document   map[string]interface{} `json:"document" swaggertype:"object"`                  // The inserted document
+	Database     string                 `json:"database" example:"mydb"`                        // Database name
+	Collection   string                 `json:"collection" example:"users"`                     // Collection name
+	InsertedID   string                 `json:"inserted_id" example:"507f1f77bcf86cd799439011"` // The ID of the inserted document
+	Document     map[string]interface{} `json:"document" swaggertype:"object"`                  // The inserted document
+	Acknowledged *bool                  `json:"acknowledged,omitempty" example:"false"`         // false when the write was sent without acknowledgement (w=0, status 202)
 }
 
 // UpdateDocumentResponse represents the response for updating a document
 type UpdateDocumentResponse struct {
+	Database          string                 `json:"database" example:"mydb"`                                               // Database name
+	Collection        string                 `json:"collection" example:"users"`                                            // Collection name
+	DocumentID        string                 `json:"document_id" example:"507f1f77bcf86cd799439011"`                        // Document ID
+	MatchedCount      int64                  `json:"matched_count" example:"1"`                                             // Number of documents matched
+	ModifiedCount     int64                  `json:"modified_count" example:"1"`                                            // Number of documents modified (omitted with returnVersion)
+	Version           interface{}            `json:"version,omitempty" swaggertype:"string" example:"2024-01-15T10:30:00Z"` // VERSION_FIELD of the updated document, only with returnVersion
+	UpdateDescription map[string]interface{} `json:"update_description,omitempty" swaggertype:"object"`                     // updated_fields, removed_fields and computed_fields of the update, only with describeChanges and when the document changed
+}
+
+// TouchDocumentResponse represents the response for touching a document
+type TouchDocumentResponse struct {
 	Database      string `json:"database" example:"mydb"`                        // Database name
 	Collection    string `json:"collection" example:"users"`                     // Collection name
 	DocumentID    string `json:"document_id" example:"507f1f77bcf86cd799439011"` // Document ID
+	Field         string `json:"field" example:"lastSeen"`                       // Timestamp field that was set
 	MatchedCount  int64  `json:"matched_count" example:"1"`                      // Number of documents matched
 	ModifiedCount int64  `json:"modified_count" example:"1"`                     // Number of documents modified
+	UpsertedCount int64  `json:"upserted_count" example:"0"`                     // Number of documents upserted
+}
+
+// UnsetFieldsRequest represents the request body for removing fields from a document
+type UnsetFieldsRequest struct {
+	Fields []string `json:"fields" example:"[\"legacyField\",\"profile.oldAddress\"]"` // Field paths to remove; dotted paths are supported
+}
+
+// UnsetFieldsResponse represents the response for removing fields from a document
+type UnsetFieldsResponse struct {
+	Database      string   `json:"database" example:"mydb"`                        // Database name
+	Collection    string   `json:"collection" example:"users"`                     // Collection name
+	DocumentID    string   `json:"document_id" example:"507f1f77bcf86cd799439011"` // Document ID
+	Fields        []string `json:"fields" example:"[\"legacyField\"]"`             // Field paths that were unset
+	MatchedCount  int64    `json:"matched_count" example:"1"`                      // Number of documents matched
+	ModifiedCount int64    `json:"modified_count" example:"1"`                     // Number of documents modified
 }
 
 // DeleteDocumentResponse represents the response for deleting a document
@@ -86,7 +136,7 @@ type DeleteDocumentResponse struct {
 // ListDatabases godoc
 //
 //	@Summary		List all databases
-//	@Description	Returns a list of all database names
+//	@Description	Returns a list of all database names, excluding those configured in HIDDEN_DATABASES and, when set, those missing from ALLOWED_DATABASES
 //	@Tags			databases
 //	@Accept			json
 //	@Produce		json
@@ -96,14 +146,20 @@ type DeleteDocumentResponse struct {
 //	@Failure		500	{object}	map[string]string		"Internal server error"
 //	@Router			/v1/databases [get]
 func (h *MongoHandler) ListDatabases(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "listDatabases", 10*time.Second)
 	defer cancel()
 
-	databases, err := h.dbClient.ListDatabases(ctx)
+	names, err := h.dbClient.ListDatabases(ctx)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	databases := make([]string, 0, len(names))
+	for _, name := range names {
+		if !h.cfg.IsHiddenDatabase(name) && h.cfg.IsAllowedDatabase(name) {
+			databases = append(databases, name)
+		}
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -115,16 +171,17 @@ func (h *MongoHandler) ListDatabases(c echo.Context) error {
 // ListCollections godoc
 //
 //	@Summary		List collections in a database
-//	@Description	Returns a list of all collection names in the specified database
+//	@Description	Returns the collection names in the specified database with the type of each: collection, view or timeseries. The type query parameter keeps only collections of one type.
 //	@Tags			collections
 //	@Accept			json
 //	@Produce		json
 //	@Security		ApiKeyAuth
-//	@Param			db	path		string					true	"Database name"	example("mydb")
-//	@Success		200	{object}	ListCollectionsResponse	"Successfully retrieved collection list"
-//	@Failure		400	{object}	map[string]string		"Bad request - invalid database name"
-//	@Failure		401	{object}	map[string]string		"Unauthorized - missing or invalid api-key"
-//	@Failure		500	{object}	map[string]string		"Internal server error"
+//	@Param			db		path		string					true	"Database name"	example("mydb")
+//	@Param			type	query		string					false	"Only list collections of this type"	Enums(collection, view, timeseries)
+//	@Success		200		{object}	ListCollectionsResponse	"Successfully retrieved collection list"
+//	@Failure		400		{object}	map[string]string		"Bad request - invalid database name or type"
+//	@Failure		401		{object}	map[string]string		"Unauthorized - missing or invalid api-key"
+//	@Failure		500		{object}	map[string]string		"Internal server error"
 //	@Router			/v1/databases/{db}/collections [get]
 func (h *MongoHandler) ListCollections(c echo.Context) error {
 	dbName := c.Param("db")
@@ -134,19 +191,37 @@ func (h *MongoHandler) ListCollections(c echo.Context) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	metrics.Track(c, "listCollections", dbName, "")
+
+	collType := c.QueryParam("type")
+	switch collType {
+	case "", database.CollectionTypeCollection, database.CollectionTypeView, database.CollectionTypeTimeseries:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "type must be collection, view or timeseries",
+		})
+	}
+
+	ctx, cancel := operationContext(h.cfg, "listCollections", 10*time.Second)
 	defer cancel()
 
-	collections, err := h.dbClient.ListCollections(ctx, dbName)
+	infos, err := h.dbClient.ListCollectionTypes(ctx, dbName, collType)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	collections := make([]string, len(infos))
+	types := make(map[string]string, len(infos))
+	for i, info := range infos {
+		collections[i] = info.Name
+		types[info.Name] = info.Type
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"database":    dbName,
 		"collections": collections,
+		"types":       types,
 		"count":       len(collections),
 	})
 }
@@ -165,9 +240,20 @@ func (h *MongoHandler) ListCollections(c echo.Context) error {
 //	@Param			limit		query		int						false	"Limit number of results"		default(100)	example(100)
 //	@Param			skip		query		int						false	"Skip number of results"		default(0)		example(0)
 //	@Param			sort		query		string					false	"Sort criteria (JSON string)"	example("{\"name\":1}")
+//	@Param			echoQuery	query		bool					false	"Include the normalized query in the response"	default(false)
+//	@Param			pluck		query		string					false	"Return only this field's values as a flat values array instead of documents"	example("email")
+//	@Param			pluckNulls	query		bool					false	"With pluck, return null for documents missing the field instead of skipping them"	default(false)
+//	@Param			stats		query		bool					false	"Include execution statistics (runs an explain)"	default(false)
+//	@Param			allowPartialResults	query	bool				false	"On sharded clusters, return results from the shards that respond instead of failing when one is unavailable. Results may be incomplete; total_count and the Link header are omitted"	default(false)
+//	@Param			omitNull	query		bool					false	"Recursively drop keys whose value is null from the returned documents"	default(false)
+//	@Param			omitEmpty	query		bool					false	"With omitNull, also drop empty strings and empty arrays"	default(false)
+//	@Param			withHash	query		bool					false	"Add an _hash field with the SHA-256 of each document's content"	default(false)
+//	@Param			sizeOnly	query		bool					false	"Return only count and estimated_bytes of every matching document instead of a page of documents"	default(false)
 //	@Success		200			{object}	FindDocumentsResponse	"Successfully retrieved documents"
+//	@Header			200			{string}	Link					"RFC 5988 pagination links (first, prev, next)"
 //	@Failure		400			{object}	map[string]string		"Bad request - invalid filter, sort, limit, or skip"
 //	@Failure		401			{object}	map[string]string		"Unauthorized - missing or invalid api-key"
+//	@Failure		404			{object}	map[string]string		"Not found - collection does not exist (STRICT_COLLECTIONS)"
 //	@Failure		500			{object}	map[string]string		"Internal server error"
 //	@Router			/v1/databases/{db}/collections/{collection}/documents [get]
 func (h *MongoHandler) FindDocuments(c echo.Context) error {
@@ -180,12 +266,7 @@ func (h *MongoHandler) FindDocuments(c echo.Context) error {
 		})
 	}
 
-	collection, err := h.dbClient.GetCollection(dbName, collectionName)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
-	}
+	metrics.Track(c, "findDocuments", dbName, collectionName)
 
 	// Parse query parameters
 	filterStr := c.QueryParam("filter")
@@ -204,6 +285,20 @@ func (h *MongoHandler) FindDocuments(c echo.Context) error {
 		}
 	}
 
+	// Apply the api-key's KEY_LIMITS caps
+	var err error
+	limits := keyLimits(h.cfg, c)
+	if limit, err = capLimit(limits, limit); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if skip, err = capSkip(limits, skip); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
 	// Build filter
 	var filter bson.M
 	if filterStr != "" {
@@ -212,9 +307,24 @@ func (h *MongoHandler) FindDocuments(c echo.Context) error {
 				"error": "Invalid filter JSON: " + err.Error(),
 			})
 		}
+		if err := checkFilterComplexity(h.cfg, filter); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid filter: " + err.Error(),
+			})
+		}
 	} else {
 		filter = bson.M{}
 	}
+	filter = scopeFilter(h.cfg, c, filter)
+
+	pluck := c.QueryParam("pluck")
+	if pluck != "" {
+		if err := validateFieldPath(pluck); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid pluck: " + err.Error(),
+			})
+		}
+	}
 
 	// Build sort
 	var sort bson.D
@@ -225,46 +335,158 @@ func (h *MongoHandler) FindDocuments(c echo.Context) error {
 			})
 		}
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	sort = withTiebreaker(h.cfg, sort)
 
 	// Build find options
-	findOptions := options.Find().SetLimit(limit).SetSkip(skip)
+	findOptions := options.Find().SetLimit(limit).SetSkip(skip).SetComment(operationComment(c))
 	if len(sort) > 0 {
 		findOptions.SetSort(sort)
 	}
+	projection := listProjection(h.cfg, dbName, collectionName, nil)
+	if pluck != "" {
+		projection = pluckProjection(pluck)
+	}
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
 
-	cursor, err := collection.Find(ctx, filter, findOptions)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+	partial := queryFlag(c, "allowPartialResults")
+	if partial {
+		findOptions.SetAllowPartialResults(true)
 	}
-	defer cursor.Close(ctx)
 
-	var results []bson.M
-	if err := cursor.All(ctx, &results); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
+	// Sizing reads the whole result set, so it skips the page and every other option
+	if queryFlag(c, "sizeOnly") {
+		var size resultSize
+		target, err := h.clients.read("findDocuments", 30*time.Second, dbName, collectionName, func(ctx context.Context, collection *mongo.Collection) error {
+			var err error
+			size, err = measureFind(ctx, collection, filter, projection, operationComment(c))
+			return err
 		})
+		defer target.cancel()
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+
+		response := map[string]interface{}{
+			"database":        dbName,
+			"collection":      collectionName,
+			"count":           size.Count,
+			"estimated_bytes": size.Bytes,
+		}
+		if size.Truncated {
+			response["truncated"] = true
+		}
+		markServedBy(c, target, response, "served_by")
+		return c.JSON(http.StatusOK, response)
 	}
 
-	// Get total count
-	count, err := collection.CountDocuments(ctx, filter)
+	var results []bson.M
+	var elapsed time.Duration
+	target, err := h.clients.read("findDocuments", 30*time.Second, dbName, collectionName, func(ctx context.Context, collection *mongo.Collection) error {
+		started := time.Now()
+		cursor, err := collection.Find(ctx, filter, findOptions)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		if err := cursor.All(ctx, &results); err != nil {
+			return err
+		}
+		elapsed = time.Since(started)
+		return nil
+	})
+	defer target.cancel()
+	flagSlowRead(target.ctx, c, h.cfg)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return serverError(c, err, err.Error())
+	}
+	// The total count and the stats explain go to the deployment that answered
+	ctx, collection := target.ctx, target.collection
+	query := slowFind{
+		action: "findDocuments", collection: collection, filter: filter, sort: sort,
+		projection: projection, limit: limit, skip: skip,
+	}
+	logSlowFind(c, h.cfg, elapsed, query)
+	accountFind(c, h.cfg, query, results)
+	if len(results) == 0 {
+		missing, err := strictMissing(ctx, h.cfg, collection)
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		if missing {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": missingCollectionMessage(collection),
+			})
+		}
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"database":    dbName,
-		"collection":  collectionName,
-		"documents":   results,
-		"count":       len(results),
-		"total_count": count,
-	})
+	// Hashes cover the documents as read, before any display options strip them
+	if pluck == "" && queryFlag(c, "withHash") {
+		if results, err = withDocumentHashes(results); err != nil {
+			return serverError(c, err, "Failed to hash documents: "+err.Error())
+		}
+	}
+
+	// Stripping copies the documents, so it only runs when asked for
+	if pluck == "" && queryFlag(c, "omitNull") {
+		results = omitNullsAll(results, queryFlag(c, "omitEmpty"))
+	}
+
+	response := map[string]interface{}{
+		"database":   dbName,
+		"collection": collectionName,
+		"documents":  results,
+		"count":      len(results),
+	}
+	markServedBy(c, target, response, "served_by")
+	if pluck != "" {
+		values := pluckValues(results, pluck, queryFlag(c, "pluckNulls"))
+		delete(response, "documents")
+		response["values"] = values
+		response["count"] = len(values)
+	}
+
+	// Counting would wait on the shards a partial read chose not to wait for, and
+	// pages of a partial read are not stable, so both are left out
+	if partial {
+		response["partial"] = true
+	} else {
+		count, err := collection.CountDocuments(ctx, filter, options.Count().SetComment(operationComment(c)))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		response["total_count"] = count
+		c.Response().Header().Set("Link", paginationLinks(c, skip, limit, count))
+	}
+
+	// Echo the normalized query so clients can derive stable cache keys
+	if queryFlag(c, "echoQuery") {
+		echoed, err := queryEcho(filter, sort, projection, limit, skip)
+		if err != nil {
+			return serverError(c, err, "Failed to encode query: "+err.Error())
+		}
+		response["query"] = echoed
+	}
+
+	// Execution statistics cost an explain round trip, so they are opt-in
+	if queryFlag(c, "stats") {
+		stats := map[string]interface{}{
+			"elapsed_ms": elapsed.Milliseconds(),
+		}
+		if explained, err := explainFind(ctx, collection, filter, sort, projection, limit, skip); err != nil {
+			stats["explain_error"] = err.Error()
+		} else {
+			stats["docs_examined"] = explained.DocsExamined
+			stats["keys_examined"] = explained.KeysExamined
+			stats["n_returned"] = explained.Returned
+			stats["execution_time_ms"] = explained.ExecutionMillis
+		}
+		response["stats"] = stats
+	}
+
+	return c.JSON(http.StatusOK, response)
 }
 
 // FindOne godoc
@@ -279,10 +501,13 @@ func (h *MongoHandler) FindDocuments(c echo.Context) error {
 //	@Param			collection	path		string					true	"Collection name"				example("users")
 //	@Param			filter		query		string					false	"MongoDB filter (JSON string)"	example("{\"name\":\"John\"}")
 //	@Param			sort		query		string					false	"Sort criteria (JSON string)"	example("{\"name\":1}")
+//	@Param			omitNull	query		bool					false	"Recursively drop keys whose value is null from the returned document"	default(false)
+//	@Param			omitEmpty	query		bool					false	"With omitNull, also drop empty strings and empty arrays"	default(false)
+//	@Param			withHash	query		bool					false	"Add an _hash field with the SHA-256 of the document's content"	default(false)
 //	@Success		200			{object}	FindOneDocumentResponse	"Successfully retrieved document"
 //	@Failure		400			{object}	map[string]string		"Bad request - invalid filter or sort"
 //	@Failure		401			{object}	map[string]string		"Unauthorized - missing or invalid api-key"
-//	@Failure		404			{object}	map[string]string		"Not found - document not found"
+//	@Failure		404			{object}	map[string]string		"Not found - document not found, or collection does not exist (STRICT_COLLECTIONS)"
 //	@Failure		500			{object}	map[string]string		"Internal server error"
 //	@Router			/v1/databases/{db}/collections/{collection}/document [get]
 func (h *MongoHandler) FindOne(c echo.Context) error {
@@ -295,12 +520,7 @@ func (h *MongoHandler) FindOne(c echo.Context) error {
 		})
 	}
 
-	collection, err := h.dbClient.GetCollection(dbName, collectionName)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
-	}
+	metrics.Track(c, "findOne", dbName, collectionName)
 
 	// Parse query parameters
 	filterStr := c.QueryParam("filter")
@@ -314,9 +534,15 @@ func (h *MongoHandler) FindOne(c echo.Context) error {
 				"error": "Invalid filter JSON: " + err.Error(),
 			})
 		}
+		if err := checkFilterComplexity(h.cfg, filter); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid filter: " + err.Error(),
+			})
+		}
 	} else {
 		filter = bson.M{}
 	}
+	filter = scopeFilter(h.cfg, c, filter)
 
 	// Build sort
 	var sort bson.D
@@ -327,40 +553,63 @@ func (h *MongoHandler) FindOne(c echo.Context) error {
 			})
 		}
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	sort = withTiebreaker(h.cfg, sort)
 
 	// Build find options
-	findOptions := options.FindOne()
+	findOptions := options.FindOne().SetComment(operationComment(c))
 	if len(sort) > 0 {
 		findOptions.SetSort(sort)
 	}
 
 	var result bson.M
-	err = collection.FindOne(ctx, filter, findOptions).Decode(&result)
+	target, err := h.clients.read("findOne", 10*time.Second, dbName, collectionName, func(ctx context.Context, collection *mongo.Collection) error {
+		var err error
+		result, err = h.reads.findOne(ctx, collection, filter, findOptions, h.cfg.Timeout("findOne", 10*time.Second))
+		return err
+	})
+	defer target.cancel()
+	flagSlowRead(target.ctx, c, h.cfg)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
+			missing, err := strictMissing(target.ctx, h.cfg, target.collection)
+			if err != nil {
+				return serverError(c, err, err.Error())
+			}
+			if missing {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": missingCollectionMessage(target.collection),
+				})
+			}
 			return c.JSON(http.StatusNotFound, map[string]string{
 				"error": "Document not found",
 			})
 		}
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return serverError(c, err, err.Error())
 	}
+	accountDocuments(c, h.cfg, result)
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	if queryFlag(c, "withHash") {
+		if result, err = withDocumentHash(result); err != nil {
+			return serverError(c, err, "Failed to hash document: "+err.Error())
+		}
+	}
+	if queryFlag(c, "omitNull") {
+		result = omitNulls(result, queryFlag(c, "omitEmpty"))
+	}
+
+	response := map[string]interface{}{
 		"database":   dbName,
 		"collection": collectionName,
 		"document":   result,
-	})
+	}
+	markServedBy(c, target, response, "served_by")
+	return c.JSON(http.StatusOK, response)
 }
 
 // InsertDocument godoc
 //
 //	@Summary		Insert a document
-//	@Description	Insert a new document into a collection
+//	@Description	Insert a new document into a collection. The Location header points at the created document.
 //	@Tags			documents
 //	@Accept			json
 //	@Produce		json
@@ -369,11 +618,30 @@ func (h *MongoHandler) FindOne(c echo.Context) error {
 //	@Param			collection	path		string					true	"Collection name"			example("users")
 //	@Param			document	body		object					true	"Document to insert (JSON)"	example({"name":"John","age":30})
 //	@Success		201			{object}	InsertDocumentResponse	"Successfully inserted document"
+//	@Success		202			{object}	InsertDocumentResponse	"Sent without acknowledgement (w=0); acknowledged is false"
+//	@Header			201			{string}	Location				"URL of the created document"
 //	@Failure		400			{object}	map[string]string		"Bad request - invalid JSON body"
 //	@Failure		401			{object}	map[string]string		"Unauthorized - missing or invalid api-key"
+//	@Failure		409			{object}	map[string]string		"Conflict - duplicate key on a unique index"
 //	@Failure		500			{object}	map[string]string		"Internal server error"
 //	@Router			/v1/databases/{db}/collections/{collection}/documents [post]
 func (h *MongoHandler) InsertDocument(c echo.Context) error {
+	return h.insertDocument(c, func(dbName, collectionName string) (documentInserter, error) {
+		collection, err := h.dbClient.GetCollection(dbName, collectionName)
+		if err != nil {
+			return nil, err
+		}
+		return collection, nil
+	})
+}
+
+// documentInserter is the part of *mongo.Collection that InsertDocument writes through
+type documentInserter interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+}
+
+// insertDocument serves InsertDocument, inserting through the collection getCollection returns
+func (h *MongoHandler) insertDocument(c echo.Context, getCollection func(dbName, collectionName string) (documentInserter, error)) error {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
 
@@ -383,42 +651,94 @@ func (h *MongoHandler) InsertDocument(c echo.Context) error {
 		})
 	}
 
+	metrics.Track(c, "insertDocument", dbName, collectionName)
+
 	var document bson.M
-	if err := c.Bind(&document); err != nil {
+	if err := bindDocument(c, &document); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid JSON body: " + err.Error(),
 		})
 	}
+	document, err := extJSONDocument(document)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid document: " + err.Error(),
+		})
+	}
+	if err := restrictDocument(h.cfg, dbName, collectionName, document); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	applyDefaults(h.cfg, dbName, collectionName, document, time.Now())
+	applyTransforms(h.cfg, dbName, collectionName, document)
+	if err := scopeDocument(h.cfg, c, document); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err := assignID(h.cfg, dbName, collectionName, document); err != nil {
+		return serverError(c, err, "Failed to generate _id: "+err.Error())
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "insertDocument", 10*time.Second)
 	defer cancel()
 
-	collection, err := h.dbClient.GetCollection(dbName, collectionName)
+	collection, err := getCollection(dbName, collectionName)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	if isDryRun(c) {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dry_run":        true,
+			"database":       dbName,
+			"collection":     collectionName,
+			"inserted_count": 1,
 		})
 	}
 
-	result, err := collection.InsertOne(ctx, document)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
+	result, err := collection.InsertOne(ctx, document, options.InsertOne().SetComment(operationComment(c)))
+	acknowledged := !unacknowledged(err)
+	if err != nil && acknowledged {
+		auth.RecordUpstreamError(c, err)
+		status, message := writeFailure(err)
+		return c.JSON(status, map[string]string{
+			"error": message,
 		})
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
+	// Point the Location header at the created resource
+	insertedID := document["_id"]
+	if result != nil {
+		insertedID = result.InsertedID
+	}
+	if oid, ok := insertedID.(primitive.ObjectID); ok {
+		insertedID = oid.Hex()
+	}
+	if id, ok := insertedID.(string); ok {
+		c.Response().Header().Set(echo.HeaderLocation,
+			"/api/v1/databases/"+url.PathEscape(dbName)+"/collections/"+url.PathEscape(collectionName)+"/documents/"+url.PathEscape(id))
+	}
+
+	response := map[string]interface{}{
 		"database":    dbName,
 		"collection":  collectionName,
-		"inserted_id": result.InsertedID,
+		"inserted_id": insertedID,
 		"document":    document,
-	})
+	}
+	// A w=0 write returns before the server applied it, so it is only accepted
+	if !acknowledged {
+		response["acknowledged"] = false
+		return c.JSON(http.StatusAccepted, response)
+	}
+	return c.JSON(http.StatusCreated, response)
 }
 
 // UpdateDocument godoc
 //
 //	@Summary		Update a document
-//	@Description	Update a document by ID
+//	@Description	Update a document by ID. The body fields are set with $set. An _id in the body is ignored when it matches the path ID and rejected otherwise.
 //	@Tags			documents
 //	@Accept			json
 //	@Produce		json
@@ -427,8 +747,10 @@ func (h *MongoHandler) InsertDocument(c echo.Context) error {
 //	@Param			collection	path		string					true	"Collection name"			example("users")
 //	@Param			id			path		string					true	"Document ID"				example("507f1f77bcf86cd799439011")
 //	@Param			document	body		object					true	"Update document (JSON)"	example({"name":"Jane","age":31})
+//	@Param			returnVersion	query	bool					false	"Return the VERSION_FIELD value of the updated document instead of modified_count"	default(false)
+//	@Param			describeChanges	query	bool					false	"Return an update_description of the fields written, computed from the update"	default(false)
 //	@Success		200			{object}	UpdateDocumentResponse	"Successfully updated document"
-//	@Failure		400			{object}	map[string]string		"Bad request - invalid document ID or JSON body"
+//	@Failure		400			{object}	map[string]string		"Bad request - invalid document ID or JSON body, or _id in the body differs from the path"
 //	@Failure		401			{object}	map[string]string		"Unauthorized - missing or invalid api-key"
 //	@Failure		404			{object}	map[string]string		"Not found - document not found"
 //	@Failure		500			{object}	map[string]string		"Internal server error"
@@ -444,7 +766,9 @@ func (h *MongoHandler) UpdateDocument(c echo.Context) error {
 		})
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(docID)
+	metrics.Track(c, "updateDocument", dbName, collectionName)
+
+	id, err := documentID(h.cfg, dbName, collectionName, docID)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid document ID: " + err.Error(),
@@ -452,31 +776,346 @@ func (h *MongoHandler) UpdateDocument(c echo.Context) error {
 	}
 
 	var updateDoc bson.M
-	if err := c.Bind(&updateDoc); err != nil {
+	if err := bindDocument(c, &updateDoc); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid JSON body: " + err.Error(),
 		})
 	}
+	updateDoc, err = extJSONDocument(updateDoc)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid document: " + err.Error(),
+		})
+	}
+	if err := dropBodyID(h.cfg, dbName, collectionName, id, updateDoc); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if len(updateDoc) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Update body must contain at least one field besides _id",
+		})
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "updateDocument", 10*time.Second)
 	defer cancel()
 
 	collection, err := h.dbClient.GetCollection(dbName, collectionName)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
+		return serverError(c, err, "Failed to get collection: "+err.Error())
 	}
 
-	filter := bson.M{"_id": objectID}
+	filter := scopeFilter(h.cfg, c, bson.M{"_id": id})
 	update := bson.M{"$set": updateDoc}
+	if err := restrictUpdate(h.cfg, dbName, collectionName, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err := guardRowFilterUpdate(h.cfg, c, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	transformUpdate(h.cfg, dbName, collectionName, update)
+
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 1, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dry_run":       true,
+			"database":      dbName,
+			"collection":    collectionName,
+			"document_id":   docID,
+			"matched_count": count,
+		})
+	}
+
+	if queryFlag(c, "returnVersion") {
+		var document bson.M
+		updateOptions := options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(pluckProjection(h.cfg.VersionField)).
+			SetComment(operationComment(c))
+		err := collection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&document)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "Document not found",
+				})
+			}
+			return serverError(c, err, err.Error())
+		}
+
+		version, _ := lookupPath(document, h.cfg.VersionField)
+		response := map[string]interface{}{
+			"database":      dbName,
+			"collection":    collectionName,
+			"document_id":   docID,
+			"matched_count": 1,
+			"version":       version,
+		}
+		if queryFlag(c, "describeChanges") {
+			response["update_description"] = describeUpdate(update, false).snakeCase()
+		}
+		return c.JSON(http.StatusOK, response)
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update, options.Update().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+
+	if result.MatchedCount == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Document not found",
+		})
+	}
+
+	response := map[string]interface{}{
+		"database":       dbName,
+		"collection":     collectionName,
+		"document_id":    docID,
+		"matched_count":  result.MatchedCount,
+		"modified_count": result.ModifiedCount,
+	}
+	if queryFlag(c, "describeChanges") && result.ModifiedCount > 0 {
+		response["update_description"] = describeUpdate(update, false).snakeCase()
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// TouchDocument godoc
+//
+//	@Summary		Touch a document
+//	@Description	Set a timestamp field on a document to the current server time without sending an update body
+//	@Tags			documents
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			db			path		string					true	"Database name"								example("mydb")
+//	@Param			collection	path		string					true	"Collection name"							example("users")
+//	@Param			id			path		string					true	"Document ID"								example("507f1f77bcf86cd799439011")
+//	@Param			field		query		string					false	"Timestamp field to set"					default(lastSeen)
+//	@Param			upsert		query		bool					false	"Create the document if it does not exist"	default(false)
+//	@Success		200			{object}	TouchDocumentResponse	"Successfully touched document"
+//	@Failure		400			{object}	map[string]string		"Bad request - invalid document ID or field name"
+//	@Failure		401			{object}	map[string]string		"Unauthorized - missing or invalid api-key"
+//	@Failure		404			{object}	map[string]string		"Not found - document not found"
+//	@Failure		500			{object}	map[string]string		"Internal server error"
+//	@Router			/v1/databases/{db}/collections/{collection}/documents/{id}/touch [post]
+func (h *MongoHandler) TouchDocument(c echo.Context) error {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+	docID := c.Param("id")
+
+	if dbName == "" || collectionName == "" || docID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Database, collection, and document ID are required",
+		})
+	}
+
+	metrics.Track(c, "touchDocument", dbName, collectionName)
+
+	id, err := documentID(h.cfg, dbName, collectionName, docID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid document ID: " + err.Error(),
+		})
+	}
+
+	field := c.QueryParam("field")
+	if field == "" {
+		field = "lastSeen"
+	}
+	if err := validateFieldPath(field); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid field: " + err.Error(),
+		})
+	}
+	if field == "_id" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid field: _id cannot be touched",
+		})
+	}
+
+	upsert := false
+	if u := c.QueryParam("upsert"); u != "" {
+		parsed, err := strconv.ParseBool(u)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid upsert: " + err.Error(),
+			})
+		}
+		upsert = parsed
+	}
+
+	ctx, cancel := operationContext(h.cfg, "touchDocument", 10*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(dbName, collectionName)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	filter := scopeFilter(h.cfg, c, bson.M{"_id": id})
+	update := bson.M{"$currentDate": bson.M{field: true}}
+	if err := restrictUpdate(h.cfg, dbName, collectionName, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err := guardRowFilterUpdate(h.cfg, c, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 1, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dry_run":       true,
+			"database":      dbName,
+			"collection":    collectionName,
+			"document_id":   docID,
+			"matched_count": count,
+		})
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(upsert).SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+
+	if result.MatchedCount == 0 && result.UpsertedCount == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Document not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"database":       dbName,
+		"collection":     collectionName,
+		"document_id":    docID,
+		"field":          field,
+		"matched_count":  result.MatchedCount,
+		"modified_count": result.ModifiedCount,
+		"upserted_count": result.UpsertedCount,
+	})
+}
+
+// UnsetFields godoc
+//
+//	@Summary		Remove fields from a document
+//	@Description	Remove one or more fields (dotted paths supported) from a document by ID using $unset
+//	@Tags			documents
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			db			path		string				true	"Database name"		example("mydb")
+//	@Param			collection	path		string				true	"Collection name"	example("users")
+//	@Param			id			path		string				true	"Document ID"		example("507f1f77bcf86cd799439011")
+//	@Param			request		body		UnsetFieldsRequest	true	"Fields to remove"
+//	@Success		200			{object}	UnsetFieldsResponse	"Successfully removed fields"
+//	@Failure		400			{object}	map[string]string	"Bad request - invalid document ID or field names"
+//	@Failure		401			{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		404			{object}	map[string]string	"Not found - document not found"
+//	@Failure		500			{object}	map[string]string	"Internal server error"
+//	@Router			/v1/databases/{db}/collections/{collection}/documents/{id}/unset [post]
+func (h *MongoHandler) UnsetFields(c echo.Context) error {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+	docID := c.Param("id")
+
+	if dbName == "" || collectionName == "" || docID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Database, collection, and document ID are required",
+		})
+	}
 
-	result, err := collection.UpdateOne(ctx, filter, update)
+	metrics.Track(c, "unsetFields", dbName, collectionName)
+
+	id, err := documentID(h.cfg, dbName, collectionName, docID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid document ID: " + err.Error(),
+		})
+	}
+
+	var req UnsetFieldsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid JSON body: " + err.Error(),
+		})
+	}
+
+	if len(req.Fields) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "fields array is required and cannot be empty",
+		})
+	}
+
+	unset := bson.M{}
+	for _, field := range req.Fields {
+		if err := validateFieldPath(field); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid field " + strconv.Quote(field) + ": " + err.Error(),
+			})
+		}
+		if field == "_id" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid field: _id cannot be removed",
+			})
+		}
+		unset[field] = ""
+	}
+
+	ctx, cancel := operationContext(h.cfg, "unsetFields", 10*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(dbName, collectionName)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	filter := scopeFilter(h.cfg, c, bson.M{"_id": id})
+	update := bson.M{"$unset": unset}
+	if err := restrictUpdate(h.cfg, dbName, collectionName, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
 		})
 	}
+	if err := guardRowFilterUpdate(h.cfg, c, update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 1, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dry_run":       true,
+			"database":      dbName,
+			"collection":    collectionName,
+			"document_id":   docID,
+			"matched_count": count,
+		})
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update, options.Update().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
 
 	if result.MatchedCount == 0 {
 		return c.JSON(http.StatusNotFound, map[string]string{
@@ -488,6 +1127,7 @@ func (h *MongoHandler) UpdateDocument(c echo.Context) error {
 		"database":       dbName,
 		"collection":     collectionName,
 		"document_id":    docID,
+		"fields":         req.Fields,
 		"matched_count":  result.MatchedCount,
 		"modified_count": result.ModifiedCount,
 	})
@@ -521,29 +1161,41 @@ func (h *MongoHandler) DeleteDocument(c echo.Context) error {
 		})
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(docID)
+	metrics.Track(c, "deleteDocument", dbName, collectionName)
+
+	id, err := documentID(h.cfg, dbName, collectionName, docID)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid document ID: " + err.Error(),
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := operationContext(h.cfg, "deleteDocument", 10*time.Second)
 	defer cancel()
 
 	collection, err := h.dbClient.GetCollection(dbName, collectionName)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	filter := scopeFilter(h.cfg, c, bson.M{"_id": id})
+	if isDryRun(c) {
+		count, err := dryRunCount(ctx, collection, filter, 1, operationComment(c))
+		if err != nil {
+			return serverError(c, err, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dry_run":       true,
+			"database":      dbName,
+			"collection":    collectionName,
+			"document_id":   docID,
+			"deleted_count": count,
 		})
 	}
 
-	filter := bson.M{"_id": objectID}
-	result, err := collection.DeleteOne(ctx, filter)
+	result, err := collection.DeleteOne(ctx, filter, options.Delete().SetComment(operationComment(c)))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return serverError(c, err, err.Error())
 	}
 
 	if result.DeletedCount == 0 {
@@ -571,6 +1223,9 @@ func (h *MongoHandler) DeleteDocument(c echo.Context) error {
 //	@Param			db			path		string					true	"Database name"		example("mydb")
 //	@Param			collection	path		string					true	"Collection name"	example("users")
 //	@Param			id			path		string					true	"Document ID"		example("507f1f77bcf86cd799439011")
+//	@Param			omitNull	query		bool					false	"Recursively drop keys whose value is null from the returned document"	default(false)
+//	@Param			omitEmpty	query		bool					false	"With omitNull, also drop empty strings and empty arrays"	default(false)
+//	@Param			withHash	query		bool					false	"Add an _hash field with the SHA-256 of the document's content"	default(false)
 //	@Success		200			{object}	map[string]interface{}	"Successfully retrieved document"
 //	@Failure		400			{object}	map[string]string		"Bad request - invalid document ID"
 //	@Failure		401			{object}	map[string]string		"Unauthorized - missing or invalid api-key"
@@ -588,40 +1243,120 @@ func (h *MongoHandler) GetDocument(c echo.Context) error {
 		})
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(docID)
+	metrics.Track(c, "getDocument", dbName, collectionName)
+
+	id, err := documentID(h.cfg, dbName, collectionName, docID)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid document ID: " + err.Error(),
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	collection, err := h.dbClient.GetCollection(dbName, collectionName)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get collection: " + err.Error(),
-		})
-	}
-
 	var result bson.M
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&result)
+	target, err := h.clients.read("getDocument", 10*time.Second, dbName, collectionName, func(ctx context.Context, collection *mongo.Collection) error {
+		var err error
+		result, err = h.reads.findOne(ctx, collection, scopeFilter(h.cfg, c, bson.M{"_id": id}), options.FindOne().SetComment(operationComment(c)), h.cfg.Timeout("getDocument", 10*time.Second))
+		return err
+	})
+	defer target.cancel()
+	flagSlowRead(target.ctx, c, h.cfg)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.JSON(http.StatusNotFound, map[string]string{
 				"error": "Document not found",
 			})
 		}
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return serverError(c, err, err.Error())
+	}
+	accountDocuments(c, h.cfg, result)
+
+	if queryFlag(c, "withHash") {
+		if result, err = withDocumentHash(result); err != nil {
+			return serverError(c, err, "Failed to hash document: "+err.Error())
+		}
+	}
+	if queryFlag(c, "omitNull") {
+		result = omitNulls(result, queryFlag(c, "omitEmpty"))
 	}
 
+	// The document is the whole body, so only the header can say who served it
+	markServedBy(c, target, nil, "")
 	return c.JSON(http.StatusOK, result)
 }
 
+// DocumentExists godoc
+//
+//	@Summary		Check whether a document exists
+//	@Description	Answers 200 if a document with the ID exists and 404 otherwise, without a response body
+//	@Tags			documents
+//	@Security		ApiKeyAuth
+//	@Param			db			path	string	true	"Database name"		example("mydb")
+//	@Param			collection	path	string	true	"Collection name"	example("users")
+//	@Param			id			path	string	true	"Document ID"		example("507f1f77bcf86cd799439011")
+//	@Success		200			"Document exists"
+//	@Failure		400			"Bad request - invalid document ID"
+//	@Failure		401			"Unauthorized - missing or invalid api-key"
+//	@Failure		404			"Not found - document not found"
+//	@Failure		500			"Internal server error"
+//	@Router			/v1/databases/{db}/collections/{collection}/documents/{id} [head]
+func (h *MongoHandler) DocumentExists(c echo.Context) error {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+	docID := c.Param("id")
+
+	if dbName == "" || collectionName == "" || docID == "" {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	metrics.Track(c, "documentExists", dbName, collectionName)
+
+	id, err := documentID(h.cfg, dbName, collectionName, docID)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	ctx, cancel := operationContext(h.cfg, "documentExists", 10*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(dbName, collectionName)
+	if err != nil {
+		auth.RecordUpstreamError(c, err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	count, err := collection.CountDocuments(ctx, scopeFilter(h.cfg, c, bson.M{"_id": id}), options.Count().SetLimit(1).SetComment(operationComment(c)))
+	if err != nil {
+		auth.RecordUpstreamError(c, err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	flagSlowRead(ctx, c, h.cfg)
+	if count == 0 {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
 // Helper function to parse int64
 func parseInt64(s string) (int64, error) {
 	return strconv.ParseInt(s, 10, 64)
 }
+
+// validateFieldPath checks that a (possibly dotted) field path is safe to use as an update key
+func validateFieldPath(path string) error {
+	if path == "" {
+		return errors.New("field name cannot be empty")
+	}
+	if strings.ContainsRune(path, 0) {
+		return errors.New("field name cannot contain null bytes")
+	}
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			return errors.New("field path cannot contain empty segments")
+		}
+		if strings.HasPrefix(part, "$") {
+			return errors.New("field name cannot start with '$'")
+		}
+	}
+	return nil
+}
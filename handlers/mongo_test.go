@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/config"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// fakeInserter records the document InsertDocument writes and fails with err if set.
+// Like the driver, it reports a new ObjectID for a document without _id.
+type fakeInserter struct {
+	inserted bson.M
+	err      error
+}
+
+func (f *fakeInserter) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	f.inserted = document.(bson.M)
+	if f.err != nil {
+		return nil, f.err
+	}
+	id, ok := f.inserted["_id"]
+	if !ok {
+		id = primitive.NewObjectID()
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+// postDocument sends body to InsertDocument for mydb.<collection> with the full api-key
+func postDocument(t *testing.T, cfg *config.Config, inserter *fakeInserter, collection, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("api-key", "full-secret")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("db", "collection")
+	c.SetParamValues("mydb", collection)
+
+	h := &MongoHandler{cfg: cfg}
+	insert := func(c echo.Context) error {
+		return h.insertDocument(c, func(dbName, collectionName string) (documentInserter, error) {
+			return inserter, nil
+		})
+	}
+	if err := auth.WriteAuth("full-secret")(insert)(c); err != nil {
+		t.Fatalf("InsertDocument: %v", err)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+	}
+	return rec, response
+}
+
+func TestInsertDocumentIDFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		body       string
+		check      func(id interface{}) bool
+	}{
+		{"uuid", "users", `{"name": "Ann"}`, func(id interface{}) bool { s, ok := id.(string); return ok && uuidPattern.MatchString(s) }},
+		{"ksuid", "events", `{"name": "Ann"}`, func(id interface{}) bool { s, ok := id.(string); return ok && ksuidPattern.MatchString(s) }},
+		{"objectid", "orders", `{"name": "Ann"}`, func(id interface{}) bool { oid, ok := id.(primitive.ObjectID); return ok && !oid.IsZero() }},
+		{"no format leaves _id to MongoDB", "logs", `{"name": "Ann"}`, func(id interface{}) bool { return id == nil }},
+		{"client _id is kept", "users", `{"_id": "custom", "name": "Ann"}`, func(id interface{}) bool { return id == "custom" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inserter := &fakeInserter{}
+			rec, response := postDocument(t, idConfig(), inserter, tt.collection, tt.body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body.String())
+			}
+			if !tt.check(inserter.inserted["_id"]) {
+				t.Fatalf("inserted _id = %#v, want a %s id", inserter.inserted["_id"], idConfig().IDFormat("mydb", tt.collection))
+			}
+			// The path parameters must not end up in the stored document
+			for field := range inserter.inserted {
+				if field != "_id" && field != "name" {
+					t.Fatalf("inserted %v, want only name and _id", inserter.inserted)
+				}
+			}
+
+			id, _ := response["inserted_id"].(string)
+			if id == "" {
+				t.Fatalf("inserted_id = %#v, want the id as a string", response["inserted_id"])
+			}
+			if location := rec.Header().Get(echo.HeaderLocation); !strings.HasSuffix(location, "/collections/"+tt.collection+"/documents/"+id) {
+				t.Fatalf("Location = %q, want the document %s", location, id)
+			}
+		})
+	}
+}
+
+func TestInsertDocumentDefaults(t *testing.T) {
+	cfg := &config.Config{Defaults: map[string]map[string]interface{}{
+		"mydb.users": {"status": "active", "createdAt": serverTimestampToken},
+	}}
+	inserter := &fakeInserter{}
+	rec, response := postDocument(t, cfg, inserter, "users", `{"name": "Ann", "status": "invited"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body.String())
+	}
+
+	if inserter.inserted["status"] != "invited" {
+		t.Fatalf("status = %#v, want the client's value", inserter.inserted["status"])
+	}
+	if _, ok := inserter.inserted["createdAt"].(time.Time); !ok {
+		t.Fatalf("createdAt = %#v, want the insert time", inserter.inserted["createdAt"])
+	}
+	document, _ := response["document"].(map[string]interface{})
+	if _, ok := document["createdAt"]; !ok {
+		t.Fatalf("response document %v does not show the default", document)
+	}
+}
+
+func TestInsertDocumentDuplicateKey(t *testing.T) {
+	inserter := &fakeInserter{err: mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: duplicateKeyCode, Message: duplicateEmail}}}}
+	rec, response := postDocument(t, &config.Config{}, inserter, "users", `{"email": "a@b.c"}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 (body %s)", rec.Code, rec.Body.String())
+	}
+	if want := `Duplicate key { email: "a@b.c" } violates unique index email_1`; response["error"] != want {
+		t.Fatalf("error = %v, want %q", response["error"], want)
+	}
+	if rec.Header().Get(echo.HeaderLocation) != "" {
+		t.Fatal("a failed insert set Location")
+	}
+}
+
+func TestInsertDocumentOtherWriteError(t *testing.T) {
+	inserter := &fakeInserter{err: mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121, Message: "Document failed validation"}}}}
+	rec, _ := postDocument(t, &config.Config{}, inserter, "users", `{"email": "a@b.c"}`)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500 (body %s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestInsertDocumentUnacknowledged(t *testing.T) {
+	inserter := &fakeInserter{err: mongo.ErrUnacknowledgedWrite}
+	rec, response := postDocument(t, idConfig(), inserter, "users", `{"name": "Ann"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202 (body %s)", rec.Code, rec.Body.String())
+	}
+	if response["acknowledged"] != false {
+		t.Fatalf("acknowledged = %#v, want false", response["acknowledged"])
+	}
+	// The driver returns no result for a w=0 write, so the id comes from the document
+	if id, _ := response["inserted_id"].(string); id == "" || id != inserter.inserted["_id"] {
+		t.Fatalf("inserted_id = %#v, want the generated _id %#v", response["inserted_id"], inserter.inserted["_id"])
+	}
+}
+
+func TestInsertDocumentTransforms(t *testing.T) {
+	cfg := &config.Config{
+		WriteTransforms: map[string]map[string][]config.FieldTransform{
+			"mydb.users": {
+				"email": {{Op: config.TransformTrim}, {Op: config.TransformLowercase}},
+				"role":  {{Op: config.TransformDefault, Value: "member"}},
+			},
+		},
+	}
+	inserter := &fakeInserter{}
+	rec, _ := postDocument(t, cfg, inserter, "users", `{"email": "  Ann@Example.COM ", "role": null}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body.String())
+	}
+
+	want := bson.M{"email": "ann@example.com", "role": "member"}
+	for field, value := range want {
+		if inserter.inserted[field] != value {
+			t.Errorf("%s = %#v, want %#v", field, inserter.inserted[field], value)
+		}
+	}
+}
+
+func TestInsertDocumentRowFilter(t *testing.T) {
+	cfg := &config.Config{
+		RowFilters: map[string]map[string]interface{}{
+			config.KeyTierFull: {"tenantId": "acme", "archived": map[string]interface{}{"$ne": true}},
+		},
+		WriteTransforms: map[string]map[string][]config.FieldTransform{
+			"mydb.users": {"tenantId": {{Op: config.TransformLowercase}}},
+		},
+	}
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"tenant stamped", `{"name": "Ann"}`, http.StatusCreated},
+		{"same tenant", `{"name": "Ann", "tenantId": "acme"}`, http.StatusCreated},
+		{"same tenant after transforms", `{"name": "Ann", "tenantId": "ACME"}`, http.StatusCreated},
+		{"other tenant", `{"name": "Ann", "tenantId": "globex"}`, http.StatusBadRequest},
+		{"operator field set", `{"name": "Ann", "archived": false}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inserter := &fakeInserter{}
+			rec, response := postDocument(t, cfg, inserter, "users", tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status != http.StatusCreated {
+				if inserter.inserted != nil {
+					t.Errorf("inserted %v, want nothing written", inserter.inserted)
+				}
+				if response["error"] == nil {
+					t.Errorf("response %v has no error", response)
+				}
+				return
+			}
+			if inserter.inserted["tenantId"] != "acme" {
+				t.Errorf("tenantId = %#v, want the row filter's acme", inserter.inserted["tenantId"])
+			}
+			if _, ok := inserter.inserted["archived"]; ok {
+				t.Errorf("archived = %#v, want an operator predicate left unset", inserter.inserted["archived"])
+			}
+		})
+	}
+}
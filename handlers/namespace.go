@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	auth "mongodb-go-proxy/middleware"
+)
+
+// validateNamespace applies the naming rules the REST routes enforce in DatabaseSelector
+// to a database and collection taken from a Data API request body, so an operator-like
+// or malformed name is rejected before it reaches the driver
+func validateNamespace(database, collection string) error {
+	if err := auth.ValidateDatabaseName(database); err != nil {
+		return err
+	}
+	return auth.ValidateCollectionName(database, collection)
+}
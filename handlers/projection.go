@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+// listProjection merges the LIST_EXCLUDED_FIELDS defaults of a collection into the client's
+// projection for list responses. Inclusion projections already leave the fields out, and
+// fields the client mentions in any way are left to the client, so they can always be requested.
+func listProjection(cfg *config.Config, database, collection string, projection bson.M) bson.M {
+	excluded := cfg.ExcludedListFields(database, collection)
+	if len(excluded) == 0 || isInclusionProjection(projection) {
+		return projection
+	}
+
+	merged := make(bson.M, len(projection)+len(excluded))
+	for key, value := range projection {
+		merged[key] = value
+	}
+	for _, field := range excluded {
+		if !projectionMentions(projection, field) {
+			merged[field] = 0
+		}
+	}
+	return merged
+}
+
+// isInclusionProjection reports whether a projection returns only the fields it names.
+// _id and $slice do not decide the mode; any other truthy value or expression does.
+func isInclusionProjection(projection bson.M) bool {
+	for key, value := range projection {
+		if key == "_id" {
+			continue
+		}
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return true
+			}
+		case int32, int64, float64:
+			if toFloat64(v) != 0 {
+				return true
+			}
+		case bson.M:
+			if _, ok := v["$slice"]; !ok {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// checkProjectionMode rejects a projection that both includes and excludes fields, which
+// MongoDB refuses with a less helpful error. _id may be excluded from an inclusion
+// projection. Embedded documents such as {"address": {"city": 1}} are checked by their
+// dotted paths; operators and expressions are left to MongoDB.
+func checkProjectionMode(projection bson.M) error {
+	var included, excluded string
+	var walk func(doc bson.M, prefix string)
+	walk = func(doc bson.M, prefix string) {
+		keys := make([]string, 0, len(doc))
+		for key := range doc {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			path := prefix + key
+			var truthy bool
+			switch v := doc[key].(type) {
+			case bool:
+				truthy = v
+			case int32, int64, float64:
+				truthy = toFloat64(v) != 0
+			case bson.M:
+				if !isOperatorDocument(v) {
+					walk(v, path+".")
+				}
+				continue
+			default:
+				continue
+			}
+			switch {
+			case path == "_id":
+			case truthy && included == "":
+				included = path
+			case !truthy && excluded == "":
+				excluded = path
+			}
+		}
+	}
+	walk(projection, "")
+
+	if included != "" && excluded != "" {
+		return fmt.Errorf("cannot mix inclusion (%s) and exclusion (%s); a projection either lists the fields to return or the fields to leave out, and only _id may be excluded from an inclusion projection", included, excluded)
+	}
+	return nil
+}
+
+// isOperatorDocument reports whether a projection value is an operator such as $slice
+// rather than an embedded projection
+func isOperatorDocument(doc bson.M) bool {
+	for key := range doc {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// projectionMentions reports whether the projection names field, a sub-path of it or a parent of it
+func projectionMentions(projection bson.M, field string) bool {
+	for key := range projection {
+		if key == field || strings.HasPrefix(key, field+".") || strings.HasPrefix(field, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSlices validates the $slice operators of a projection and converts their
+// operands to integers. JSON numbers decode as doubles, which $slice does not accept.
+func normalizeSlices(projection bson.M) error {
+	for field, value := range projection {
+		spec, ok := value.(bson.M)
+		if !ok {
+			continue
+		}
+		operand, ok := spec["$slice"]
+		if !ok {
+			continue
+		}
+
+		switch v := operand.(type) {
+		case bson.A:
+			if len(v) != 2 {
+				return errors.New(field + ": $slice array must be [skip, limit]")
+			}
+			skip, okSkip := sliceInt(v[0])
+			limit, okLimit := sliceInt(v[1])
+			if !okSkip || !okLimit || limit <= 0 {
+				return errors.New(field + ": $slice [skip, limit] must be integers with a positive limit")
+			}
+			spec["$slice"] = bson.A{skip, limit}
+		default:
+			n, ok := sliceInt(v)
+			if !ok {
+				return errors.New(field + ": $slice must be an integer or [skip, limit]")
+			}
+			spec["$slice"] = n
+		}
+	}
+	return nil
+}
+
+// sliceInt converts a whole number of any BSON numeric type to int64
+func sliceInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+// pluckProjection fetches only field, for find requests with ?pluck
+func pluckProjection(field string) bson.M {
+	if field == "_id" {
+		return bson.M{"_id": 1}
+	}
+	return bson.M{field: 1, "_id": 0}
+}
+
+// pluckValues extracts field from every document into a flat list. Documents without the
+// field are skipped, or contribute null when keepMissing is set.
+func pluckValues(docs []bson.M, field string, keepMissing bool) []interface{} {
+	values := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		value, ok := lookupPath(doc, field)
+		if !ok && !keepMissing {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// lookupPath follows a dotted field path through embedded documents
+func lookupPath(doc bson.M, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		embedded, ok := current.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = embedded[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// omitNulls returns a copy of doc without null-valued keys, recursing into embedded
+// documents and documents inside arrays. With omitEmpty, empty strings and empty arrays
+// are dropped too. Array elements are never removed so positions stay meaningful. The
+// input is left untouched because coalesced reads share documents between requests.
+func omitNulls(doc bson.M, omitEmpty bool) bson.M {
+	if doc == nil {
+		return nil
+	}
+	result := make(bson.M, len(doc))
+	for key, value := range doc {
+		if value, keep := omitNullValue(value, omitEmpty); keep {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// omitNullValue applies omitNulls to one value and reports whether its key should stay
+func omitNullValue(value interface{}, omitEmpty bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return v, !(omitEmpty && v == "")
+	case bson.M:
+		return omitNulls(v, omitEmpty), true
+	case bson.A:
+		if omitEmpty && len(v) == 0 {
+			return v, false
+		}
+		elements := make(bson.A, len(v))
+		for i, element := range v {
+			if element != nil {
+				element, _ = omitNullValue(element, omitEmpty)
+			}
+			elements[i] = element
+		}
+		return elements, true
+	default:
+		return value, true
+	}
+}
+
+// omitNullsAll applies omitNulls to every document of a result set
+func omitNullsAll(docs []bson.M, omitEmpty bool) []bson.M {
+	result := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		result[i] = omitNulls(doc, omitEmpty)
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNormalizeSlices(t *testing.T) {
+	tests := []struct {
+		name    string
+		slice   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"JSON count", float64(5), int64(5), false},
+		{"negative count takes from the end", float64(-3), int64(-3), false},
+		{"int32 count", int32(2), int64(2), false},
+		{"int64 count", int64(7), int64(7), false},
+		{"skip and limit", bson.A{float64(10), float64(5)}, bson.A{int64(10), int64(5)}, false},
+		{"negative skip", bson.A{float64(-20), float64(10)}, bson.A{int64(-20), int64(10)}, false},
+		{"fractional count", 2.5, nil, true},
+		{"count beyond int32", float64(1 << 40), nil, true},
+		{"string count", "5", nil, true},
+		{"array with one element", bson.A{float64(5)}, nil, true},
+		{"array with three elements", bson.A{float64(1), float64(2), float64(3)}, nil, true},
+		{"zero limit", bson.A{float64(1), float64(0)}, nil, true},
+		{"negative limit", bson.A{float64(1), float64(-2)}, nil, true},
+		{"fractional skip", bson.A{1.5, float64(2)}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projection := bson.M{"comments": bson.M{"$slice": tt.slice}, "title": float64(1)}
+			err := normalizeSlices(projection)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("normalizeSlices accepted $slice %v", tt.slice)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeSlices: %v", err)
+			}
+			if got := projection["comments"].(bson.M)["$slice"]; !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("$slice = %#v, want %#v", got, tt.want)
+			}
+			if projection["title"] != float64(1) {
+				t.Fatalf("title = %#v, want the plain projection value unchanged", projection["title"])
+			}
+		})
+	}
+}
+
+func TestNormalizeSlicesIgnoresOtherOperators(t *testing.T) {
+	projection := bson.M{"tags": bson.M{"$elemMatch": bson.M{"score": bson.M{"$gt": float64(5)}}}}
+	if err := normalizeSlices(projection); err != nil {
+		t.Fatalf("normalizeSlices: %v", err)
+	}
+	want := bson.M{"tags": bson.M{"$elemMatch": bson.M{"score": bson.M{"$gt": float64(5)}}}}
+	if !reflect.DeepEqual(projection, want) {
+		t.Fatalf("projection = %v, want %v", projection, want)
+	}
+}
+
+func TestCheckProjectionMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		projection bson.M
+		wantError  string
+	}{
+		{"inclusion", bson.M{"name": int32(1), "email": true}, ""},
+		{"exclusion", bson.M{"password": int32(0), "token": false}, ""},
+		{"_id excluded from an inclusion", bson.M{"name": int32(1), "_id": int32(0)}, ""},
+		{"_id excluded from an exclusion", bson.M{"password": int32(0), "_id": int32(0)}, ""},
+		{"dotted inclusion", bson.M{"address.city": int32(1), "name": float64(1)}, ""},
+		{"embedded exclusion", bson.M{"address": bson.M{"zip": int32(0)}, "password": int32(0)}, ""},
+		{"$slice with an inclusion", bson.M{"comments": bson.M{"$slice": int64(5)}, "title": int32(1)}, ""},
+		{"$slice with an exclusion", bson.M{"comments": bson.M{"$slice": int64(5)}, "secret": int32(0)}, ""},
+		{"expressions are left to MongoDB", bson.M{"name": int32(1), "label": bson.M{"$concat": bson.A{"$first", " ", "$last"}}}, ""},
+		{"mixed", bson.M{"name": int32(1), "password": int32(0)}, "cannot mix inclusion (name) and exclusion (password)"},
+		{"mixed booleans and doubles", bson.M{"name": true, "password": float64(0)}, "cannot mix inclusion (name) and exclusion (password)"},
+		{"mixed dotted paths", bson.M{"address.city": int32(1), "address.zip": int32(0)}, "cannot mix inclusion (address.city) and exclusion (address.zip)"},
+		{"mixed inside an embedded projection", bson.M{"address": bson.M{"city": int32(1), "zip": int32(0)}}, "cannot mix inclusion (address.city) and exclusion (address.zip)"},
+		{"embedded inclusion with a top-level exclusion", bson.M{"address": bson.M{"city": int32(1)}, "password": int32(0)}, "cannot mix inclusion (address.city) and exclusion (password)"},
+		{"_id included in an exclusion", bson.M{"_id": int32(1), "password": int32(0)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkProjectionMode(tt.projection)
+			switch {
+			case tt.wantError == "" && err != nil:
+				t.Fatalf("checkProjectionMode: %v", err)
+			case tt.wantError != "" && err == nil:
+				t.Fatalf("checkProjectionMode accepted %v, want %q", tt.projection, tt.wantError)
+			case tt.wantError != "" && !strings.HasPrefix(err.Error(), tt.wantError):
+				t.Fatalf("error = %q, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
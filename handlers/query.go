@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+// errEmptyDestructiveFilter is returned when a bulk mutation has an empty filter and no confirmAll
+const errEmptyDestructiveFilter = "filter cannot be empty for this operation; set confirmAll to true to affect every document"
+
+// destructiveFilterAllowed applies the REQUIRE_FILTER_ON_DESTRUCTIVE policy to a bulk mutation.
+// Every multi-document update or delete must call this before touching the collection.
+func destructiveFilterAllowed(cfg *config.Config, filter bson.M, confirmAll bool) bool {
+	return !cfg.RequireFilterOnDestructive || len(filter) > 0 || confirmAll
+}
+
+// checkFilterComplexity rejects filters with more operators or deeper document nesting
+// than MAX_FILTER_OPS and MAX_FILTER_DEPTH allow, so a pathological $or/$and tree from a
+// client never reaches the query planner. A cap of 0 disables that check.
+func checkFilterComplexity(cfg *config.Config, filter bson.M) error {
+	ops, depth := filterComplexity(filter, 1)
+	if cfg.MaxFilterOps > 0 && ops > cfg.MaxFilterOps {
+		return fmt.Errorf("filter uses %d operators, more than the maximum of %d", ops, cfg.MaxFilterOps)
+	}
+	if cfg.MaxFilterDepth > 0 && depth > cfg.MaxFilterDepth {
+		return fmt.Errorf("filter is nested %d levels deep, more than the maximum of %d", depth, cfg.MaxFilterDepth)
+	}
+	return nil
+}
+
+// filterComplexity counts the $-prefixed keys in value and the deepest document nesting,
+// value itself being at level depth. Arrays do not add a level of their own.
+func filterComplexity(value interface{}, depth int) (ops, maxDepth int) {
+	maxDepth = depth - 1
+	visit := func(key string, child interface{}, childDepth int) {
+		if strings.HasPrefix(key, "$") {
+			ops++
+		}
+		childOps, childMax := filterComplexity(child, childDepth)
+		ops += childOps
+		if childMax > maxDepth {
+			maxDepth = childMax
+		}
+	}
+
+	switch v := value.(type) {
+	case bson.M:
+		maxDepth = depth
+		for key, child := range v {
+			visit(key, child, depth+1)
+		}
+	case map[string]interface{}:
+		maxDepth = depth
+		for key, child := range v {
+			visit(key, child, depth+1)
+		}
+	case bson.D:
+		maxDepth = depth
+		for _, elem := range v {
+			visit(elem.Key, elem.Value, depth+1)
+		}
+	case bson.A:
+		for _, child := range v {
+			visit("", child, depth)
+		}
+	case []interface{}:
+		for _, child := range v {
+			visit("", child, depth)
+		}
+	}
+	return ops, maxDepth
+}
+
+// withTiebreaker appends _id to a client-supplied sort so documents with equal sort keys
+// come back in the same order on every page. _id follows the direction of the last key,
+// which keeps compound indexes usable. Disabled by SORT_TIEBREAKER=false.
+func withTiebreaker(cfg *config.Config, sortSpec bson.D) bson.D {
+	if !cfg.SortTiebreaker || len(sortSpec) == 0 {
+		return sortSpec
+	}
+	for _, elem := range sortSpec {
+		if elem.Key == "_id" {
+			return sortSpec
+		}
+	}
+
+	direction := 1
+	if toFloat64(sortSpec[len(sortSpec)-1].Value) < 0 {
+		direction = -1
+	}
+	return append(sortSpec, bson.E{Key: "_id", Value: direction})
+}
+
+// queryFlag reports whether a boolean query parameter is set to a true value
+func queryFlag(c echo.Context, name string) bool {
+	enabled, err := strconv.ParseBool(c.QueryParam(name))
+	return err == nil && enabled
+}
+
+// queryEcho describes the normalized query that was executed, for use as a client cache key.
+// Documents are rendered as relaxed Extended JSON with object keys sorted so the output is
+// stable across requests; sort specifications keep their order since it is significant.
+func queryEcho(filter bson.M, sortSpec bson.D, projection bson.M, limit, skip int64) (map[string]interface{}, error) {
+	echoed := map[string]interface{}{
+		"limit": limit,
+		"skip":  skip,
+	}
+
+	filterJSON, err := bson.MarshalExtJSON(canonicalDocument(filter), false, false)
+	if err != nil {
+		return nil, err
+	}
+	echoed["filter"] = json.RawMessage(filterJSON)
+
+	if len(sortSpec) > 0 {
+		sortJSON, err := bson.MarshalExtJSON(sortSpec, false, false)
+		if err != nil {
+			return nil, err
+		}
+		echoed["sort"] = json.RawMessage(sortJSON)
+	}
+
+	if len(projection) > 0 {
+		projectionJSON, err := bson.MarshalExtJSON(canonicalDocument(projection), false, false)
+		if err != nil {
+			return nil, err
+		}
+		echoed["projection"] = json.RawMessage(projectionJSON)
+	}
+
+	return echoed, nil
+}
+
+// canonicalDocument converts a bson.M (recursively) into a bson.D with keys in sorted order
+func canonicalDocument(doc bson.M) bson.D {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, bson.E{Key: key, Value: canonicalValue(doc[key])})
+	}
+	return result
+}
+
+// canonicalValue applies canonicalDocument to any documents nested within a value
+func canonicalValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		return canonicalDocument(v)
+	case map[string]interface{}:
+		return canonicalDocument(bson.M(v))
+	case bson.D:
+		result := make(bson.D, len(v))
+		for i, elem := range v {
+			result[i] = bson.E{Key: elem.Key, Value: canonicalValue(elem.Value)}
+		}
+		return result
+	case bson.A:
+		result := make(bson.A, len(v))
+		for i, elem := range v {
+			result[i] = canonicalValue(elem)
+		}
+		return result
+	case []interface{}:
+		result := make(bson.A, len(v))
+		for i, elem := range v {
+			result[i] = canonicalValue(elem)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// paginationLinks builds an RFC 5988 Link header value with first, prev and next relations
+// for a skip/limit paginated listing. next is only included when more results exist.
+func paginationLinks(c echo.Context, skip, limit, total int64) string {
+	pageURL := func(pageSkip int64) string {
+		u := *c.Request().URL
+		query := u.Query()
+		query.Set("skip", strconv.FormatInt(pageSkip, 10))
+		query.Set("limit", strconv.FormatInt(limit, 10))
+		u.RawQuery = query.Encode()
+		return u.RequestURI()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(0))}
+	if limit <= 0 {
+		return links[0]
+	}
+
+	if skip > 0 {
+		prev := skip - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(prev)))
+	}
+	if skip+limit < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(skip+limit)))
+	}
+	return strings.Join(links, ", ")
+}
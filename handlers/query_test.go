@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+func TestFilterComplexity(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    bson.M
+		wantOps   int
+		wantDepth int
+	}{
+		{"empty", bson.M{}, 0, 1},
+		{"equality", bson.M{"a": 1}, 0, 1},
+		{"one operator", bson.M{"a": bson.M{"$gt": 1}}, 1, 2},
+		{"two operators on a field", bson.M{"a": bson.M{"$gt": 1, "$lt": 5}}, 2, 2},
+		{"arrays add no level", bson.M{"$or": bson.A{bson.M{"a": 1}, bson.M{"b": bson.M{"$gt": 2}}}}, 2, 3},
+		{"$in values are not operators", bson.M{"a": bson.M{"$in": bson.A{"x", "y", "z"}}}, 1, 2},
+		{"embedded document", bson.M{"address": bson.M{"city": bson.M{"name": "Oslo"}}}, 0, 3},
+		{"decoded JSON maps", bson.M{"a": map[string]interface{}{"$not": map[string]interface{}{"$gt": 1}}}, 2, 3},
+		{"ordered documents", bson.M{"a": bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "b", Value: 1}}}}}, 1, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, depth := filterComplexity(tt.filter, 1)
+			if ops != tt.wantOps || depth != tt.wantDepth {
+				t.Fatalf("filterComplexity = %d ops, depth %d, want %d ops, depth %d", ops, depth, tt.wantOps, tt.wantDepth)
+			}
+		})
+	}
+}
+
+func TestCheckFilterComplexity(t *testing.T) {
+	// Three operators, nested three levels deep
+	filter := bson.M{"$and": bson.A{
+		bson.M{"age": bson.M{"$gte": 18}},
+		bson.M{"status": "active"},
+		bson.M{"score": bson.M{"$lt": 100}},
+	}}
+
+	tests := []struct {
+		name      string
+		maxOps    int
+		maxDepth  int
+		wantError string
+	}{
+		{"unlimited", 0, 0, ""},
+		{"at both limits", 3, 3, ""},
+		{"one operator over", 2, 0, "filter uses 3 operators, more than the maximum of 2"},
+		{"one level over", 0, 2, "filter is nested 3 levels deep, more than the maximum of 2"},
+		{"operators are checked first", 1, 1, "filter uses 3 operators"},
+		{"above both limits", 4, 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{MaxFilterOps: tt.maxOps, MaxFilterDepth: tt.maxDepth}
+			err := checkFilterComplexity(cfg, filter)
+			switch {
+			case tt.wantError == "" && err != nil:
+				t.Fatalf("checkFilterComplexity: %v", err)
+			case tt.wantError != "" && err == nil:
+				t.Fatalf("checkFilterComplexity accepted the filter, want %q", tt.wantError)
+			case tt.wantError != "" && !strings.HasPrefix(err.Error(), tt.wantError):
+				t.Fatalf("error = %q, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
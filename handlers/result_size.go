@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// resultSize is the size of a find result set returned for ?sizeOnly=true
+type resultSize struct {
+	Count     int64
+	Bytes     int64
+	Truncated bool // the operation timeout expired before the cursor was exhausted
+}
+
+// measureFind iterates every document matching filter, without limit or skip, and adds
+// up their BSON sizes without decoding them. When ctx expires part way through, the
+// totals so far are returned with Truncated set, so the operation timeout caps the work.
+func measureFind(ctx context.Context, collection *mongo.Collection, filter bson.M, projection bson.M, comment string) (resultSize, error) {
+	findOptions := options.Find().SetComment(comment)
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return resultSize{}, err
+	}
+	// The context may already be expired, so closing gets a fresh one
+	defer cursor.Close(context.Background())
+
+	var size resultSize
+	for cursor.Next(ctx) {
+		size.Count++
+		size.Bytes += int64(len(cursor.Current))
+	}
+	if err := cursor.Err(); err != nil {
+		if size.Count > 0 && (errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err)) {
+			size.Truncated = true
+			return size, nil
+		}
+		return resultSize{}, err
+	}
+	return size, nil
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// crossCollectionStages read or write other collections, which a row filter on the
+// source collection cannot restrict. $merge and $out match and replace documents of
+// their target whatever tenant they belong to.
+var crossCollectionStages = []string{"$lookup", "$graphLookup", "$unionWith", "$merge", "$out"}
+
+// scopeFilter AND-s the ROW_FILTERS predicate of the request's api-key into filter. It
+// runs after checks that look at the client's own filter, such as the empty-filter guard
+// of updateMany and deleteMany, and returns filter unchanged for unrestricted keys.
+func scopeFilter(cfg *config.Config, c echo.Context, filter bson.M) bson.M {
+	predicate := cfg.RowFilter(auth.KeyTier(c))
+	if predicate == nil {
+		return filter
+	}
+	if len(filter) == 0 {
+		// A copy, as handlers add to the filter they are given
+		scoped := make(bson.M, len(predicate))
+		for key, value := range predicate {
+			scoped[key] = value
+		}
+		return scoped
+	}
+	return bson.M{"$and": bson.A{filter, bson.M(predicate)}}
+}
+
+// scopePipeline puts the ROW_FILTERS predicate of the request's api-key in a leading $match.
+// Stages that read or write other collections are rejected for restricted keys, since
+// their documents would bypass the predicate.
+func scopePipeline(cfg *config.Config, c echo.Context, pipeline []bson.D) ([]bson.D, error) {
+	predicate := cfg.RowFilter(auth.KeyTier(c))
+	if predicate == nil {
+		return pipeline, nil
+	}
+	for _, stage := range pipeline {
+		if name, ok := findStage(stage, crossCollectionStages); ok {
+			return nil, fmt.Errorf("%s is not allowed for an api-key with a row filter", name)
+		}
+	}
+
+	scoped := make([]bson.D, 0, len(pipeline)+1)
+	scoped = append(scoped, bson.D{{Key: "$match", Value: bson.M(predicate)}})
+	return append(scoped, pipeline...), nil
+}
+
+// findStage walks a decoded pipeline value, including $facet and nested pipelines, and
+// returns the first of names used as a stage
+func findStage(value interface{}, names []string) (string, bool) {
+	switch v := value.(type) {
+	case bson.D:
+		for _, elem := range v {
+			for _, name := range names {
+				if elem.Key == name {
+					return name, true
+				}
+			}
+			if name, ok := findStage(elem.Value, names); ok {
+				return name, true
+			}
+		}
+	case bson.A:
+		for _, item := range v {
+			if name, ok := findStage(item, names); ok {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// guardRowFilterUpdate rejects updates that write a field of the api-key's ROW_FILTERS
+// predicate, which would move a document out of the key's reach or into another tenant's
+func guardRowFilterUpdate(cfg *config.Config, c echo.Context, update bson.M) error {
+	predicate := cfg.RowFilter(auth.KeyTier(c))
+	if predicate == nil {
+		return nil
+	}
+	for _, operator := range sortedKeys(update) {
+		fields, ok := update[operator].(bson.M)
+		if !ok {
+			continue
+		}
+		for _, path := range sortedKeys(fields) {
+			paths := []string{path}
+			if target, ok := fields[path].(string); ok && operator == "$rename" {
+				paths = append(paths, target)
+			}
+			for _, p := range paths {
+				field, _, _ := strings.Cut(p, ".")
+				if _, fixed := predicate[field]; fixed {
+					return errors.New("field " + field + " is fixed by the api-key's row filter")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scopeDocument applies the ROW_FILTERS predicate of the request's api-key to a document
+// about to be inserted, see applyRowFilter
+func scopeDocument(cfg *config.Config, c echo.Context, doc bson.M) error {
+	return applyRowFilter(cfg.RowFilter(auth.KeyTier(c)), doc)
+}
+
+// applyRowFilter stamps the equality fields of a ROW_FILTERS predicate on a document about
+// to be inserted, so it lands within the key's reach. A document holding another value for
+// such a field, or setting a field the predicate constrains with operators, is rejected,
+// since it would be written into another tenant's rows. It runs after DEFAULTS and
+// WRITE_TRANSFORMS, on the fields as they will be stored.
+func applyRowFilter(predicate map[string]interface{}, doc bson.M) error {
+	for _, field := range sortedKeys(predicate) {
+		want := predicate[field]
+		value, set := doc[field]
+		switch {
+		case isOperatorValue(want):
+			if set {
+				return errors.New("field " + field + " is fixed by the api-key's row filter")
+			}
+		case !set:
+			doc[field] = want
+		case !reflect.DeepEqual(canonicalValue(value), canonicalValue(want)):
+			return errors.New("field " + field + " is fixed by the api-key's row filter")
+		}
+	}
+	return nil
+}
+
+// isOperatorValue reports whether a predicate value is a query operator document, such as
+// {"$ne": true}, rather than a value to compare for equality
+func isOperatorValue(value interface{}) bool {
+	switch v := value.(type) {
+	case bson.M:
+		return isOperatorDocument(v)
+	case map[string]interface{}:
+		return isOperatorDocument(v)
+	case bson.D:
+		return len(v) > 0 && strings.HasPrefix(v[0].Key, "$")
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+// paramKey marks a placeholder in a saved filter: {"$param": "name"} is replaced by params.name
+const paramKey = "$param"
+
+// savedFilter builds the SAVED_FILTERS entry called name, substituting params into its
+// placeholders. Every placeholder needs a parameter and every parameter must be used.
+// Parameter values cannot contain query operators, so clients fill in values but never
+// change the shape of the query.
+func savedFilter(cfg *config.Config, name string, params map[string]interface{}) (bson.M, error) {
+	template, ok := cfg.SavedFilters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown saved filter %q", name)
+	}
+
+	values := bson.M{}
+	if len(params) > 0 {
+		var err error
+		if values, err = extJSONDocument(params); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	for param, value := range values {
+		if containsOperator(value) {
+			return nil, fmt.Errorf("param %q cannot contain query operators", param)
+		}
+	}
+
+	used := make(map[string]bool, len(values))
+	filter := make(bson.M, len(template))
+	for key, value := range template {
+		substituted, err := substituteParams(value, values, used)
+		if err != nil {
+			return nil, err
+		}
+		filter[key] = substituted
+	}
+
+	var unused []string
+	for param := range values {
+		if !used[param] {
+			unused = append(unused, param)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return nil, fmt.Errorf("saved filter %q has no params named %s", name, strings.Join(unused, ", "))
+	}
+	return filter, nil
+}
+
+// substituteParams returns a copy of value with every {"$param": "name"} placeholder replaced
+func substituteParams(value interface{}, values bson.M, used map[string]bool) (interface{}, error) {
+	switch v := value.(type) {
+	case bson.D:
+		if len(v) == 1 && v[0].Key == paramKey {
+			return paramValue(v[0].Value, values, used)
+		}
+		result := make(bson.D, len(v))
+		for i, elem := range v {
+			substituted, err := substituteParams(elem.Value, values, used)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = bson.E{Key: elem.Key, Value: substituted}
+		}
+		return result, nil
+	case bson.M:
+		return substituteMap(v, values, used)
+	case map[string]interface{}:
+		return substituteMap(v, values, used)
+	case bson.A:
+		return substituteSlice(v, values, used)
+	case []interface{}:
+		return substituteSlice(v, values, used)
+	default:
+		return value, nil
+	}
+}
+
+// substituteMap applies substituteParams to a document, or resolves it if it is a placeholder
+func substituteMap(doc map[string]interface{}, values bson.M, used map[string]bool) (interface{}, error) {
+	if name, ok := doc[paramKey]; ok && len(doc) == 1 {
+		return paramValue(name, values, used)
+	}
+	result := make(bson.M, len(doc))
+	for key, value := range doc {
+		substituted, err := substituteParams(value, values, used)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = substituted
+	}
+	return result, nil
+}
+
+// substituteSlice applies substituteParams to every element of an array
+func substituteSlice(values []interface{}, params bson.M, used map[string]bool) (interface{}, error) {
+	result := make(bson.A, len(values))
+	for i, value := range values {
+		substituted, err := substituteParams(value, params, used)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = substituted
+	}
+	return result, nil
+}
+
+// paramValue resolves the placeholder naming param
+func paramValue(param interface{}, values bson.M, used map[string]bool) (interface{}, error) {
+	name, ok := param.(string)
+	if !ok {
+		return nil, fmt.Errorf("saved filter placeholder %s must name a param", paramKey)
+	}
+	value, ok := values[name]
+	if !ok {
+		return nil, fmt.Errorf("missing param %q", name)
+	}
+	used[name] = true
+	return value, nil
+}
+
+// containsOperator reports whether a value holds a document with a $-prefixed key
+func containsOperator(value interface{}) bool {
+	switch v := value.(type) {
+	case bson.M:
+		for key, elem := range v {
+			if strings.HasPrefix(key, "$") || containsOperator(elem) {
+				return true
+			}
+		}
+	case bson.D:
+		for _, elem := range v {
+			if strings.HasPrefix(elem.Key, "$") || containsOperator(elem.Value) {
+				return true
+			}
+		}
+	case bson.A:
+		for _, elem := range v {
+			if containsOperator(elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"mongodb-go-proxy/config"
+)
+
+// slowFind describes a find for the slow query log
+type slowFind struct {
+	action     string
+	collection *mongo.Collection
+	filter     bson.M
+	sort       bson.D
+	projection bson.M
+	limit      int64
+	skip       int64
+}
+
+// logSlowFind logs q when it took longer than SLOW_QUERY_THRESHOLD. With
+// EXPLAIN_SLOW_QUERIES, the winning plan is asked for in the background and logged with
+// whether it scans the whole collection, so the response is not held up by the explain.
+func logSlowFind(c echo.Context, cfg *config.Config, elapsed time.Duration, q slowFind) {
+	if cfg.SlowQueryThreshold <= 0 || elapsed < cfg.SlowQueryThreshold {
+		return
+	}
+
+	namespace := q.collection.Database().Name() + "." + q.collection.Name()
+	requestID := operationComment(c)
+	filter, err := bson.MarshalExtJSON(canonicalDocument(q.filter), false, false)
+	if err != nil {
+		filter = []byte("?")
+	}
+	log.Printf("Slow query: %s on %s took %s (request %s, filter %s)", q.action, namespace, elapsed.Round(time.Millisecond), requestID, filter)
+
+	if !cfg.ExplainSlowQueries {
+		return
+	}
+	go func() {
+		ctx, cancel := operationContext(cfg, "explain", 10*time.Second)
+		defer cancel()
+
+		plan, err := explainPlan(ctx, q.collection, q.filter, q.sort, q.projection, q.limit, q.skip)
+		if err != nil {
+			log.Printf("Slow query explain failed: %s on %s (request %s): %v", q.action, namespace, requestID, err)
+			return
+		}
+		log.Printf("Slow query plan: %s on %s (request %s): %s, collection scan: %t", q.action, namespace, requestID, strings.Join(plan.Stages, " > "), plan.CollectionScan)
+	}()
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// queryStats is the execution metadata returned for find requests with ?stats=true
+type queryStats struct {
+	DocsExamined    int64
+	KeysExamined    int64
+	Returned        int64
+	ExecutionMillis int64
+}
+
+// explainFind runs the find through explain with executionStats verbosity. This executes
+// the query a second time on the server, which is why stats are opt-in.
+func explainFind(ctx context.Context, collection *mongo.Collection, filter bson.M, sortSpec bson.D, projection bson.M, limit, skip int64) (*queryStats, error) {
+	command := bson.D{
+		{Key: "explain", Value: findCommand(collection, filter, sortSpec, projection, limit, skip)},
+		{Key: "verbosity", Value: "executionStats"},
+	}
+
+	var result struct {
+		ExecutionStats bson.M `bson:"executionStats"`
+	}
+	if err := collection.Database().RunCommand(ctx, command).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	stats := result.ExecutionStats
+	return &queryStats{
+		DocsExamined:    int64(toFloat64(stats["totalDocsExamined"])),
+		KeysExamined:    int64(toFloat64(stats["totalKeysExamined"])),
+		Returned:        int64(toFloat64(stats["nReturned"])),
+		ExecutionMillis: int64(toFloat64(stats["executionTimeMillis"])),
+	}, nil
+}
+
+// queryPlan is the winning plan of a find, as logged for slow queries
+type queryPlan struct {
+	Stages         []string // stages from the root down, IXSCAN with its index name
+	CollectionScan bool
+}
+
+// explainPlan asks the query planner for the winning plan of a find. Unlike explainFind
+// it uses queryPlanner verbosity, which does not execute the query.
+func explainPlan(ctx context.Context, collection *mongo.Collection, filter bson.M, sortSpec bson.D, projection bson.M, limit, skip int64) (*queryPlan, error) {
+	command := bson.D{
+		{Key: "explain", Value: findCommand(collection, filter, sortSpec, projection, limit, skip)},
+		{Key: "verbosity", Value: "queryPlanner"},
+	}
+
+	var result struct {
+		QueryPlanner struct {
+			WinningPlan bson.M `bson:"winningPlan"`
+		} `bson:"queryPlanner"`
+	}
+	if err := collection.Database().RunCommand(ctx, command).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	plan := &queryPlan{}
+	plan.walk(result.QueryPlanner.WinningPlan)
+	return plan, nil
+}
+
+// walk records the stages of a plan node and its inputs. Newer servers nest the plan
+// under queryPlan, and sharded clusters report one winning plan per shard.
+func (p *queryPlan) walk(node bson.M) {
+	if node == nil {
+		return
+	}
+	if inner, ok := node["queryPlan"].(bson.M); ok {
+		p.walk(inner)
+		return
+	}
+	if shards, ok := node["shards"].(bson.A); ok {
+		for _, shard := range shards {
+			if doc, ok := shard.(bson.M); ok {
+				if plan, ok := doc["winningPlan"].(bson.M); ok {
+					p.walk(plan)
+				}
+			}
+		}
+		return
+	}
+
+	stage, _ := node["stage"].(string)
+	switch stage {
+	case "":
+	case "IXSCAN":
+		if index, ok := node["indexName"].(string); ok {
+			stage += "(" + index + ")"
+		}
+		p.Stages = append(p.Stages, stage)
+	case "COLLSCAN":
+		p.CollectionScan = true
+		p.Stages = append(p.Stages, stage)
+	default:
+		p.Stages = append(p.Stages, stage)
+	}
+
+	if input, ok := node["inputStage"].(bson.M); ok {
+		p.walk(input)
+	}
+	if inputs, ok := node["inputStages"].(bson.A); ok {
+		for _, input := range inputs {
+			if doc, ok := input.(bson.M); ok {
+				p.walk(doc)
+			}
+		}
+	}
+}
+
+// findCommand is the find command for the given query, as passed to explain
+func findCommand(collection *mongo.Collection, filter bson.M, sortSpec bson.D, projection bson.M, limit, skip int64) bson.D {
+	find := bson.D{
+		{Key: "find", Value: collection.Name()},
+		{Key: "filter", Value: filter},
+	}
+	if len(sortSpec) > 0 {
+		find = append(find, bson.E{Key: "sort", Value: sortSpec})
+	}
+	if len(projection) > 0 {
+		find = append(find, bson.E{Key: "projection", Value: projection})
+	}
+	if limit > 0 {
+		find = append(find, bson.E{Key: "limit", Value: limit})
+	}
+	if skip > 0 {
+		find = append(find, bson.E{Key: "skip", Value: skip})
+	}
+	return find
+}
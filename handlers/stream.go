@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/config"
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
+)
+
+const (
+	// streamBatchSize is the default number of documents sent per InsertMany call
+	streamBatchSize = 500
+	// maxStreamBatchSize caps the batchSize query parameter
+	maxStreamBatchSize = 5000
+	// maxStreamLineBytes matches MongoDB's 16MB document limit plus Extended JSON overhead
+	maxStreamLineBytes = 32 * 1024 * 1024
+	// maxStreamLineErrors caps how many malformed lines are reported individually
+	maxStreamLineErrors = 100
+)
+
+// StreamLineError describes an NDJSON line that could not be decoded or was rejected by MongoDB
+type StreamLineError struct {
+	Line  int    `json:"line" example:"42"`                  // 1-based line number in the request body
+	Code  int    `json:"code,omitempty" example:"11000"`     // MongoDB error code (write errors only)
+	Error string `json:"error" example:"invalid JSON input"` // Decode or write error
+}
+
+// StreamInsertProgress is emitted as one NDJSON line after every flushed batch and once at the end
+type StreamInsertProgress struct {
+	Done        bool              `json:"done" example:"false"`   // True on the final line
+	DryRun      bool              `json:"dryRun,omitempty"`       // True when X-Dry-Run was set and nothing was written
+	Lines       int               `json:"lines" example:"1000"`   // Lines read so far
+	Inserted    int64             `json:"inserted" example:"998"` // Documents inserted so far
+	Failed      int               `json:"failed" example:"2"`     // Lines that could not be decoded so far
+	LineErrors  []StreamLineError `json:"lineErrors,omitempty"`   // Malformed lines (final line only, capped at 100)
+	WriteErrors []StreamLineError `json:"writeErrors,omitempty"`  // Documents rejected by MongoDB (final line only)
+	Error       string            `json:"error,omitempty"`        // Fatal error that stopped the import (final line only)
+}
+
+// prepareOrdered runs the insert steps of Insert Document on an NDJSON document, which
+// keeps its field order: WRITABLE_FIELDS, then DEFAULTS, WRITE_TRANSFORMS, the api-key's
+// rowFilter and ID_FORMATS. Values are updated in place, and fields the steps add are appended.
+func prepareOrdered(cfg *config.Config, database, collection string, doc bson.D, rowFilter map[string]interface{}, now time.Time) (bson.D, error) {
+	doc, err := restrictOrdered(cfg, database, collection, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(bson.M, len(doc))
+	for _, elem := range doc {
+		fields[elem.Key] = elem.Value
+	}
+	applyDefaults(cfg, database, collection, fields, now)
+	applyTransforms(cfg, database, collection, fields)
+	if err := applyRowFilter(rowFilter, fields); err != nil {
+		return nil, err
+	}
+	if err := assignID(cfg, database, collection, fields); err != nil {
+		return nil, fmt.Errorf("failed to generate _id: %w", err)
+	}
+
+	for i, elem := range doc {
+		if value, ok := fields[elem.Key]; ok {
+			doc[i].Value = value
+			delete(fields, elem.Key)
+		}
+	}
+	for _, key := range sortedKeys(fields) {
+		doc = append(doc, bson.E{Key: key, Value: fields[key]})
+	}
+	return doc, nil
+}
+
+// InsertStream godoc
+//
+//	@Summary		Stream documents into a collection
+//	@Description	Insert documents from an NDJSON request body (one Extended JSON document per line), flushing to MongoDB in batches. The response is NDJSON: one progress line per batch followed by a final line with done=true. Malformed lines are skipped and reported by line number. The import stops at the first document MongoDB rejects; that document is reported under writeErrors with its line number and error code.
+//	@Tags			documents
+//	@Accept			x-ndjson
+//	@Produce		x-ndjson
+//	@Security		ApiKeyAuth
+//	@Param			db			path		string					true	"Database name"							example("mydb")
+//	@Param			collection	path		string					true	"Collection name"						example("users")
+//	@Param			batchSize	query		int						false	"Documents per InsertMany (max 5000)"	default(500)
+//	@Param			documents	body		string					true	"NDJSON documents"
+//	@Success		200			{object}	StreamInsertProgress	"Progress lines followed by the final summary"
+//	@Failure		400			{object}	map[string]string		"Bad request - invalid batch size"
+//	@Failure		401			{object}	map[string]string		"Unauthorized - missing or invalid api-key"
+//	@Failure		500			{object}	map[string]string		"Internal server error"
+//	@Router			/v1/databases/{db}/collections/{collection}/documents/stream [post]
+func (h *MongoHandler) InsertStream(c echo.Context) error {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	if dbName == "" || collectionName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Database and collection names are required",
+		})
+	}
+
+	metrics.Track(c, "insertStream", dbName, collectionName)
+
+	batchSize := streamBatchSize
+	if b := c.QueryParam("batchSize"); b != "" {
+		parsed, err := parseInt64(b)
+		if err != nil || parsed <= 0 || parsed > maxStreamBatchSize {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "batchSize must be between 1 and 5000",
+			})
+		}
+		batchSize = int(parsed)
+	}
+
+	collection, err := h.dbClient.GetCollection(dbName, collectionName)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	insert := func(ctx context.Context, batch []interface{}) (*mongo.InsertManyResult, error) {
+		return collection.InsertMany(ctx, batch, options.InsertMany().SetComment(operationComment(c)))
+	}
+	return h.streamInsert(c, dbName, collectionName, batchSize, insert)
+}
+
+// streamInsert reads the NDJSON body of an InsertStream request and passes it to insert in
+// batches, writing a progress line after each batch and the summary at the end
+func (h *MongoHandler) streamInsert(c echo.Context, dbName, collectionName string, batchSize int, insert func(ctx context.Context, batch []interface{}) (*mongo.InsertManyResult, error)) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+
+	// Progress is written while the body is still being read. HTTP/1.x closes an unread
+	// request body once the response is flushed unless the connection is full duplex; where
+	// it cannot be made so, progress lines are held back until the body has been read.
+	duplex := true
+	if err := http.NewResponseController(res.Writer).EnableFullDuplex(); err != nil {
+		duplex = false
+	}
+	if duplex {
+		res.WriteHeader(http.StatusOK)
+	}
+	encoder := json.NewEncoder(res)
+
+	dryRun := isDryRun(c)
+	rowFilter := h.cfg.RowFilter(auth.KeyTier(c))
+	now := time.Now()
+	progress := StreamInsertProgress{DryRun: dryRun}
+	emit := func() {
+		line := progress
+		if !line.Done {
+			if !duplex {
+				return
+			}
+			line.LineErrors = nil
+			line.WriteErrors = nil
+		}
+		encoder.Encode(line)
+		res.Flush()
+	}
+
+	batch := make([]interface{}, 0, batchSize)
+	batchLines := make([]int, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		// In a dry run every decodable line counts as inserted without touching MongoDB
+		if dryRun {
+			progress.Inserted += int64(len(batch))
+			batch, batchLines = batch[:0], batchLines[:0]
+			emit()
+			return nil
+		}
+
+		ctx, cancel := streamContext(c, h.cfg, "insertStream", 30*time.Second)
+		defer cancel()
+
+		result, err := insert(ctx, batch)
+		n := len(batch)
+		lines := batchLines
+		batch, batchLines = batch[:0], batchLines[:0]
+		if err != nil {
+			// Map rejected documents back to their line numbers. The insert is ordered,
+			// so the documents before the first failure are in and the rest are not.
+			writeErrors, _, ok := bulkWriteErrors(err)
+			if ok {
+				progress.Inserted += int64(len(succeededIndexes(n, writeErrors, true)))
+				for _, we := range writeErrors {
+					progress.WriteErrors = append(progress.WriteErrors, StreamLineError{Line: lines[we.Index], Code: we.Code, Error: we.Message})
+				}
+			}
+			// The status was sent with the first progress line, so the breaker learns of
+			// an unreachable MongoDB from the recorded error alone
+			auth.RecordUpstreamError(c, err)
+			return err
+		}
+		progress.Inserted += int64(len(result.InsertedIDs))
+		emit()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(c.Request().Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineBytes)
+	for scanner.Scan() {
+		progress.Lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var doc bson.D
+		err := bson.UnmarshalExtJSON(line, false, &doc)
+		if err == nil {
+			doc, err = prepareOrdered(h.cfg, dbName, collectionName, doc, rowFilter, now)
+		}
+		if err != nil {
+			progress.Failed++
+			if len(progress.LineErrors) < maxStreamLineErrors {
+				progress.LineErrors = append(progress.LineErrors, StreamLineError{Line: progress.Lines, Error: err.Error()})
+			}
+			continue
+		}
+
+		batch = append(batch, doc)
+		batchLines = append(batchLines, progress.Lines)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				// Nobody is left to read the summary of an import the client abandoned
+				if c.Request().Context().Err() != nil {
+					return nil
+				}
+				progress.Done = true
+				progress.Error = err.Error()
+				emit()
+				return nil
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		progress.Error = "Failed to read request body: " + err.Error()
+	} else if err := flush(); err != nil {
+		progress.Error = err.Error()
+	}
+
+	progress.Done = true
+	emit()
+	return nil
+}
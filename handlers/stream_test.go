@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"mongodb-go-proxy/config"
+)
+
+// TestStreamInsertReadsChunkedBody streams a chunked body many batches long. Progress is
+// flushed after every batch while the rest of the body is still arriving, which on
+// HTTP/1.x closes the request body unless the connection is full duplex.
+func TestStreamInsertReadsChunkedBody(t *testing.T) {
+	const lines = 20000
+
+	h := &MongoHandler{cfg: &config.Config{}}
+	insert := func(ctx context.Context, batch []interface{}) (*mongo.InsertManyResult, error) {
+		return &mongo.InsertManyResult{InsertedIDs: batch}, nil
+	}
+	e := echo.New()
+	e.POST("/stream", func(c echo.Context) error {
+		return h.streamInsert(c, "mydb", "users", 100, insert)
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	body, writer := io.Pipe()
+	go func() {
+		for i := 0; i < lines; i++ {
+			if _, err := fmt.Fprintf(writer, "{\"n\": %d}\n", i); err != nil {
+				return
+			}
+		}
+		writer.Close()
+	}()
+
+	res, err := http.Post(server.URL+"/stream", "application/x-ndjson", body)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer res.Body.Close()
+
+	var last StreamInsertProgress
+	progressLines := 0
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		progressLines++
+		if err := json.Unmarshal(scanner.Bytes(), &last); err != nil {
+			t.Fatalf("progress line %d: %v", progressLines, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+
+	if last.Error != "" {
+		t.Fatalf("import failed after %d lines: %s", last.Lines, last.Error)
+	}
+	if !last.Done || last.Lines != lines || last.Inserted != lines {
+		t.Fatalf("final progress = %+v, want done with %d lines inserted", last, lines)
+	}
+	if progressLines < 2 {
+		t.Errorf("got %d progress lines, want one per batch before the summary", progressLines)
+	}
+}
+
+func TestPrepareOrdered(t *testing.T) {
+	cfg := &config.Config{
+		WritableFields:     map[string][]string{"mydb.users": {"name", "email"}},
+		WritableFieldsMode: config.WritableFieldsStrip,
+		Defaults:           map[string]map[string]interface{}{"mydb.users": {"status": "active", "createdAt": serverTimestampToken}},
+		WriteTransforms: map[string]map[string][]config.FieldTransform{"mydb.users": {
+			"email": {{Op: config.TransformTrim}, {Op: config.TransformLowercase}},
+			"name":  {{Op: config.TransformTrim}},
+		}},
+		IDFormats: map[string]string{"mydb.users": config.IDFormatUUID},
+	}
+	now := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	line := bson.D{{Key: "name", Value: " Ann "}, {Key: "email", Value: " ANN@EXAMPLE.COM "}, {Key: "role", Value: "admin"}}
+
+	doc, err := prepareOrdered(cfg, "mydb", "users", line, nil, now)
+	if err != nil {
+		t.Fatalf("prepareOrdered: %v", err)
+	}
+
+	var keys []string
+	fields := bson.M{}
+	for _, elem := range doc {
+		keys = append(keys, elem.Key)
+		fields[elem.Key] = elem.Value
+	}
+	if fmt.Sprint(keys) != "[name email _id createdAt status]" {
+		t.Fatalf("keys = %v, want the line's writable fields followed by the added ones", keys)
+	}
+	if fields["name"] != "Ann" || fields["email"] != "ann@example.com" {
+		t.Errorf("name, email = %q, %q; want them transformed", fields["name"], fields["email"])
+	}
+	if fields["status"] != "active" || fields["createdAt"] != now {
+		t.Errorf("status, createdAt = %v, %v; want the defaults", fields["status"], fields["createdAt"])
+	}
+	if id, ok := fields["_id"].(string); !ok || len(id) != 36 {
+		t.Errorf("_id = %v, want a generated UUID", fields["_id"])
+	}
+
+	// WRITABLE_FIELDS of users would strip tenantId, so the row filter is checked on orders
+	rowFilter := map[string]interface{}{"tenantId": "acme"}
+	line = bson.D{{Key: "name", Value: "Ann"}}
+	doc, err = prepareOrdered(cfg, "mydb", "orders", line, rowFilter, now)
+	if err != nil {
+		t.Fatalf("prepareOrdered with a row filter: %v", err)
+	}
+	if tenant := doc[len(doc)-1]; tenant.Key != "tenantId" || tenant.Value != "acme" {
+		t.Errorf("last field = %v, want the row filter's tenantId appended", tenant)
+	}
+	line = bson.D{{Key: "name", Value: "Ann"}, {Key: "tenantId", Value: "globex"}}
+	if _, err := prepareOrdered(cfg, "mydb", "orders", line, rowFilter, now); err == nil {
+		t.Error("a document of another tenant was accepted under a row filter")
+	}
+
+	cfg.WritableFieldsMode = config.WritableFieldsReject
+	line = bson.D{{Key: "name", Value: "Ann"}, {Key: "role", Value: "admin"}}
+	if _, err := prepareOrdered(cfg, "mydb", "users", line, nil, now); err == nil {
+		t.Error("a field outside WRITABLE_FIELDS was accepted in reject mode")
+	}
+}
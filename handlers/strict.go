@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"mongodb-go-proxy/config"
+)
+
+// strictMissing reports whether STRICT_COLLECTIONS is set and collection does not exist,
+// in which case an empty find or findOne answers 404 so a misspelled name is not mistaken
+// for a collection without matches. Callers only ask for empty results, and pass the
+// collection of the deployment that served the read.
+func strictMissing(ctx context.Context, cfg *config.Config, collection *mongo.Collection) (bool, error) {
+	if !cfg.StrictCollections {
+		return false, nil
+	}
+	names, err := collection.Database().ListCollectionNames(ctx, bson.M{"name": collection.Name()})
+	if err != nil {
+		return false, err
+	}
+	return len(names) == 0, nil
+}
+
+// missingCollectionMessage is the 404 error of a read on a collection that does not exist
+func missingCollectionMessage(collection *mongo.Collection) string {
+	return "Collection " + collection.Name() + " does not exist in " + collection.Database().Name()
+}
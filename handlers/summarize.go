@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/metrics"
+)
+
+// summaryOps maps the metric ops a summarize request may use to their $group accumulator
+var summaryOps = map[string]string{
+	"sum":   "$sum",
+	"avg":   "$avg",
+	"min":   "$min",
+	"max":   "$max",
+	"count": "$sum",
+}
+
+// dateBucketFormats maps the units of a summarize dateBucket to their $dateToString format.
+// Each format sorts chronologically as a string; weeks are ISO weeks starting on Monday.
+var dateBucketFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%G-W%V",
+	"month": "%Y-%m",
+}
+
+// SummaryDateBucket groups a summarize request by the day, week or month of a date field
+type SummaryDateBucket struct {
+	Field string `json:"field" example:"createdAt"`        // Date field to bucket on (required)
+	Unit  string `json:"unit" example:"day"`               // Bucket size: day, week or month (required)
+	As    string `json:"as,omitempty" example:"createdOn"` // Output name of the bucket in each row (optional, default: bucket)
+}
+
+// SummaryMetric is one value computed per group by a summarize request
+type SummaryMetric struct {
+	Op    string `json:"op" example:"sum"`                 // Aggregation: sum, avg, min, max or count (required)
+	Field string `json:"field,omitempty" example:"amount"` // Field to aggregate (required except for count)
+	As    string `json:"as" example:"total"`               // Output name of the metric in each row (required)
+}
+
+// SummarizeRequest represents the request for summarize action
+//
+//	@Description	Request body for summarize action. Groups matching documents by one or more fields and computes the requested metrics per group.
+type SummarizeRequest struct {
+	baseRequest
+	GroupBy    json.RawMessage    `json:"groupBy,omitempty" swaggertype:"array,string"` // Field or list of fields to group by (optional, default: one group over all matches). Example: ["country","status"]
+	DateBucket *SummaryDateBucket `json:"dateBucket,omitempty"`                         // Also group by the day, week or month of a date field (optional)
+	Metrics    []SummaryMetric    `json:"metrics"`                                      // Metrics to compute per group (required)
+	Filter     interface{}        `json:"filter,omitempty" swaggertype:"object"`        // MongoDB filter query (optional). Example: {"status":"paid"}
+}
+
+// SummarizeResponse represents the response for summarize action
+type SummarizeResponse struct {
+	Rows      []map[string]interface{} `json:"rows" swaggertype:"array,object"` // One row per group: the groupBy fields followed by the metrics
+	Truncated bool                     `json:"truncated" example:"false"`       // True when rows were cut at MAX_AGGREGATE_RESULTS
+}
+
+// Summarize godoc
+//
+//	@Summary		Summarize documents per group
+//	@Description	Groups the documents matching filter by the groupBy fields and computes sum, avg, min, max or count metrics for each group, without sending a raw aggregation pipeline. With dateBucket, rows are also grouped by the day, week or month of a date field and sorted chronologically first. Rows are sorted by the groupBy fields and capped at MAX_AGGREGATE_RESULTS.
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		SummarizeRequest	true	"Summarize request"
+//	@Success		200		{object}	SummarizeResponse	"Successfully computed summary"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid groupBy, dateBucket, metrics or filter"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/data-api/action/summarize [post]
+func (h *DataAPIHandler) Summarize(c echo.Context) error {
+	var req SummarizeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if req.Database == "" || req.Collection == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "database and collection are required",
+		})
+	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "summarize", req.Database, req.Collection)
+
+	groupBy, err := parseGroupBy(req.GroupBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid groupBy: " + err.Error(),
+		})
+	}
+
+	if len(req.Metrics) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "metrics array is required and cannot be empty",
+		})
+	}
+
+	filter, err := h.buildFilter(req.Filter)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid filter: " + err.Error(),
+		})
+	}
+	filter = scopeFilter(h.cfg, c, filter)
+
+	pipeline, err := summaryPipeline(filter, groupBy, req.DateBucket, req.Metrics)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	// Cap the rows like aggregate does, fetching one extra to detect truncation
+	maxResults := aggregateResultCap(h.cfg, c)
+	if maxResults > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(maxResults) + 1}})
+	}
+
+	ctx, cancel := operationContext(h.cfg, "summarize", 30*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetComment(operationComment(c)))
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	rows := []bson.M{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return serverError(c, err, err.Error())
+	}
+	flagSlowRead(ctx, c, h.cfg)
+
+	truncated := false
+	if maxResults > 0 && len(rows) > maxResults {
+		rows = rows[:maxResults]
+		truncated = true
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"rows":      rows,
+		"truncated": truncated,
+	})
+}
+
+// parseGroupBy accepts a single field name or a list of them
+func parseGroupBy(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var fields []string
+	var field string
+	if err := json.Unmarshal(raw, &field); err == nil {
+		fields = []string{field}
+	} else if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, errors.New("must be a field name or an array of field names")
+	}
+
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if field == "" || strings.HasPrefix(field, "$") {
+			return nil, fmt.Errorf("%q is not a field name", field)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%q is listed more than once", field)
+		}
+		seen[field] = true
+	}
+	// A field and its own sub-path would be projected onto the same output path
+	for _, field := range fields {
+		for _, other := range fields {
+			if strings.HasPrefix(other, field+".") {
+				return nil, fmt.Errorf("%q and %q overlap", field, other)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// summaryPipeline assembles the $match, $group, $project and $sort stages of a summarize
+// request. Group keys are numbered inside _id so dotted field paths can be grouped on,
+// then projected back under their own names ahead of the metrics. A date bucket is keyed
+// "d" and sorted first; documents without a usable date fall out of the buckets.
+func summaryPipeline(filter bson.M, groupBy []string, dateBucket *SummaryDateBucket, summaryMetrics []SummaryMetric) ([]bson.D, error) {
+	group := bson.D{}
+	project := bson.D{{Key: "_id", Value: 0}}
+	sort := bson.D{}
+	outputs := make(map[string]bool, len(groupBy)+len(summaryMetrics)+1)
+
+	var id interface{}
+	var bucketAs string
+	if len(groupBy) > 0 || dateBucket != nil {
+		keys := bson.D{}
+		if dateBucket != nil {
+			format, ok := dateBucketFormats[dateBucket.Unit]
+			if !ok {
+				return nil, errors.New("dateBucket: unit must be one of day, week, month")
+			}
+			if dateBucket.Field == "" || strings.HasPrefix(dateBucket.Field, "$") {
+				return nil, errors.New("dateBucket: field is required")
+			}
+			bucketAs = dateBucket.As
+			if bucketAs == "" {
+				bucketAs = "bucket"
+			}
+			if bucketAs == "_id" || strings.HasPrefix(bucketAs, "$") || strings.Contains(bucketAs, ".") {
+				return nil, errors.New("dateBucket: as must be a plain field name other than _id")
+			}
+			keys = append(keys, bson.E{Key: "d", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+				{Key: "format", Value: format},
+				{Key: "date", Value: "$" + dateBucket.Field},
+				{Key: "timezone", Value: "UTC"},
+			}}}})
+			project = append(project, bson.E{Key: bucketAs, Value: "$_id.d"})
+			sort = append(sort, bson.E{Key: "_id.d", Value: 1})
+			outputs[bucketAs] = true
+		}
+		for i, field := range groupBy {
+			key := fmt.Sprintf("k%d", i)
+			keys = append(keys, bson.E{Key: key, Value: "$" + field})
+			top := strings.SplitN(field, ".", 2)[0]
+			if top == bucketAs {
+				return nil, fmt.Errorf("groupBy %q collides with the dateBucket output", field)
+			}
+			project = append(project, bson.E{Key: field, Value: "$_id." + key})
+			sort = append(sort, bson.E{Key: "_id." + key, Value: 1})
+			outputs[top] = true
+		}
+		id = keys
+	}
+	group = append(group, bson.E{Key: "_id", Value: id})
+
+	for i, metric := range summaryMetrics {
+		accumulator, ok := summaryOps[metric.Op]
+		if !ok {
+			return nil, fmt.Errorf("metrics[%d]: op must be one of sum, avg, min, max, count", i)
+		}
+		if metric.As == "" || metric.As == "_id" || strings.HasPrefix(metric.As, "$") || strings.Contains(metric.As, ".") {
+			return nil, fmt.Errorf("metrics[%d]: as must be a plain field name other than _id", i)
+		}
+		if outputs[metric.As] {
+			return nil, fmt.Errorf("metrics[%d]: as %q collides with another output field", i, metric.As)
+		}
+		outputs[metric.As] = true
+
+		var operand interface{} = 1
+		if metric.Op != "count" {
+			if metric.Field == "" || strings.HasPrefix(metric.Field, "$") {
+				return nil, fmt.Errorf("metrics[%d]: field is required for %s", i, metric.Op)
+			}
+			operand = "$" + metric.Field
+		} else if metric.Field != "" {
+			return nil, fmt.Errorf("metrics[%d]: count does not take a field", i)
+		}
+
+		group = append(group, bson.E{Key: metric.As, Value: bson.D{{Key: accumulator, Value: operand}}})
+		project = append(project, bson.E{Key: metric.As, Value: 1})
+	}
+
+	pipeline := []bson.D{}
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: filter}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$group", Value: group}})
+	if dateBucket != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "_id.d", Value: bson.D{{Key: "$ne", Value: nil}}}}}})
+	}
+	if len(sort) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sort}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: project}})
+	return pipeline, nil
+}
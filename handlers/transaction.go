@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// maxTransactionOperations caps the number of operations a single transaction may carry
+const maxTransactionOperations = 100
+
+// TransactionOperation is one write of a transaction request
+type TransactionOperation struct {
+	Action     string      `json:"action" example:"updateOne"`              // insertOne, updateOne, updateMany, deleteOne or deleteMany (required)
+	Database   string      `json:"database,omitempty" example:"bank"`       // Database of this operation (optional, default: the request database)
+	Collection string      `json:"collection" example:"accounts"`           // Collection of this operation (required)
+	Document   interface{} `json:"document,omitempty" swaggertype:"object"` // Document to insert (insertOne). Example: {"account":"A-1","amount":-50}
+	Filter     interface{} `json:"filter,omitempty" swaggertype:"object"`   // MongoDB filter query (update and delete actions). Example: {"_id":"A-1"}
+	Update     interface{} `json:"update,omitempty" swaggertype:"object"`   // Update document (update actions). Example: {"$inc":{"balance":-50}}
+	ConfirmAll bool        `json:"confirmAll,omitempty" example:"false"`    // Must be true for updateMany/deleteMany with an empty filter when REQUIRE_FILTER_ON_DESTRUCTIVE is enabled
+}
+
+// TransactionRequest represents the request for transaction action
+//
+//	@Description	Request body for transaction action. Operations run in order in one multi-document transaction and may target different collections and databases.
+type TransactionRequest struct {
+	Database   string                 `json:"database,omitempty" example:"bank"` // Default database of the operations (optional if every operation names one)
+	Operations []TransactionOperation `json:"operations"`                        // Writes to apply atomically, in order (required, at most 100)
+}
+
+// TransactionResult reports the outcome of one transaction operation
+type TransactionResult struct {
+	Index         int    `json:"index" example:"0"`                                       // 0-based position of the operation in the request
+	Action        string `json:"action" example:"updateOne"`                              // Action of the operation
+	InsertedID    string `json:"insertedId,omitempty" example:"507f1f77bcf86cd799439011"` // ID of the inserted document (insertOne)
+	MatchedCount  int64  `json:"matchedCount,omitempty" example:"1"`                      // Documents matched (update actions)
+	ModifiedCount int64  `json:"modifiedCount,omitempty" example:"1"`                     // Documents modified (update actions)
+	DeletedCount  int64  `json:"deletedCount,omitempty" example:"1"`                      // Documents deleted (delete actions)
+}
+
+// TransactionResponse represents the response for transaction action
+type TransactionResponse struct {
+	Results []TransactionResult `json:"results"` // One result per operation, in request order
+}
+
+// transactionWrite is a validated transaction operation ready to run
+type transactionWrite struct {
+	action     string
+	database   string
+	collection string
+	document   bson.M
+	filter     bson.M
+	update     bson.M
+}
+
+// Transaction godoc
+//
+//	@Summary		Apply writes atomically in one transaction
+//	@Description	Runs insertOne, updateOne, updateMany, deleteOne and deleteMany operations in order inside one multi-document transaction. Operations may target different collections and databases of the cluster. Either every operation is applied or none is. Requires a replica set or sharded cluster.
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		TransactionRequest	true	"Transaction request"
+//	@Success		200		{object}	TransactionResponse	"Transaction committed"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid operation, or empty filter without confirmAll"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials or database not in ALLOWED_DATABASES"
+//	@Failure		409		{object}	map[string]string	"Conflict - an operation violated a unique index; nothing was applied"
+//	@Failure		500		{object}	map[string]string	"Internal server error; nothing was applied"
+//	@Router			/v1/data-api/action/transaction [post]
+func (h *DataAPIHandler) Transaction(c echo.Context) error {
+	var req TransactionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if len(req.Operations) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "operations array is required and cannot be empty",
+		})
+	}
+	if len(req.Operations) > maxTransactionOperations {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("operations cannot contain more than %d entries", maxTransactionOperations),
+		})
+	}
+
+	trackedDatabase := req.Database
+	if trackedDatabase == "" {
+		trackedDatabase = req.Operations[0].Database
+	}
+	metrics.Track(c, "transaction", trackedDatabase, "*")
+
+	// Validate everything up front so a bad operation never opens a transaction
+	writes := make([]transactionWrite, len(req.Operations))
+	now := time.Now()
+	for i, op := range req.Operations {
+		write, status, err := h.transactionWrite(c, req.Database, op, now)
+		if err != nil {
+			return c.JSON(status, map[string]string{
+				"error": fmt.Sprintf("operations[%d]: %s", i, err.Error()),
+			})
+		}
+		writes[i] = write
+	}
+
+	ctx, cancel := operationContext(h.cfg, "transaction", 30*time.Second)
+	defer cancel()
+
+	if isDryRun(c) {
+		results := make([]interface{}, len(writes))
+		for i, write := range writes {
+			result := map[string]interface{}{"index": i, "action": write.action}
+			if write.action == "insertOne" {
+				result["insertedCount"] = 1
+			} else {
+				collection, err := h.dbClient.GetCollection(write.database, write.collection)
+				if err != nil {
+					return serverError(c, err, "Failed to get collection: "+err.Error())
+				}
+				var limit int64
+				if write.action == "updateOne" || write.action == "deleteOne" {
+					limit = 1
+				}
+				count, err := dryRunCount(ctx, collection, write.filter, limit, operationComment(c))
+				if err != nil {
+					return serverError(c, err, err.Error())
+				}
+				if write.action == "deleteOne" || write.action == "deleteMany" {
+					result["deletedCount"] = count
+				} else {
+					result["matchedCount"] = count
+				}
+			}
+			results[i] = result
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":  true,
+			"results": results,
+		})
+	}
+
+	// The driver retries the callback on transient errors, so each attempt starts over
+	failedIndex := -1
+	committed, err := h.dbClient.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		results := make([]interface{}, len(writes))
+		for i, write := range writes {
+			failedIndex = i
+			result, err := h.runTransactionWrite(sc, write, operationComment(c))
+			if err != nil {
+				return nil, err
+			}
+			result["index"] = i
+			result["action"] = write.action
+			results[i] = result
+		}
+		failedIndex = -1
+		return results, nil
+	})
+	if err != nil {
+		auth.RecordUpstreamError(c, err)
+		status, message := writeFailure(err)
+		if failedIndex >= 0 {
+			message = fmt.Sprintf("operations[%d]: %s", failedIndex, message)
+		}
+		return c.JSON(status, map[string]string{
+			"error": "Transaction aborted, nothing was applied: " + message,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"results": committed,
+	})
+}
+
+// transactionWrite validates one operation and resolves its namespace, returning the
+// status to answer with when it is invalid
+func (h *DataAPIHandler) transactionWrite(c echo.Context, defaultDatabase string, op TransactionOperation, now time.Time) (transactionWrite, int, error) {
+	write := transactionWrite{
+		action:     op.Action,
+		database:   op.Database,
+		collection: op.Collection,
+	}
+	if write.database == "" {
+		write.database = defaultDatabase
+	}
+	if write.database == "" || write.collection == "" {
+		return write, http.StatusBadRequest, errors.New("database and collection are required")
+	}
+	if err := validateNamespace(write.database, write.collection); err != nil {
+		return write, http.StatusBadRequest, err
+	}
+	if !h.cfg.IsAllowedDatabase(write.database) {
+		return write, http.StatusForbidden, fmt.Errorf("database %q is not allowed", write.database)
+	}
+
+	var err error
+	switch op.Action {
+	case "insertOne":
+		if op.Document == nil {
+			return write, http.StatusBadRequest, errors.New("document is required")
+		}
+		if write.document, err = extJSONDocument(op.Document); err != nil {
+			return write, http.StatusBadRequest, fmt.Errorf("invalid document: %w", err)
+		}
+		if err := restrictDocument(h.cfg, write.database, write.collection, write.document); err != nil {
+			return write, http.StatusBadRequest, err
+		}
+		applyDefaults(h.cfg, write.database, write.collection, write.document, now)
+		applyTransforms(h.cfg, write.database, write.collection, write.document)
+		if err := scopeDocument(h.cfg, c, write.document); err != nil {
+			return write, http.StatusBadRequest, err
+		}
+		if err := assignID(h.cfg, write.database, write.collection, write.document); err != nil {
+			return write, http.StatusInternalServerError, fmt.Errorf("failed to generate _id: %w", err)
+		}
+		return write, http.StatusOK, nil
+	case "updateOne", "updateMany", "deleteOne", "deleteMany":
+	default:
+		return write, http.StatusBadRequest, errors.New("action must be one of insertOne, updateOne, updateMany, deleteOne, deleteMany")
+	}
+
+	if op.Filter == nil {
+		return write, http.StatusBadRequest, errors.New("filter is required")
+	}
+	if write.filter, err = h.buildFilter(op.Filter); err != nil {
+		return write, http.StatusBadRequest, fmt.Errorf("invalid filter: %w", err)
+	}
+	if (op.Action == "updateMany" || op.Action == "deleteMany") && !destructiveFilterAllowed(h.cfg, write.filter, op.ConfirmAll) {
+		return write, http.StatusBadRequest, errors.New(errEmptyDestructiveFilter)
+	}
+	write.filter = scopeFilter(h.cfg, c, write.filter)
+	if op.Action == "deleteMany" && h.cfg.DeleteConfirmation {
+		// A transaction has no second phase to present a confirmation token in
+		return write, http.StatusBadRequest, errors.New("deleteMany is not allowed in a transaction while DELETE_CONFIRMATION is enabled")
+	}
+
+	if op.Action == "updateOne" || op.Action == "updateMany" {
+		if op.Update == nil {
+			return write, http.StatusBadRequest, errors.New("update is required")
+		}
+		if write.update, err = h.buildUpdate(op.Update); err != nil {
+			return write, http.StatusBadRequest, fmt.Errorf("invalid update: %w", err)
+		}
+		if err := restrictUpdate(h.cfg, write.database, write.collection, write.update); err != nil {
+			return write, http.StatusBadRequest, err
+		}
+		if err := guardRowFilterUpdate(h.cfg, c, write.update); err != nil {
+			return write, http.StatusBadRequest, err
+		}
+		transformUpdate(h.cfg, write.database, write.collection, write.update)
+	}
+	return write, http.StatusOK, nil
+}
+
+// runTransactionWrite applies one validated operation inside the transaction, tagged
+// with comment like any other operation of the request
+func (h *DataAPIHandler) runTransactionWrite(sc mongo.SessionContext, write transactionWrite, comment string) (map[string]interface{}, error) {
+	collection, err := h.dbClient.GetCollection(write.database, write.collection)
+	if err != nil {
+		return nil, err
+	}
+
+	switch write.action {
+	case "insertOne":
+		result, err := collection.InsertOne(sc, write.document, options.InsertOne().SetComment(comment))
+		if err != nil {
+			return nil, err
+		}
+		insertedID := result.InsertedID
+		if oid, ok := insertedID.(primitive.ObjectID); ok {
+			insertedID = oid.Hex()
+		}
+		return map[string]interface{}{"insertedId": insertedID}, nil
+	case "updateOne", "updateMany":
+		update := collection.UpdateOne
+		if write.action == "updateMany" {
+			update = collection.UpdateMany
+		}
+		result, err := update(sc, write.filter, write.update, options.Update().SetComment(comment))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"matchedCount":  result.MatchedCount,
+			"modifiedCount": result.ModifiedCount,
+		}, nil
+	default:
+		remove := collection.DeleteOne
+		if write.action == "deleteMany" {
+			remove = collection.DeleteMany
+		}
+		result, err := remove(sc, write.filter, options.Delete().SetComment(comment))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"deletedCount": result.DeletedCount}, nil
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+// transformValue runs the WRITE_TRANSFORMS steps of one field. present is whether the
+// field is set; a default only fills a missing or null value, and string transforms
+// leave other types alone.
+func transformValue(steps []config.FieldTransform, value interface{}, present bool) (interface{}, bool) {
+	for _, step := range steps {
+		switch step.Op {
+		case config.TransformDefault:
+			if !present || value == nil {
+				value, present = step.Value, true
+			}
+		case config.TransformLowercase:
+			if s, ok := value.(string); ok {
+				value = strings.ToLower(s)
+			}
+		case config.TransformTrim:
+			if s, ok := value.(string); ok {
+				value = strings.TrimSpace(s)
+			}
+		}
+	}
+	return value, present
+}
+
+// applyTransforms normalizes the top-level fields of a document about to be inserted
+// with the WRITE_TRANSFORMS of its collection. It runs after applyDefaults.
+func applyTransforms(cfg *config.Config, database, collection string, doc bson.M) {
+	for field, steps := range cfg.Transforms(database, collection) {
+		value, present := doc[field]
+		if value, present = transformValue(steps, value, present); present {
+			doc[field] = value
+		}
+	}
+}
+
+// transformUpdate applies the WRITE_TRANSFORMS of a collection to the values an update
+// writes with $set and $setOnInsert. Fields the update does not set are left alone, so a
+// default only replaces an explicit null.
+func transformUpdate(cfg *config.Config, database, collection string, update bson.M) {
+	transforms := cfg.Transforms(database, collection)
+	if len(transforms) == 0 {
+		return
+	}
+	for _, operator := range []string{"$set", "$setOnInsert"} {
+		fields, ok := update[operator].(bson.M)
+		if !ok {
+			continue
+		}
+		for field, steps := range transforms {
+			if value, present := fields[field]; present {
+				fields[field], _ = transformValue(steps, value, true)
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/metrics"
+)
+
+// maxUpdateBulkEntries caps the number of updates a single updateBulk request may carry
+const maxUpdateBulkEntries = 1000
+
+// UpdateBulkEntry is one update of an updateBulk request
+type UpdateBulkEntry struct {
+	Filter interface{} `json:"filter" swaggertype:"object"`      // MongoDB filter query (required). Example: {"_id":"507f1f77bcf86cd799439011"}
+	Update interface{} `json:"update" swaggertype:"object"`      // Update document (required). Example: {"$set":{"name":"Jane"}}
+	Upsert bool        `json:"upsert,omitempty" example:"false"` // Insert a document when the filter matches nothing
+}
+
+// UpdateBulkRequest represents the request for updateBulk action
+//
+//	@Description	Request body for updateBulk action. Each entry updates the first document matching its own filter; all entries are sent in one bulk write.
+type UpdateBulkRequest struct {
+	baseRequest
+	Updates []UpdateBulkEntry `json:"updates"`                          // Updates to apply (required, at most 1000)
+	Ordered *bool             `json:"ordered,omitempty" example:"true"` // Stop at the first failed update (default true)
+}
+
+// UpdateBulkResult reports the outcome of one updateBulk entry
+type UpdateBulkResult struct {
+	Index      int         `json:"index" example:"0"`                                                            // 0-based position of the entry in the request
+	Applied    bool        `json:"applied" example:"true"`                                                       // Whether MongoDB applied the update
+	UpsertedID interface{} `json:"upsertedId,omitempty" swaggertype:"string" example:"507f1f77bcf86cd799439011"` // ID of the inserted document, if the entry upserted
+	Code       int         `json:"code,omitempty" example:"11000"`                                               // MongoDB error code, if the entry failed
+	Error      string      `json:"error,omitempty"`                                                              // MongoDB error message, if the entry failed
+}
+
+// UpdateBulkResponse represents the response for updateBulk action
+type UpdateBulkResponse struct {
+	MatchedCount  int64              `json:"matchedCount" example:"3"`  // Number of documents matched across all entries
+	ModifiedCount int64              `json:"modifiedCount" example:"2"` // Number of documents modified across all entries
+	UpsertedCount int64              `json:"upsertedCount" example:"1"` // Number of documents inserted by upserts
+	Results       []UpdateBulkResult `json:"results"`                   // One result per entry, in request order
+}
+
+// UpdateBulk godoc
+//
+//	@Summary		Apply many single-document updates
+//	@Description	Applies a distinct update to the first document matching each entry's filter in one bulk write. Returns aggregated counts and one result per entry. If MongoDB rejects some entries, the response is 207 with the same body plus an error summary and writeErrors.
+//	@Tags			data-api
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		UpdateBulkRequest	true	"Update bulk request"
+//	@Success		200		{object}	UpdateBulkResponse	"Successfully applied updates"
+//	@Success		207		{object}	UpdateBulkResponse	"Some updates were rejected"
+//	@Failure		400		{object}	map[string]string	"Bad request - missing or invalid entries"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - invalid credentials"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/data-api/action/updateBulk [post]
+func (h *DataAPIHandler) UpdateBulk(c echo.Context) error {
+	var req UpdateBulkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if req.Database == "" || req.Collection == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "database and collection are required",
+		})
+	}
+	if err := validateNamespace(req.Database, req.Collection); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	metrics.Track(c, "updateBulk", req.Database, req.Collection)
+
+	if len(req.Updates) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "updates array is required and cannot be empty",
+		})
+	}
+
+	if len(req.Updates) > maxUpdateBulkEntries {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "updates cannot contain more than 1000 entries",
+		})
+	}
+
+	// Validate and build every entry up front so a bad entry fails the whole request
+	models := make([]mongo.WriteModel, len(req.Updates))
+	filters := make([]bson.M, len(req.Updates))
+	for i, entry := range req.Updates {
+		if entry.Filter == nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("updates[%d]: filter is required", i),
+			})
+		}
+		if entry.Update == nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("updates[%d]: update is required", i),
+			})
+		}
+
+		filter, err := h.buildFilter(entry.Filter)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("updates[%d]: invalid filter: %s", i, err.Error()),
+			})
+		}
+		filter = scopeFilter(h.cfg, c, filter)
+		update, err := h.buildUpdate(entry.Update)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("updates[%d]: invalid update: %s", i, err.Error()),
+			})
+		}
+		if err := restrictUpdate(h.cfg, req.Database, req.Collection, update); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("updates[%d]: %s", i, err.Error()),
+			})
+		}
+		if err := guardRowFilterUpdate(h.cfg, c, update); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("updates[%d]: %s", i, err.Error()),
+			})
+		}
+		transformUpdate(h.cfg, req.Database, req.Collection, update)
+
+		filters[i] = filter
+		models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(entry.Upsert)
+	}
+
+	ctx, cancel := operationContext(h.cfg, "updateBulk", 30*time.Second)
+	defer cancel()
+
+	collection, err := h.dbClient.GetCollection(req.Database, req.Collection)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	if isDryRun(c) {
+		var matched int64
+		for _, filter := range filters {
+			count, err := dryRunCount(ctx, collection, filter, 1, operationComment(c))
+			if err != nil {
+				return serverError(c, err, err.Error())
+			}
+			matched += count
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dryRun":       true,
+			"matchedCount": matched,
+		})
+	}
+
+	ordered := req.Ordered == nil || *req.Ordered
+	result, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered).SetComment(operationComment(c)))
+
+	var writeErrors []WriteError
+	var writeConcernError string
+	if err != nil {
+		var ok bool
+		writeErrors, writeConcernError, ok = bulkWriteErrors(err)
+		if !ok || result == nil {
+			return serverError(c, err, err.Error())
+		}
+	}
+
+	results := make([]UpdateBulkResult, len(models))
+	for i := range results {
+		results[i].Index = i
+	}
+	succeeded := succeededIndexes(len(models), writeErrors, ordered)
+	for _, i := range succeeded {
+		results[i].Applied = true
+	}
+	for _, we := range writeErrors {
+		results[we.Index].Code = we.Code
+		results[we.Index].Error = we.Message
+	}
+	for i, id := range result.UpsertedIDs {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			id = oid.Hex()
+		}
+		results[i].UpsertedID = id
+	}
+
+	response := map[string]interface{}{
+		"matchedCount":  result.MatchedCount,
+		"modifiedCount": result.ModifiedCount,
+		"upsertedCount": result.UpsertedCount,
+		"results":       results,
+	}
+
+	if err != nil {
+		// Report which entries were applied so clients can retry only the failed ones
+		response["error"] = fmt.Sprintf("%d of %d updates could not be applied", len(models)-len(succeeded), len(models))
+		response["writeErrors"] = writeErrors
+		if writeConcernError != "" {
+			response["writeConcernError"] = writeConcernError
+			if len(writeErrors) == 0 {
+				response["error"] = "Write concern error: " + writeConcernError
+			}
+		}
+		return c.JSON(http.StatusMultiStatus, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UpdateDescription describes what an update wrote, in the shape of a change stream
+// event's updateDescription. It is computed from the update operators rather than read
+// back from the server, so values derived from the stored document are not known.
+type UpdateDescription struct {
+	UpdatedFields  map[string]interface{} `json:"updatedFields" swaggertype:"object"`  // Fields written with a literal value ($set, $setOnInsert on upsert), keyed by path. Example: {"name":"Jane"}
+	RemovedFields  []string               `json:"removedFields"`                       // Fields removed by $unset or renamed away by $rename. Example: ["nickname"]
+	ComputedFields map[string]string      `json:"computedFields" swaggertype:"object"` // Fields whose new value depends on the stored document, mapped to the operator. Example: {"visits":"$inc"}
+}
+
+// describeUpdate derives an UpdateDescription from an update document built by
+// buildUpdate. $setOnInsert only counts when the update inserted a document.
+func describeUpdate(update bson.M, inserted bool) UpdateDescription {
+	description := UpdateDescription{
+		UpdatedFields:  map[string]interface{}{},
+		RemovedFields:  []string{},
+		ComputedFields: map[string]string{},
+	}
+
+	for operator, operand := range update {
+		fields, ok := operand.(bson.M)
+		if !ok {
+			continue
+		}
+		switch operator {
+		case "$set":
+			for field, value := range fields {
+				description.UpdatedFields[field] = value
+			}
+		case "$setOnInsert":
+			if !inserted {
+				continue
+			}
+			for field, value := range fields {
+				description.UpdatedFields[field] = value
+			}
+		case "$unset":
+			for field := range fields {
+				description.RemovedFields = append(description.RemovedFields, field)
+			}
+		case "$rename":
+			for field, target := range fields {
+				description.RemovedFields = append(description.RemovedFields, field)
+				if name, ok := target.(string); ok {
+					description.ComputedFields[name] = operator
+				}
+			}
+		default:
+			// $inc, $mul, $min, $max, $currentDate, $push, $pull and the other operators
+			// compute the new value from the stored one
+			for field := range fields {
+				description.ComputedFields[field] = operator
+			}
+		}
+	}
+
+	sort.Strings(description.RemovedFields)
+	return description
+}
+
+// camelCase renders the description as a map, so ExtJSONSerializer converts the BSON
+// values in updatedFields like those of any other document
+func (d UpdateDescription) camelCase() map[string]interface{} {
+	return map[string]interface{}{
+		"updatedFields":  d.UpdatedFields,
+		"removedFields":  d.RemovedFields,
+		"computedFields": d.ComputedFields,
+	}
+}
+
+// snakeCase renders the description with the REST API's snake_case keys
+func (d UpdateDescription) snakeCase() map[string]interface{} {
+	return map[string]interface{}{
+		"updated_fields":  d.UpdatedFields,
+		"removed_fields":  d.RemovedFields,
+		"computed_fields": d.ComputedFields,
+	}
+}
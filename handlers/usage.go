@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"mongodb-go-proxy/config"
+	"mongodb-go-proxy/metrics"
+)
+
+// Usage headers set on accounted reads when USAGE_ACCOUNTING is enabled. The examined
+// ones are only set in explain mode.
+const (
+	UsageDocumentsReturnedHeader = "X-Usage-Documents-Returned"
+	UsageBytesReturnedHeader     = "X-Usage-Bytes-Returned"
+	UsageDocumentsExaminedHeader = "X-Usage-Documents-Examined"
+	UsageKeysExaminedHeader      = "X-Usage-Keys-Examined"
+	UsageBytesScannedHeader      = "X-Usage-Bytes-Scanned"
+)
+
+// UsageHeaders lists the usage headers, for CORS
+var UsageHeaders = []string{
+	UsageDocumentsReturnedHeader,
+	UsageBytesReturnedHeader,
+	UsageDocumentsExaminedHeader,
+	UsageKeysExaminedHeader,
+	UsageBytesScannedHeader,
+}
+
+// accountDocuments reports the cursor usage of a read that returned docs: how many
+// documents and how many BSON bytes. It must run before the response is written.
+func accountDocuments(c echo.Context, cfg *config.Config, docs ...bson.M) {
+	if cfg.UsageAccounting == config.UsageAccountingOff {
+		return
+	}
+	reportUsage(c, returnedUsage(docs), false)
+}
+
+// accountFind is accountDocuments for a find. In explain mode the find is also explained
+// with executionStats, which runs it a second time, to report what the server examined.
+// The bytes behind the examined documents are estimated from the collection's average
+// document size. A failed explain is logged and leaves the cursor figures.
+func accountFind(c echo.Context, cfg *config.Config, q slowFind, docs []bson.M) {
+	if cfg.UsageAccounting == config.UsageAccountingOff {
+		return
+	}
+	usage := returnedUsage(docs)
+	if cfg.UsageAccounting != config.UsageAccountingExplain {
+		reportUsage(c, usage, false)
+		return
+	}
+
+	ctx, cancel := operationContext(cfg, "usageExplain", 10*time.Second)
+	defer cancel()
+
+	explained, err := explainFind(ctx, q.collection, q.filter, q.sort, q.projection, q.limit, q.skip)
+	if err != nil {
+		log.Printf("Usage explain failed: %s (request %s): %v", q.action, operationComment(c), err)
+		reportUsage(c, usage, false)
+		return
+	}
+	usage.DocumentsExamined = explained.DocsExamined
+	usage.KeysExamined = explained.KeysExamined
+	if size, err := averageDocumentSize(ctx, q.collection, operationComment(c)); err == nil {
+		usage.BytesScanned = int64(size * float64(explained.DocsExamined))
+	}
+	reportUsage(c, usage, true)
+}
+
+// returnedUsage measures docs by their BSON encoding
+func returnedUsage(docs []bson.M) metrics.Usage {
+	usage := metrics.Usage{DocumentsReturned: int64(len(docs))}
+	for _, doc := range docs {
+		if raw, err := bson.Marshal(doc); err == nil {
+			usage.BytesReturned += int64(len(raw))
+		}
+	}
+	return usage
+}
+
+// reportUsage sets the usage headers and records usage for the ledger
+func reportUsage(c echo.Context, usage metrics.Usage, explained bool) {
+	header := c.Response().Header()
+	header.Set(UsageDocumentsReturnedHeader, strconv.FormatInt(usage.DocumentsReturned, 10))
+	header.Set(UsageBytesReturnedHeader, strconv.FormatInt(usage.BytesReturned, 10))
+	if explained {
+		header.Set(UsageDocumentsExaminedHeader, strconv.FormatInt(usage.DocumentsExamined, 10))
+		header.Set(UsageKeysExaminedHeader, strconv.FormatInt(usage.KeysExamined, 10))
+		header.Set(UsageBytesScannedHeader, strconv.FormatInt(usage.BytesScanned, 10))
+	}
+	metrics.RecordUsage(c, usage)
+}
+
+// averageDocumentSize is the average stored BSON size of the documents of collection,
+// summed over all shards. Views have no storage stats and fail.
+func averageDocumentSize(ctx context.Context, collection *mongo.Collection, comment string) (float64, error) {
+	pipeline := mongo.Pipeline{{{Key: "$collStats", Value: bson.D{{Key: "storageStats", Value: bson.D{}}}}}}
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetComment(comment))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var shards []struct {
+		StorageStats struct {
+			Size  float64 `bson:"size"`
+			Count float64 `bson:"count"`
+		} `bson:"storageStats"`
+	}
+	if err := cursor.All(ctx, &shards); err != nil {
+		return 0, err
+	}
+	var size, count float64
+	for _, shard := range shards {
+		size += shard.StorageStats.Size
+		count += shard.StorageStats.Count
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return size / count, nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"mongodb-go-proxy/metrics"
+	auth "mongodb-go-proxy/middleware"
+)
+
+// namespaceExistsCode is the MongoDB error code for creating a collection or view that already exists
+const namespaceExistsCode = 48
+
+// CreateViewRequest represents the request for creating a view
+//
+//	@Description	Request body for creating a read-only view. Pipeline is an array of MongoDB aggregation stages (Extended JSON).
+type CreateViewRequest struct {
+	Name     string            `json:"name" example:"paid_orders"`          // View name (required)
+	ViewOn   string            `json:"viewOn" example:"orders"`             // Source collection or view in the same database (required)
+	Pipeline []json.RawMessage `json:"pipeline" swaggertype:"array,object"` // Aggregation stages defining the view (optional). Example: [{"$match":{"status":"paid"}}]
+}
+
+// CreateViewResponse represents the response for creating a view
+type CreateViewResponse struct {
+	Database string `json:"database" example:"mydb"`    // Database name
+	Name     string `json:"name" example:"paid_orders"` // View name
+	ViewOn   string `json:"view_on" example:"orders"`   // Source collection or view
+}
+
+// CreateView godoc
+//
+//	@Summary		Create a view
+//	@Description	Creates a read-only view on a collection or view of the same database. The view is read through the document routes like a collection. $out and $merge are not allowed in its pipeline.
+//	@Tags			collections
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			db		path		string				true	"Database name"	example("mydb")
+//	@Param			request	body		CreateViewRequest	true	"View definition"
+//	@Success		201		{object}	CreateViewResponse	"Successfully created view"
+//	@Header			201		{string}	Location			"URL of the view's documents"
+//	@Failure		400		{object}	map[string]string	"Bad request - invalid name, pipeline, or missing source collection"
+//	@Failure		401		{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403		{object}	map[string]string	"Forbidden - the api-key has a row filter"
+//	@Failure		409		{object}	map[string]string	"Conflict - a collection or view with the name exists"
+//	@Failure		500		{object}	map[string]string	"Internal server error"
+//	@Router			/v1/databases/{db}/views [post]
+func (h *MongoHandler) CreateView(c echo.Context) error {
+	dbName := c.Param("db")
+
+	var req CreateViewRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if dbName == "" || req.Name == "" || req.ViewOn == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Database, name and viewOn are required",
+		})
+	}
+	for _, name := range []string{req.Name, req.ViewOn} {
+		if err := auth.ValidateCollectionName(dbName, name); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+	}
+	if req.Name == req.ViewOn {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "A view cannot be defined on itself",
+		})
+	}
+
+	// Reads of a view filter its output, which the view's own stages can reshape to pass
+	// any predicate, such as by setting the predicate's fields
+	if h.cfg.RowFilter(auth.KeyTier(c)) != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "An api-key with a row filter cannot create views",
+		})
+	}
+
+	metrics.Track(c, "createView", dbName, req.Name)
+
+	pipeline, err := buildPipeline(req.Pipeline)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid pipeline: " + err.Error(),
+		})
+	}
+	// A view is read-only, so MongoDB refuses writing stages in its definition anyway
+	targets, err := pipelineWriteTargets(pipeline, dbName)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid pipeline: " + err.Error(),
+		})
+	}
+	if len(targets) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid pipeline: a view cannot use $out or $merge",
+		})
+	}
+
+	ctx, cancel := operationContext(h.cfg, "createView", 10*time.Second)
+	defer cancel()
+
+	// MongoDB accepts a view on a missing source and shows it as empty, which hides typos
+	sources, err := h.dbClient.ListCollectionTypes(ctx, dbName, "")
+	if err != nil {
+		return serverError(c, err, err.Error())
+	}
+	found := false
+	for _, source := range sources {
+		if source.Name == req.ViewOn {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Source collection " + req.ViewOn + " does not exist in " + dbName,
+		})
+	}
+
+	if isDryRun(c) {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dry_run":  true,
+			"database": dbName,
+			"name":     req.Name,
+			"view_on":  req.ViewOn,
+		})
+	}
+
+	collection, err := h.dbClient.GetCollection(dbName, req.Name)
+	if err != nil {
+		return serverError(c, err, "Failed to get collection: "+err.Error())
+	}
+
+	if err := collection.Database().CreateView(ctx, req.Name, req.ViewOn, pipeline); err != nil {
+		var serverErr mongo.ServerError
+		if errors.As(err, &serverErr) && serverErr.HasErrorCode(namespaceExistsCode) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "A collection or view named " + req.Name + " already exists",
+			})
+		}
+		return serverError(c, err, err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation,
+		"/api/v1/databases/"+url.PathEscape(dbName)+"/collections/"+url.PathEscape(req.Name)+"/documents")
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"database": dbName,
+		"name":     req.Name,
+		"view_on":  req.ViewOn,
+	})
+}
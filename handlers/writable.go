@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+// restrictDocument applies the WRITABLE_FIELDS of a collection to a document about to be
+// inserted. It runs before applyDefaults, so fields the proxy adds itself are not
+// affected. Fields outside the list fail the insert or, in strip mode, are removed.
+func restrictDocument(cfg *config.Config, database, collection string, doc bson.M) error {
+	allowed := cfg.WritableFieldsOf(database, collection)
+	if allowed == nil {
+		return nil
+	}
+	for _, field := range sortedKeys(doc) {
+		if writableField(allowed, field) {
+			continue
+		}
+		if cfg.WritableFieldsMode == config.WritableFieldsStrip {
+			delete(doc, field)
+			continue
+		}
+		return fmt.Errorf("field %s is not writable in %s.%s", field, database, collection)
+	}
+	return nil
+}
+
+// restrictOrdered is restrictDocument for a document that keeps its field order, as
+// read by InsertStream. It is the first step of prepareOrdered.
+func restrictOrdered(cfg *config.Config, database, collection string, doc bson.D) (bson.D, error) {
+	allowed := cfg.WritableFieldsOf(database, collection)
+	if allowed == nil {
+		return doc, nil
+	}
+	result := doc[:0]
+	for _, elem := range doc {
+		if writableField(allowed, elem.Key) {
+			result = append(result, elem)
+			continue
+		}
+		if cfg.WritableFieldsMode != config.WritableFieldsStrip {
+			return nil, fmt.Errorf("field %s is not writable in %s.%s", elem.Key, database, collection)
+		}
+	}
+	return result, nil
+}
+
+// restrictUpdate applies the WRITABLE_FIELDS of a collection to the field paths of every
+// update operator, including the new names of $rename. A path is judged by its top-level
+// field, so "address.city" needs "address". Operators left empty by stripping are
+// dropped, and an update with nothing left to write is an error.
+func restrictUpdate(cfg *config.Config, database, collection string, update bson.M) error {
+	allowed := cfg.WritableFieldsOf(database, collection)
+	if allowed == nil {
+		return nil
+	}
+	for _, operator := range sortedKeys(update) {
+		fields, ok := update[operator].(bson.M)
+		if !ok {
+			continue
+		}
+		for _, path := range sortedKeys(fields) {
+			target, _ := fields[path].(string)
+			if writablePath(allowed, path) && (operator != "$rename" || writablePath(allowed, target)) {
+				continue
+			}
+			if cfg.WritableFieldsMode == config.WritableFieldsStrip {
+				delete(fields, path)
+				continue
+			}
+			if writablePath(allowed, path) {
+				path = target
+			}
+			return fmt.Errorf("field %s is not writable in %s.%s", path, database, collection)
+		}
+		if len(fields) == 0 {
+			delete(update, operator)
+		}
+	}
+	if len(update) == 0 {
+		return errors.New("the update sets no writable fields in " + database + "." + collection)
+	}
+	return nil
+}
+
+// writablePath reports whether the top-level field of a dotted path is writable
+func writablePath(allowed []string, path string) bool {
+	field, _, _ := strings.Cut(path, ".")
+	return writableField(allowed, field)
+}
+
+// writableField reports whether field is _id or listed in allowed
+func writableField(allowed []string, field string) bool {
+	if field == "_id" {
+		return true
+	}
+	for _, name := range allowed {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of doc in order, so the field a rejection names is stable
+func sortedKeys(doc bson.M) []string {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
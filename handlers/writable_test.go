@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"mongodb-go-proxy/config"
+)
+
+// writableConfig allows name and address in mydb.users, in the given WRITABLE_FIELDS_MODE
+func writableConfig(mode string) *config.Config {
+	return &config.Config{
+		WritableFields:     map[string][]string{"mydb.users": {"name", "address"}},
+		WritableFieldsMode: mode,
+	}
+}
+
+func TestRestrictDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		doc     bson.M
+		want    bson.M
+		wantErr string
+	}{
+		{"writable fields pass", config.WritableFieldsReject, bson.M{"_id": 1, "name": "Ann", "address": bson.M{"city": "Paris"}}, bson.M{"_id": 1, "name": "Ann", "address": bson.M{"city": "Paris"}}, ""},
+		{"reject names the field", config.WritableFieldsReject, bson.M{"name": "Ann", "role": "admin"}, nil, "field role is not writable in mydb.users"},
+		{"reject names the first field in order", config.WritableFieldsReject, bson.M{"role": "admin", "plan": "pro"}, nil, "field plan is not writable"},
+		{"strip drops the field", config.WritableFieldsStrip, bson.M{"name": "Ann", "role": "admin"}, bson.M{"name": "Ann"}, ""},
+		{"strip may leave nothing", config.WritableFieldsStrip, bson.M{"role": "admin"}, bson.M{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := restrictDocument(writableConfig(tt.mode), "mydb", "users", tt.doc)
+			checkRestricted(t, err, tt.wantErr, tt.doc, tt.want)
+		})
+	}
+}
+
+func TestRestrictDocumentUnrestrictedCollection(t *testing.T) {
+	doc := bson.M{"role": "admin"}
+	if err := restrictDocument(writableConfig(config.WritableFieldsReject), "mydb", "events", doc); err != nil {
+		t.Fatalf("collection without WRITABLE_FIELDS: %v", err)
+	}
+	if !reflect.DeepEqual(doc, bson.M{"role": "admin"}) {
+		t.Fatalf("doc = %v, want it untouched", doc)
+	}
+}
+
+func TestRestrictOrdered(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		doc     bson.D
+		want    bson.D
+		wantErr string
+	}{
+		{"order is kept", config.WritableFieldsReject, bson.D{{Key: "name", Value: "Ann"}, {Key: "_id", Value: 1}, {Key: "address", Value: "Paris"}}, bson.D{{Key: "name", Value: "Ann"}, {Key: "_id", Value: 1}, {Key: "address", Value: "Paris"}}, ""},
+		{"reject names the field", config.WritableFieldsReject, bson.D{{Key: "name", Value: "Ann"}, {Key: "role", Value: "admin"}}, nil, "field role is not writable in mydb.users"},
+		{"strip keeps the order of the rest", config.WritableFieldsStrip, bson.D{{Key: "address", Value: "Paris"}, {Key: "role", Value: "admin"}, {Key: "name", Value: "Ann"}}, bson.D{{Key: "address", Value: "Paris"}, {Key: "name", Value: "Ann"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := restrictOrdered(writableConfig(tt.mode), "mydb", "users", tt.doc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("doc = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestrictUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		update  bson.M
+		want    bson.M
+		wantErr string
+	}{
+		{"writable paths pass", config.WritableFieldsReject,
+			bson.M{"$set": bson.M{"name": "Ann", "address.city": "Paris"}, "$unset": bson.M{"address.zip": ""}},
+			bson.M{"$set": bson.M{"name": "Ann", "address.city": "Paris"}, "$unset": bson.M{"address.zip": ""}}, ""},
+		{"reject names the field", config.WritableFieldsReject,
+			bson.M{"$set": bson.M{"name": "Ann", "role": "admin"}}, nil, "field role is not writable"},
+		{"reject judges a dotted path by its top-level field", config.WritableFieldsReject,
+			bson.M{"$set": bson.M{"role.level": 2}}, nil, "field role.level is not writable"},
+		{"reject a $rename from a protected field", config.WritableFieldsReject,
+			bson.M{"$rename": bson.M{"role": "name"}}, nil, "field role is not writable"},
+		{"reject a $rename onto a protected field", config.WritableFieldsReject,
+			bson.M{"$rename": bson.M{"name": "role"}}, nil, "field role is not writable"},
+		{"reject a $rename onto a protected dotted path", config.WritableFieldsReject,
+			bson.M{"$rename": bson.M{"address.city": "role.city"}}, nil, "field role.city is not writable"},
+		{"$rename between writable fields passes", config.WritableFieldsReject,
+			bson.M{"$rename": bson.M{"name": "address.name"}}, bson.M{"$rename": bson.M{"name": "address.name"}}, ""},
+		{"strip drops the protected paths", config.WritableFieldsStrip,
+			bson.M{"$set": bson.M{"name": "Ann", "role.level": 2}, "$inc": bson.M{"logins": 1}},
+			bson.M{"$set": bson.M{"name": "Ann"}}, ""},
+		{"strip drops a $rename onto a protected field", config.WritableFieldsStrip,
+			bson.M{"$set": bson.M{"name": "Ann"}, "$rename": bson.M{"address": "role"}},
+			bson.M{"$set": bson.M{"name": "Ann"}}, ""},
+		{"strip leaving nothing to write fails", config.WritableFieldsStrip,
+			bson.M{"$set": bson.M{"role": "admin"}}, nil, "the update sets no writable fields in mydb.users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := restrictUpdate(writableConfig(tt.mode), "mydb", "users", tt.update)
+			checkRestricted(t, err, tt.wantErr, tt.update, tt.want)
+		})
+	}
+}
+
+// checkRestricted compares the outcome of a restrict function that edits got in place
+func checkRestricted(t *testing.T, err error, wantErr string, got, want bson.M) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("err = %v, want %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("result = %v, want %v", got, want)
+	}
+}
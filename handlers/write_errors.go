@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	auth "mongodb-go-proxy/middleware"
+)
+
+// duplicateKeyCode is the MongoDB error code for a unique index violation
+const duplicateKeyCode = 11000
+
+// duplicateKeyPattern extracts the index and key from an E11000 error message such as
+// "E11000 duplicate key error collection: mydb.users index: email_1 dup key: { email: "a@b.c" }"
+var duplicateKeyPattern = regexp.MustCompile(`index: (\S+) dup key: (\{.*\})`)
+
+// WriteError describes one operation of a bulk write that MongoDB rejected
+type WriteError struct {
+	Index   int    `json:"index" example:"3"`                                          // 0-based position of the failed document in the request
+	Code    int    `json:"code" example:"11000"`                                       // MongoDB error code
+	Message string `json:"message" example:"E11000 duplicate key error collection..."` // MongoDB error message
+}
+
+// bulkWriteErrors extracts the per-operation errors of a failed bulk write. ok is false
+// when err is not a mongo.BulkWriteException, i.e. the whole operation failed.
+func bulkWriteErrors(err error) (writeErrors []WriteError, writeConcernError string, ok bool) {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) {
+		return nil, "", false
+	}
+
+	writeErrors = make([]WriteError, 0, len(bwe.WriteErrors))
+	for _, we := range bwe.WriteErrors {
+		writeErrors = append(writeErrors, WriteError{Index: we.Index, Code: we.Code, Message: we.Message})
+	}
+	if bwe.WriteConcernError != nil {
+		writeConcernError = bwe.WriteConcernError.Message
+	}
+	return writeErrors, writeConcernError, true
+}
+
+// firstWriteError returns the error with the lowest index, where an ordered write stopped
+func firstWriteError(writeErrors []WriteError) WriteError {
+	first := writeErrors[0]
+	for _, we := range writeErrors[1:] {
+		if we.Index < first.Index {
+			first = we
+		}
+	}
+	return first
+}
+
+// succeededIndexes returns the positions of the n operations that were applied despite
+// writeErrors. An ordered write stops at its first error, so nothing after it is applied.
+func succeededIndexes(n int, writeErrors []WriteError, ordered bool) []int {
+	failed := make(map[int]bool, len(writeErrors))
+	first := n
+	for _, we := range writeErrors {
+		failed[we.Index] = true
+		if we.Index < first {
+			first = we.Index
+		}
+	}
+	if ordered {
+		n = first
+	}
+
+	indexes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if !failed[i] {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// positionalInsertedIDs lists the insertedIds of a partially failed insertMany: one entry
+// per input document, in input order. ids holds the _id of every document by position;
+// only the positions in succeeded are reported, the others are nil.
+func positionalInsertedIDs(ids []interface{}, succeeded []int) []interface{} {
+	insertedIds := make([]interface{}, len(ids))
+	for _, i := range succeeded {
+		insertedIds[i] = insertedIDValue(ids[i])
+	}
+	return insertedIds
+}
+
+// insertedIDValue renders an inserted _id for a response, an ObjectID as its hex string
+func insertedIDValue(id interface{}) interface{} {
+	if oid, ok := id.(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return id
+}
+
+// unacknowledged reports whether err only says that the write was sent without waiting
+// for the server, as with w=0 in MONGO_URI. Such a write may or may not be applied.
+func unacknowledged(err error) bool {
+	return errors.Is(err, mongo.ErrUnacknowledgedWrite)
+}
+
+// serverError answers a failed operation with 500 and message. err is recorded for the
+// circuit breaker, which only counts MongoDB being unreachable or too slow, never a query
+// the client got wrong.
+func serverError(c echo.Context, err error, message string) error {
+	auth.RecordUpstreamError(c, err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
+
+// writeFailure maps a failed single-document write to a response status and message.
+// A unique index violation is the client's doing and becomes 409 Conflict naming the
+// index and duplicated key; anything else is a 500 carrying the driver's message.
+func writeFailure(err error) (int, string) {
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, writeErr := range we.WriteErrors {
+			if writeErr.Code == duplicateKeyCode {
+				return http.StatusConflict, duplicateKeyMessage(writeErr.Message)
+			}
+		}
+	}
+	return http.StatusInternalServerError, err.Error()
+}
+
+// duplicateKeyMessage rewrites an E11000 message into one naming the index and key
+func duplicateKeyMessage(message string) string {
+	match := duplicateKeyPattern.FindStringSubmatch(message)
+	if match == nil {
+		return "Duplicate key: " + message
+	}
+	return "Duplicate key " + match[2] + " violates unique index " + match[1]
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const duplicateEmail = `E11000 duplicate key error collection: mydb.users index: email_1 dup key: { email: "a@b.c" }`
+
+func TestWriteFailure(t *testing.T) {
+	duplicate := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Index: 0, Code: duplicateKeyCode, Message: duplicateEmail}}}
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string // empty: the driver's message
+	}{
+		{"duplicate key", duplicate, http.StatusConflict, `Duplicate key { email: "a@b.c" } violates unique index email_1`},
+		{"wrapped duplicate key", fmt.Errorf("insert: %w", duplicate), http.StatusConflict, `Duplicate key { email: "a@b.c" } violates unique index email_1`},
+		{"duplicate after another write error",
+			mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121, Message: "Document failed validation"}, {Code: duplicateKeyCode, Message: duplicateEmail}}},
+			http.StatusConflict, `Duplicate key { email: "a@b.c" } violates unique index email_1`},
+		{"unparsable duplicate message",
+			mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: duplicateKeyCode, Message: "E11000 duplicate key error"}}},
+			http.StatusConflict, "Duplicate key: E11000 duplicate key error"},
+		{"other write error", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121, Message: "Document failed validation"}}},
+			http.StatusInternalServerError, ""},
+		{"not a write exception", errors.New("connection reset"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message := writeFailure(tt.err)
+			if tt.wantMessage == "" {
+				tt.wantMessage = tt.err.Error()
+			}
+			if status != tt.wantStatus || message != tt.wantMessage {
+				t.Fatalf("writeFailure = %d %q, want %d %q", status, message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestDuplicateKeyMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{duplicateEmail, `Duplicate key { email: "a@b.c" } violates unique index email_1`},
+		{`E11000 duplicate key error collection: mydb.orders index: tenant_1_number_1 dup key: { tenant: "acme", number: 42 }`,
+			`Duplicate key { tenant: "acme", number: 42 } violates unique index tenant_1_number_1`},
+		{`E11000 duplicate key error collection: mydb.users index: _id_ dup key: { _id: ObjectId('65a1b2c3d4e5f6a7b8c9d0e1') }`,
+			`Duplicate key { _id: ObjectId('65a1b2c3d4e5f6a7b8c9d0e1') } violates unique index _id_`},
+		{"E11000 duplicate key error", "Duplicate key: E11000 duplicate key error"},
+	}
+	for _, tt := range tests {
+		if got := duplicateKeyMessage(tt.message); got != tt.want {
+			t.Errorf("duplicateKeyMessage(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestPositionalInsertedIDs(t *testing.T) {
+	oid := primitive.NewObjectID()
+	// Five documents: the second has a duplicate _id and the fourth fails validation
+	ids := []interface{}{oid, "dup", int32(3), "bad", "e"}
+	writeErrors := []WriteError{
+		{Index: 3, Code: 121, Message: "Document failed validation"},
+		{Index: 1, Code: duplicateKeyCode, Message: duplicateEmail},
+	}
+
+	tests := []struct {
+		name    string
+		ordered bool
+		want    []interface{}
+	}{
+		// An ordered insert stops at the duplicate, so nothing after it was attempted
+		{"ordered", true, []interface{}{oid.Hex(), nil, nil, nil, nil}},
+		// An unordered insert carries on past each failure
+		{"unordered", false, []interface{}{oid.Hex(), nil, int32(3), nil, "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := positionalInsertedIDs(ids, succeededIndexes(len(ids), writeErrors, tt.ordered))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("insertedIds = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSucceededIndexes(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		writeErrors []WriteError
+		ordered     bool
+		want        []int
+	}{
+		{"no errors", 3, nil, true, []int{0, 1, 2}},
+		{"ordered stops at the first error", 5, []WriteError{{Index: 2}}, true, []int{0, 1}},
+		{"ordered with the first document failing", 3, []WriteError{{Index: 0}}, true, []int{}},
+		{"ordered uses the lowest index", 5, []WriteError{{Index: 4}, {Index: 1}}, true, []int{0}},
+		{"unordered skips each failure", 5, []WriteError{{Index: 4}, {Index: 1}}, false, []int{0, 2, 3}},
+		{"unordered with every document failing", 2, []WriteError{{Index: 0}, {Index: 1}}, false, []int{}},
+		{"write concern error only", 2, []WriteError{}, true, []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := succeededIndexes(tt.n, tt.writeErrors, tt.ordered); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("succeededIndexes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
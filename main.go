@@ -1,8 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
@@ -12,6 +23,7 @@ import (
 	"mongodb-go-proxy/database"
 	swagger_docs "mongodb-go-proxy/docs" // swagger docs
 	"mongodb-go-proxy/handlers"
+	"mongodb-go-proxy/metrics"
 	auth "mongodb-go-proxy/middleware"
 )
 
@@ -41,6 +53,11 @@ func main() {
 		log.Fatalf("Configuration error: %v", err)
 	}
 
+	// Log what the environment resolved to, secrets fingerprinted and credentials stripped
+	if effective, err := json.Marshal(handlers.EffectiveConfig(cfg)); err == nil {
+		log.Printf("Effective configuration: %s", effective)
+	}
+
 	swagger_docs.SwaggerInfo.Host = config.GetEnv("SWAGGER_HOST", "localhost:8080") // ex: "api.example.com"
 	log.Println("Swagger Host:", swagger_docs.SwaggerInfo.Host)
 	// Initialize MongoDB client (connection will be established lazily on first use)
@@ -49,95 +66,265 @@ func main() {
 		log.Fatalf("Failed to create MongoDB client: %v", err)
 	}
 
+	// Reads fail over to MONGO_URI_FALLBACK when it is set; it also connects on first use
+	var fallbackClient *database.Client
+	var failover func(c echo.Context) bool
+	if cfg.MongoURIFallback != "" {
+		fallbackClient, err = database.NewClient(cfg.MongoURIFallback)
+		if err != nil {
+			log.Fatalf("Failed to create fallback MongoDB client: %v", err)
+		}
+		// While the primary is down, reads the fallback can serve skip the fast 503
+		failover = func(c echo.Context) bool {
+			return fallbackClient.Healthy() && handlers.FailoverRoute(c)
+		}
+	}
+
+	// Connecting at startup defeats lazy connections on serverless cold starts, so this is opt-in
+	if cfg.ValidateDefaultDatabase {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := dbClient.ValidateDatabase(ctx, cfg.Database)
+		cancel()
+		if err != nil {
+			log.Fatalf("Default database %q (MONGO_DATABASE) is not accessible: %v", cfg.Database, err)
+		}
+		log.Printf("Default database %q is accessible", cfg.Database)
+	}
+
 	// Create Echo instance
 	e := echo.New()
+	e.JSONSerializer = handlers.ExtJSONSerializer{UTCDates: cfg.UTCDates}
+
+	// Pre-routing middleware: normalize the path and method before a route is matched
+	e.Pre(echoMiddleware.RemoveTrailingSlash())
+	if len(cfg.MethodOverrideMethods) > 0 {
+		e.Pre(auth.MethodOverride(cfg.MethodOverrideMethods))
+	}
 
 	// Middleware
+	e.Use(auth.AssignRequestID())
 	e.Use(echoMiddleware.Logger())
 	e.Use(echoMiddleware.Recover())
 
 	// CORS middleware
 	e.Use(echoMiddleware.CORSWithConfig(echoMiddleware.CORSConfig{
-		AllowOrigins: []string{"*"}, // In production, specify exact origins
-		AllowMethods: []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "api-secret", "api-key"},
+		AllowOrigins:  []string{"*"}, // In production, specify exact origins
+		AllowMethods:  []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "api-secret", "api-key", "X-Keep-Alive", "X-Dry-Run", echo.HeaderXHTTPMethodOverride, auth.CompatHeader, auth.DatabaseHeader, handlers.UTCDatesHeader, echo.HeaderXRequestID},
+		ExposeHeaders: append([]string{"Link", echo.HeaderLocation, "X-Inventory-Truncated", echo.HeaderXRequestID, handlers.ServedByHeader, handlers.LatencyWarningHeader}, handlers.UsageHeaders...),
 	}))
 
 	// Initialize handlers
-	mongoHandler := handlers.NewMongoHandler(dbClient)
-	dataAPIHandler := handlers.NewDataAPIHandler(dbClient)
+	mongoHandler := handlers.NewMongoHandler(dbClient, fallbackClient, cfg)
+	dataAPIHandler := handlers.NewDataAPIHandler(dbClient, fallbackClient, cfg)
+
+	breaker := auth.NewCircuitBreaker(cfg.CircuitBreakerThreshold, cfg.CircuitBreakerWindow, cfg.CircuitBreakerCooldown)
+	errorRate := auth.NewErrorRateTracker(cfg.DegradedErrorRate, cfg.ErrorRateWindow, cfg.ErrorRateMinRequests)
+
+	inflight := auth.NewInflightLimiter(cfg.MaxInflight)
+	keyInflight := auth.NewKeyInflightLimiter(map[string]int{
+		config.KeyTierFull:     cfg.MaxInflightFor(config.KeyTierFull),
+		config.KeyTierReadOnly: cfg.MaxInflightFor(config.KeyTierReadOnly),
+	}).Middleware()
+	// Runs once ReadAuth or WriteAuth resolved the api-key: its in-flight cap, then
+	// X-Keep-Alive, which unauthenticated callers must not use to hold the connection open
+	keepAlive := auth.KeepAlive(dbClient)
+	afterAuth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return keyInflight(keepAlive(next))
+	}
+	operations := metrics.NewOperations(cfg.MetricsNamespaces)
+	usage := metrics.NewUsageLedger(auth.KeyTier)
 
 	api := e.Group("/api")
+	api.Use(inflight.Middleware(), operations.Middleware(), usage.Middleware())
 	// Public routes (no auth required)
 	api.GET("/health", healthCheck)
+	api.GET("/health/detailed", detailedHealthCheck(dbClient, breaker, errorRate))
 	database := api.Group("/v1/databases")
+	database.Use(auth.UpstreamHealth(dbClient, failover), breaker.Middleware(), errorRate.Middleware(), auth.DatabaseSelector(cfg.Database, cfg.AllowedDatabases))
+	if cfg.ReadOnlyMode {
+		database.Use(auth.ReadOnlyMode(nil))
+	}
 	// Setup routes with appropriate authentication
-	setupMongoRoutes(database, mongoHandler, cfg.APISecret, cfg.ReadOnlyAPISecret)
+	setupMongoRoutes(database, mongoHandler, cfg.APISecret, cfg.ReadOnlyAPISecret, afterAuth)
+
+	// Document routes without a database segment; the database comes from X-Mongo-Database or MONGO_DATABASE
+	collections := api.Group("/v1/collections")
+	collections.Use(auth.UpstreamHealth(dbClient, failover), breaker.Middleware(), errorRate.Middleware(), auth.DatabaseSelector(cfg.Database, cfg.AllowedDatabases))
+	if cfg.ReadOnlyMode {
+		collections.Use(auth.ReadOnlyMode(nil))
+	}
+	setupDocumentRoutes(collections, mongoHandler, cfg.APISecret, cfg.ReadOnlyAPISecret, afterAuth, "/:collection")
+
+	// Database and collection inventory for catalog views
+	inventory := api.Group("/v1/inventory")
+	inventory.Use(auth.UpstreamHealth(dbClient, nil), breaker.Middleware(), errorRate.Middleware(), auth.ReadAuth(cfg.APISecret, cfg.ReadOnlyAPISecret), afterAuth)
+	inventory.GET("", mongoHandler.Inventory)
+
+	// Status of background imports, which live in this process and need no upstream
+	jobs := api.Group("/v1/jobs")
+	jobs.Use(auth.WriteAuth(cfg.APISecret), keyInflight)
+	jobs.GET("/:id", mongoHandler.ImportJob)
 
 	// MongoDB Data API routes (compatible with mongo-rest-client npm package)
 	dataApi := api.Group("/v1/data-api")
+	dataApi.Use(auth.AtlasCompat(), auth.UpstreamHealth(dbClient, failover), breaker.Middleware(), errorRate.Middleware(), auth.EchoNamespace(cfg.EchoNamespace))
+	if cfg.ReadOnlyMode {
+		// Data API reads are POSTs too, so they are let through by action name
+		var readActions []string
+		for _, action := range dataAPIHandler.Actions() {
+			if !action.Write {
+				readActions = append(readActions, action.Name)
+			}
+		}
+		dataApi.Use(auth.ReadOnlyMode(readActions))
+	}
 	// MongoDB Data API routes (compatible with mongo-rest-client npm package)
-	setupDataAPIRoutes(dataApi, dataAPIHandler, cfg.APISecret, cfg.ReadOnlyAPISecret)
+	setupDataAPIRoutes(dataApi, dataAPIHandler, cfg.APISecret, cfg.ReadOnlyAPISecret, afterAuth)
+
+	// Data API action catalog for client generators (no auth, and no upstream needed)
+	api.GET("/v1/data-api/actions", dataAPIHandler.ListActions)
+
+	// Operation control for incident response, only when an admin secret is configured.
+	// Not behind the circuit breaker, which is likely open while a runaway query is killed.
+	if cfg.AdminAPISecret != "" {
+		admin := api.Group("/v1/admin")
+		admin.Use(auth.AdminAuth(cfg.AdminAPISecret))
+		// The configuration view needs no upstream, so it works while MongoDB is down
+		admin.GET("/config", mongoHandler.Config)
+		admin.GET("/usage", usageReportHandler(cfg, usage))
+		admin.GET("/health", adminHealthCheck(dbClient, breaker, errorRate))
+
+		ops := admin.Group("/current-ops")
+		ops.Use(auth.UpstreamHealth(dbClient, nil))
+		ops.GET("", mongoHandler.CurrentOps)
+		ops.DELETE("/:opid", mongoHandler.KillOp)
+
+		// Query tuning on live collections, capped by the sample size
+		tuning := admin.Group("/databases/:db/collections/:collection")
+		tuning.Use(auth.UpstreamHealth(dbClient, nil))
+		tuning.GET("/index-selectivity", mongoHandler.IndexSelectivity)
+	}
+
+	// Capability descriptor for version-aware clients, read from the routes registered above
+	api.GET("/v1/capabilities", capabilitiesHandler(e, cfg, dataAPIHandler))
+
+	// Metrics in Prometheus text format (no auth, like the health checks)
+	e.GET("/metrics", metricsHandler(inflight, operations))
 
 	// Swagger documentation (no auth for easier access)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	// Start server
 	port := ":" + cfg.ServerPort
-	e.Logger.Fatal(e.Start(port))
+	go func() {
+		if err := e.Start(port); err != nil && err != http.ErrServerClosed {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	// On SIGINT or SIGTERM, stop accepting connections and give in-flight requests, streamed
+	// responses included, and then asynchronous imports SHUTDOWN_TIMEOUT_MS to finish
+	// before the MongoDB clients close
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Printf("Shutting down, draining requests for up to %s", cfg.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		log.Printf("Requests still running at the shutdown timeout were cut off: %v", err)
+	}
+	mongoHandler.StopImports(ctx)
+
+	closeClient("MongoDB", dbClient, cfg.MongoCloseTimeout)
+	if fallbackClient != nil {
+		closeClient("fallback MongoDB", fallbackClient, cfg.MongoCloseTimeout)
+	}
 }
 
-// setupMongoRoutes configures all MongoDB proxy routes with appropriate authentication
-func setupMongoRoutes(api *echo.Group, handler *handlers.MongoHandler, apiSecret, readOnlyAPISecret string) {
+// closeClient disconnects client within timeout, logging a failure
+func closeClient(name string, client *database.Client, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := client.Close(ctx); err != nil {
+		log.Printf("Failed to close %s client: %v", name, err)
+	}
+}
+
+// setupMongoRoutes configures all MongoDB proxy routes with appropriate authentication.
+// afterAuth runs after authentication, once the api-key tier is known.
+func setupMongoRoutes(api *echo.Group, handler *handlers.MongoHandler, apiSecret, readOnlyAPISecret string, afterAuth echo.MiddlewareFunc) {
 	// Read routes - accept both API_SECRET and READONLY_API_SECRET
 	readRoutes := api.Group("")
-	readRoutes.Use(auth.ReadAuth(apiSecret, readOnlyAPISecret))
+	readRoutes.Use(auth.ReadAuth(apiSecret, readOnlyAPISecret), afterAuth)
 	{
 		// Database routes (read)
 		readRoutes.GET("", handler.ListDatabases)
 
 		// Collection routes (read)
 		readRoutes.GET("/:db/collections", handler.ListCollections)
+	}
 
+	// Write routes - only accept API_SECRET
+	writeRoutes := api.Group("")
+	writeRoutes.Use(auth.WriteAuth(apiSecret), afterAuth)
+	{
+		writeRoutes.POST("/:db/views", handler.CreateView)
+	}
+
+	setupDocumentRoutes(api, handler, apiSecret, readOnlyAPISecret, afterAuth, "/:db/collections/:collection")
+}
+
+// setupDocumentRoutes configures the document routes of a collection under prefix
+func setupDocumentRoutes(api *echo.Group, handler *handlers.MongoHandler, apiSecret, readOnlyAPISecret string, afterAuth echo.MiddlewareFunc, prefix string) {
+	// Read routes - accept both API_SECRET and READONLY_API_SECRET
+	readRoutes := api.Group("")
+	readRoutes.Use(auth.ReadAuth(apiSecret, readOnlyAPISecret), afterAuth)
+	{
 		// Document read routes
-		readRoutes.GET("/:db/collections/:collection/documents", handler.FindDocuments)
-		readRoutes.GET("/:db/collections/:collection/documents/:id", handler.GetDocument)
-		readRoutes.GET("/:db/collections/:collection/document", handler.FindOne)
+		readRoutes.GET(prefix+"/documents", handler.FindDocuments)
+		readRoutes.GET(prefix+"/documents/:id", handler.GetDocument)
+		readRoutes.HEAD(prefix+"/documents/:id", handler.DocumentExists)
+		readRoutes.GET(prefix+"/document", handler.FindOne)
+		readRoutes.GET(prefix+"/index-stats", handler.IndexStats)
 	}
 
 	// Write routes - only accept API_SECRET
 	writeRoutes := api.Group("")
-	writeRoutes.Use(auth.WriteAuth(apiSecret))
+	writeRoutes.Use(auth.WriteAuth(apiSecret), afterAuth)
 	{
 		// Document write routes
-		writeRoutes.POST("/:db/collections/:collection/documents", handler.InsertDocument)
-		writeRoutes.PUT("/:db/collections/:collection/documents/:id", handler.UpdateDocument)
-		writeRoutes.DELETE("/:db/collections/:collection/documents/:id", handler.DeleteDocument)
+		writeRoutes.POST(prefix+"/documents", handler.InsertDocument)
+		writeRoutes.POST(prefix+"/documents/stream", handler.InsertStream)
+		writeRoutes.POST(prefix+"/import-async", handler.ImportAsync)
+		writeRoutes.PUT(prefix+"/documents/:id", handler.UpdateDocument)
+		writeRoutes.POST(prefix+"/documents/:id/touch", handler.TouchDocument)
+		writeRoutes.POST(prefix+"/documents/:id/unset", handler.UnsetFields)
+		writeRoutes.DELETE(prefix+"/documents/:id", handler.DeleteDocument)
 	}
 }
 
 // setupDataAPIRoutes configures MongoDB Data API routes (compatible with mongo-rest-client npm package)
-func setupDataAPIRoutes(api *echo.Group, handler *handlers.DataAPIHandler, apiSecret, readOnlyAPISecret string) {
+func setupDataAPIRoutes(api *echo.Group, handler *handlers.DataAPIHandler, apiSecret, readOnlyAPISecret string, afterAuth echo.MiddlewareFunc) {
 	actionRoute := api.Group("/action")
 
 	// Read actions - accept both API_SECRET and READONLY_API_SECRET
 	readRoutes := actionRoute.Group("")
-	readRoutes.Use(auth.ReadAuth(apiSecret, readOnlyAPISecret))
-	{
-		readRoutes.POST("/findOne", handler.FindOne)
-		readRoutes.POST("/find", handler.Find)
-	}
+	readRoutes.Use(auth.ReadAuth(apiSecret, readOnlyAPISecret), afterAuth)
 
 	// Write actions - only accept API_SECRET
 	writeRoutes := actionRoute.Group("")
-	writeRoutes.Use(auth.WriteAuth(apiSecret))
-	{
-		writeRoutes.POST("/insertOne", handler.InsertOne)
-		writeRoutes.POST("/insertMany", handler.InsertMany)
-		writeRoutes.POST("/updateOne", handler.UpdateOne)
-		writeRoutes.POST("/updateMany", handler.UpdateMany)
-		writeRoutes.POST("/deleteOne", handler.DeleteOne)
-		writeRoutes.POST("/deleteMany", handler.DeleteMany)
+	writeRoutes.Use(auth.WriteAuth(apiSecret), afterAuth)
+
+	// Routes come from the same registry as the action catalog
+	for _, action := range handler.Actions() {
+		if action.Write {
+			writeRoutes.POST("/"+action.Name, action.Handler)
+		} else {
+			readRoutes.POST("/"+action.Name, action.Handler)
+		}
 	}
 }
 
@@ -156,3 +343,207 @@ func healthCheck(c echo.Context) error {
 		"message": "API is running",
 	})
 }
+
+// detailedHealthCheck godoc
+//
+//	@Summary		Detailed health check endpoint
+//	@Description	Returns ok, or 503 and degraded while MongoDB is unreachable, the circuit breaker is open or the error rate is too high. Which of them it is, is reported by /v1/admin/health.
+//	@Tags			health
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	map[string]string
+//	@Failure		503	{object}	map[string]string
+//	@Router			/health/detailed [get]
+func detailedHealthCheck(dbClient *database.Client, breaker *auth.CircuitBreaker, errorRate *auth.ErrorRateTracker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, report := healthReport(dbClient, breaker, errorRate)
+		return c.JSON(status, map[string]interface{}{
+			"status": report["status"],
+		})
+	}
+}
+
+// adminHealthCheck godoc
+//
+//	@Summary		Upstream state behind the detailed health check
+//	@Description	Returns the status of /health/detailed together with the MongoDB connection, the circuit breaker state and the recent error rate. Requires ADMIN_API_SECRET.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		401	{object}	map[string]string	"Unauthorized - missing or invalid api-key"
+//	@Failure		403	{object}	map[string]string	"Forbidden - not the admin api-key"
+//	@Failure		503	{object}	map[string]interface{}
+//	@Router			/v1/admin/health [get]
+func adminHealthCheck(dbClient *database.Client, breaker *auth.CircuitBreaker, errorRate *auth.ErrorRateTracker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(healthReport(dbClient, breaker, errorRate))
+	}
+}
+
+// healthReport returns the status code and body of the admin health check. The breaker
+// state and error rate are kept out of the unauthenticated endpoint, since they tell
+// anyone how close the proxy is to refusing traffic.
+func healthReport(dbClient *database.Client, breaker *auth.CircuitBreaker, errorRate *auth.ErrorRateTracker) (int, map[string]interface{}) {
+	healthy := dbClient.Healthy()
+	breakerState := breaker.State()
+	rate := errorRate.Snapshot()
+
+	status := http.StatusOK
+	statusText := "ok"
+	if !healthy || breakerState == auth.BreakerOpen || rate.Degraded {
+		status = http.StatusServiceUnavailable
+		statusText = "degraded"
+	}
+
+	return status, map[string]interface{}{
+		"status": statusText,
+		"mongo": map[string]interface{}{
+			"healthy":   healthy,
+			"connected": dbClient.IsConnected(),
+		},
+		"circuit_breaker": breakerState,
+		"error_rate": map[string]interface{}{
+			"requests": rate.Requests,
+			"failures": rate.Failures,
+			"percent":  math.Round(rate.Percent*10) / 10,
+			"degraded": rate.Degraded,
+		},
+	}
+}
+
+// capabilitiesHandler godoc
+//
+//	@Summary		Describe what this proxy supports
+//	@Description	Returns the enabled endpoints, taken from the registered routes, the Data API actions, response formats, authentication, limits and which optional features the configuration enables. Admin routes are not listed. Needs no api-key and no upstream.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}	"Capability descriptor"
+//	@Router			/v1/capabilities [get]
+func capabilitiesHandler(e *echo.Echo, cfg *config.Config, dataAPI *handlers.DataAPIHandler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		type endpoint struct {
+			Method string `json:"method"`
+			Path   string `json:"path"`
+		}
+		seen := make(map[endpoint]bool)
+		endpoints := []endpoint{}
+		for _, route := range e.Routes() {
+			ep := endpoint{Method: route.Method, Path: route.Path}
+			if !strings.HasPrefix(ep.Path, "/api/") || strings.HasPrefix(ep.Path, "/api/v1/admin") || seen[ep] {
+				continue
+			}
+			seen[ep] = true
+			endpoints = append(endpoints, ep)
+		}
+		sort.Slice(endpoints, func(i, j int) bool {
+			if endpoints[i].Path != endpoints[j].Path {
+				return endpoints[i].Path < endpoints[j].Path
+			}
+			return endpoints[i].Method < endpoints[j].Method
+		})
+
+		// Write actions are registered in read-only mode too, but answer 403
+		actions := []map[string]interface{}{}
+		for _, action := range dataAPI.Actions() {
+			access := "read"
+			if action.Write {
+				access = "write"
+			}
+			actions = append(actions, map[string]interface{}{
+				"name":    action.Name,
+				"access":  access,
+				"enabled": !action.Write || !cfg.ReadOnlyMode,
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"api_version":      "v1",
+			"endpoints":        endpoints,
+			"data_api_actions": actions,
+			"formats": map[string]interface{}{
+				// Extended JSON (relaxed) is the only body format; there is no Accept negotiation
+				"responses":     []string{echo.MIMEApplicationJSON},
+				"stream_input":  []string{"application/x-ndjson"},
+				"stream_output": []string{"application/x-ndjson"},
+			},
+			"auth": map[string]interface{}{
+				"header":        "api-key",
+				"read_only_key": cfg.ReadOnlyAPISecret != "",
+				"admin_routes":  cfg.AdminAPISecret != "",
+			},
+			"limits": map[string]interface{}{
+				"max_aggregate_results": cfg.MaxAggregateResults,
+				"max_distinct_values":   cfg.MaxDistinctValues,
+				"max_return_ids":        cfg.MaxReturnIDs,
+				"max_filter_ops":        cfg.MaxFilterOps,
+				"max_filter_depth":      cfg.MaxFilterDepth,
+				"max_import_jobs":       cfg.MaxImportJobs,
+				"per_key_limits":        len(cfg.KeyLimits) > 0,
+			},
+			"features": map[string]interface{}{
+				"aggregate":           true,
+				"aggregate_writes":    len(cfg.AggregateWriteAllowlist) > 0 && !cfg.ReadOnlyMode,
+				"transactions":        true,
+				"change_streams":      false,
+				"async_import":        true,
+				"dry_run":             true,
+				"read_only_mode":      cfg.ReadOnlyMode,
+				"fallback_reads":      cfg.MongoURIFallback != "",
+				"delete_confirmation": cfg.DeleteConfirmation,
+				"strict_collections":  cfg.StrictCollections,
+				"method_override":     cfg.MethodOverrideMethods,
+				"usage_accounting":    cfg.UsageAccounting,
+			},
+		})
+	}
+}
+
+// usageReportHandler godoc
+//
+//	@Summary		Report resource usage per api-key
+//	@Description	Returns the MongoDB work accumulated per api-key tier since the process started, as recorded by USAGE_ACCOUNTING: requests, documents and bytes returned and, in explain mode, documents and index keys examined and estimated bytes scanned. Requires ADMIN_API_SECRET.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]interface{}	"Usage per api-key tier"
+//	@Failure		401	{object}	map[string]string		"Unauthorized - missing or invalid api-key"
+//	@Failure		403	{object}	map[string]string		"Forbidden - not the admin api-key"
+//	@Router			/v1/admin/usage [get]
+func usageReportHandler(cfg *config.Config, usage *metrics.UsageLedger) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		since, keys := usage.Report()
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"mode":  cfg.UsageAccounting,
+			"since": since,
+			"keys":  keys,
+		})
+	}
+}
+
+// metricsHandler godoc
+//
+//	@Summary		Process metrics
+//	@Description	Returns in-flight request metrics and per-namespace operation metrics in Prometheus text exposition format
+//	@Tags			health
+//	@Produce		plain
+//	@Success		200	{string}	string
+//	@Router			/metrics [get]
+func metricsHandler(inflight *auth.InflightLimiter, operations *metrics.Operations) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var body bytes.Buffer
+		fmt.Fprintf(&body, `# HELP mongodb_proxy_inflight_requests HTTP requests currently being served.
+# TYPE mongodb_proxy_inflight_requests gauge
+mongodb_proxy_inflight_requests %d
+# HELP mongodb_proxy_inflight_max Configured MAX_INFLIGHT ceiling (0 means unlimited).
+# TYPE mongodb_proxy_inflight_max gauge
+mongodb_proxy_inflight_max %d
+# HELP mongodb_proxy_inflight_rejected_total Requests rejected because MAX_INFLIGHT was reached.
+# TYPE mongodb_proxy_inflight_rejected_total counter
+mongodb_proxy_inflight_rejected_total %d
+`, inflight.InFlight(), inflight.Max(), inflight.Rejected())
+		operations.WritePrometheus(&body)
+
+		return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", body.Bytes())
+	}
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// namespaceKey is the echo context key Track stores the operation target under
+const namespaceKey = "metrics.namespace"
+
+// otherNamespace labels operations on namespaces that are not tracked individually
+const otherNamespace = "_other"
+
+// Operation identifies the target of a request for per-namespace metrics
+type Operation struct {
+	Action     string
+	Database   string
+	Collection string
+}
+
+// series holds the counters for one Operation
+type series struct {
+	requests uint64
+	errors   uint64
+	seconds  float64
+}
+
+// Track records the action and namespace a handler resolved for the current request.
+// Requests that never call Track (health checks, database listings) are not recorded.
+func Track(c echo.Context, action, database, collection string) {
+	c.Set(namespaceKey, Operation{Action: action, Database: database, Collection: collection})
+}
+
+// Tracked returns the Operation a handler recorded with Track, if any
+func Tracked(c echo.Context) (Operation, bool) {
+	op, ok := c.Get(namespaceKey).(Operation)
+	return op, ok
+}
+
+// Operations collects request counts and latency per action, database and collection.
+// When namespaces are configured, only those are tracked by name and everything else is
+// folded into database="_other", collection="_other" to bound label cardinality.
+type Operations struct {
+	namespaces map[string]bool
+	databases  map[string]bool
+
+	mu     sync.Mutex
+	series map[Operation]*series
+}
+
+// NewOperations creates the collector. Each namespace is "db.collection" or "db.*";
+// an empty list tracks every namespace.
+func NewOperations(namespaces []string) *Operations {
+	o := &Operations{
+		namespaces: make(map[string]bool),
+		databases:  make(map[string]bool),
+		series:     make(map[Operation]*series),
+	}
+	for _, ns := range namespaces {
+		if db, ok := strings.CutSuffix(ns, ".*"); ok {
+			o.databases[db] = true
+		} else {
+			o.namespaces[ns] = true
+		}
+	}
+	return o
+}
+
+// Middleware times each request and records it under the Operation set by Track
+func (o *Operations) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			op, ok := c.Get(namespaceKey).(Operation)
+			if !ok {
+				return err
+			}
+
+			status := c.Response().Status
+			if he, isHTTPError := err.(*echo.HTTPError); isHTTPError {
+				status = he.Code
+			} else if err != nil {
+				status = http.StatusInternalServerError
+			}
+			o.observe(op, time.Since(start), status >= http.StatusInternalServerError)
+			return err
+		}
+	}
+}
+
+// tracked reports whether a namespace gets its own series
+func (o *Operations) tracked(database, collection string) bool {
+	if len(o.namespaces) == 0 && len(o.databases) == 0 {
+		return true
+	}
+	return o.databases[database] || o.namespaces[database+"."+collection]
+}
+
+// observe adds one request to the series of op
+func (o *Operations) observe(op Operation, elapsed time.Duration, failed bool) {
+	if !o.tracked(op.Database, op.Collection) {
+		op.Database, op.Collection = otherNamespace, otherNamespace
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s, ok := o.series[op]
+	if !ok {
+		s = &series{}
+		o.series[op] = s
+	}
+	s.requests++
+	if failed {
+		s.errors++
+	}
+	s.seconds += elapsed.Seconds()
+}
+
+// WritePrometheus writes the collected metrics in Prometheus text exposition format
+func (o *Operations) WritePrometheus(w io.Writer) {
+	o.mu.Lock()
+	ops := make([]Operation, 0, len(o.series))
+	snapshot := make(map[Operation]series, len(o.series))
+	for op, s := range o.series {
+		ops = append(ops, op)
+		snapshot[op] = *s
+	}
+	o.mu.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Database != ops[j].Database {
+			return ops[i].Database < ops[j].Database
+		}
+		if ops[i].Collection != ops[j].Collection {
+			return ops[i].Collection < ops[j].Collection
+		}
+		return ops[i].Action < ops[j].Action
+	})
+
+	fmt.Fprintln(w, "# HELP mongodb_proxy_operations_total Requests per action, database and collection.")
+	fmt.Fprintln(w, "# TYPE mongodb_proxy_operations_total counter")
+	for _, op := range ops {
+		fmt.Fprintf(w, "mongodb_proxy_operations_total%s %d\n", labels(op), snapshot[op].requests)
+	}
+	fmt.Fprintln(w, "# HELP mongodb_proxy_operation_errors_total Requests that ended with a 5xx response.")
+	fmt.Fprintln(w, "# TYPE mongodb_proxy_operation_errors_total counter")
+	for _, op := range ops {
+		fmt.Fprintf(w, "mongodb_proxy_operation_errors_total%s %d\n", labels(op), snapshot[op].errors)
+	}
+	fmt.Fprintln(w, "# HELP mongodb_proxy_operation_duration_seconds Request latency.")
+	fmt.Fprintln(w, "# TYPE mongodb_proxy_operation_duration_seconds summary")
+	for _, op := range ops {
+		fmt.Fprintf(w, "mongodb_proxy_operation_duration_seconds_sum%s %g\n", labels(op), snapshot[op].seconds)
+		fmt.Fprintf(w, "mongodb_proxy_operation_duration_seconds_count%s %d\n", labels(op), snapshot[op].requests)
+	}
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels renders the label set of an operation
+func labels(op Operation) string {
+	return fmt.Sprintf(`{database="%s",collection="%s",action="%s"}`,
+		labelEscaper.Replace(op.Database), labelEscaper.Replace(op.Collection), labelEscaper.Replace(op.Action))
+}
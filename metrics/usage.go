@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// usageKey is the echo context key RecordUsage accumulates the request's Usage under
+const usageKey = "metrics.usage"
+
+// Usage is the MongoDB work attributed to a request. The examined figures are only
+// known when the read was explained, and are zero otherwise.
+type Usage struct {
+	DocumentsReturned int64 `json:"documents_returned"` // Documents sent to the client
+	BytesReturned     int64 `json:"bytes_returned"`     // BSON size of the documents sent
+	DocumentsExamined int64 `json:"documents_examined"` // Documents the server read to answer
+	KeysExamined      int64 `json:"keys_examined"`      // Index keys the server read to answer
+	BytesScanned      int64 `json:"bytes_scanned"`      // Estimated bytes behind documents_examined
+}
+
+// add sums other into u
+func (u *Usage) add(other Usage) {
+	u.DocumentsReturned += other.DocumentsReturned
+	u.BytesReturned += other.BytesReturned
+	u.DocumentsExamined += other.DocumentsExamined
+	u.KeysExamined += other.KeysExamined
+	u.BytesScanned += other.BytesScanned
+}
+
+// RecordUsage adds u to the usage of the current request, for the UsageLedger to
+// attribute to its api-key once the handler returns
+func RecordUsage(c echo.Context, u Usage) {
+	total, _ := c.Get(usageKey).(Usage)
+	total.add(u)
+	c.Set(usageKey, total)
+}
+
+// KeyUsage is the usage accumulated for one api-key tier
+type KeyUsage struct {
+	Requests int64 `json:"requests"` // Requests that recorded usage
+	Usage
+}
+
+// UsageLedger accumulates the Usage of requests per api-key tier since the process
+// started. tier resolves the tier of a request after its handler ran.
+type UsageLedger struct {
+	tier  func(echo.Context) string
+	since time.Time
+
+	mu   sync.Mutex
+	keys map[string]*KeyUsage
+}
+
+// NewUsageLedger creates an empty ledger
+func NewUsageLedger(tier func(echo.Context) string) *UsageLedger {
+	return &UsageLedger{
+		tier:  tier,
+		since: time.Now().UTC(),
+		keys:  make(map[string]*KeyUsage),
+	}
+}
+
+// Middleware adds the usage a handler recorded with RecordUsage to the ledger. Requests
+// without recorded usage or without an api-key are not counted.
+func (l *UsageLedger) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			usage, ok := c.Get(usageKey).(Usage)
+			tier := l.tier(c)
+			if !ok || tier == "" {
+				return err
+			}
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			k, ok := l.keys[tier]
+			if !ok {
+				k = &KeyUsage{}
+				l.keys[tier] = k
+			}
+			k.Requests++
+			k.add(usage)
+			return err
+		}
+	}
+}
+
+// Report returns when accounting started and a copy of the usage per api-key tier
+func (l *UsageLedger) Report() (time.Time, map[string]KeyUsage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := make(map[string]KeyUsage, len(l.keys))
+	for tier, k := range l.keys {
+		keys[tier] = *k
+	}
+	return l.since, keys
+}
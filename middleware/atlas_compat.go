@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CompatHeader selects a response compatibility mode. The only supported value is "atlas".
+const CompatHeader = "X-Compat"
+
+// atlasCreatedActions are the Data API actions Atlas answers with 201 Created
+var atlasCreatedActions = []string{"/action/insertOne", "/action/insertMany"}
+
+// atlasErrorCodes maps HTTP status codes to Atlas Data API error_code values
+var atlasErrorCodes = map[int]string{
+	http.StatusBadRequest:          "InvalidParameter",
+	http.StatusUnauthorized:        "InvalidSession",
+	http.StatusForbidden:           "Forbidden",
+	http.StatusNotFound:            "NoMatchingRoute",
+	http.StatusConflict:            "DuplicateKey",
+	http.StatusTooManyRequests:     "TooManyRequests",
+	http.StatusServiceUnavailable:  "ServiceUnavailable",
+	http.StatusInternalServerError: "InternalServerError",
+}
+
+// atlasError is the error envelope returned by the Atlas Data API
+type atlasError struct {
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code"`
+	Link      string `json:"link"`
+}
+
+// bufferedWriter holds a response back so it can be rewritten before reaching the client
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header         { return w.header }
+func (w *bufferedWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *bufferedWriter) WriteHeader(status int)      { w.status = status }
+
+// AtlasCompat reshapes responses to match the Atlas Data API when the request carries
+// "X-Compat: atlas": errors use the {"error","error_code","link"} envelope and inserts
+// answer 201 instead of 200. Requests without the header pass through untouched.
+func AtlasCompat() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !strings.EqualFold(c.Request().Header.Get(CompatHeader), "atlas") {
+				return next(c)
+			}
+
+			res := c.Response()
+			original := res.Writer
+			buffered := &bufferedWriter{header: original.Header(), status: http.StatusOK}
+			res.Writer = buffered
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			res.Writer = original
+
+			status := buffered.status
+			body := buffered.body.Bytes()
+
+			if status >= http.StatusBadRequest {
+				body = atlasErrorBody(status, body)
+				original.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+			} else if status == http.StatusOK && isAtlasCreatedAction(c.Request().URL.Path) {
+				status = http.StatusCreated
+			}
+
+			original.Header().Set(echo.HeaderContentLength, strconv.Itoa(len(body)))
+			original.WriteHeader(status)
+			res.Status = status
+			res.Size = int64(len(body))
+			_, err := original.Write(body)
+			return err
+		}
+	}
+}
+
+// atlasErrorBody converts a {"error": "..."} response body into the Atlas error envelope
+func atlasErrorBody(status int, body []byte) []byte {
+	var parsed map[string]interface{}
+	message := http.StatusText(status)
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if msg, ok := parsed["error"].(string); ok && msg != "" {
+			message = msg
+		} else if msg, ok := parsed["message"].(string); ok && msg != "" {
+			// echo.HTTPError responses use "message"
+			message = msg
+		}
+	}
+
+	code, ok := atlasErrorCodes[status]
+	if !ok {
+		code = atlasErrorCodes[http.StatusInternalServerError]
+	}
+
+	out, _ := json.Marshal(atlasError{Error: message, ErrorCode: code})
+	return append(out, '\n')
+}
+
+// isAtlasCreatedAction reports whether the path is an insert action
+func isAtlasCreatedAction(path string) bool {
+	for _, suffix := range atlasCreatedActions {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,8 +4,20 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+
+	"mongodb-go-proxy/config"
 )
 
+// keyTierKey is the echo context key ReadAuth and WriteAuth store the api-key tier under
+const keyTierKey = "auth.keyTier"
+
+// KeyTier returns the tier of the api-key that authenticated the request
+// (config.KeyTierFull or config.KeyTierReadOnly), or "" on unauthenticated routes
+func KeyTier(c echo.Context) string {
+	tier, _ := c.Get(keyTierKey).(string)
+	return tier
+}
+
 // getAPISecret extracts the API secret from request headers
 func getAPISecret(c echo.Context) string {
 	// Get api-key from header
@@ -52,11 +64,13 @@ func ReadAuth(apiSecret, readOnlyAPISecret string) echo.MiddlewareFunc {
 
 			// Accept API_SECRET for read operations
 			if providedSecret == apiSecret {
+				c.Set(keyTierKey, config.KeyTierFull)
 				return next(c)
 			}
 
 			// Also accept READONLY_API_SECRET if it's configured
 			if readOnlyAPISecret != "" && providedSecret == readOnlyAPISecret {
+				c.Set(keyTierKey, config.KeyTierReadOnly)
 				return next(c)
 			}
 
@@ -88,6 +102,31 @@ func WriteAuth(apiSecret string) echo.MiddlewareFunc {
 				})
 			}
 
+			c.Set(keyTierKey, config.KeyTierFull)
+			return next(c)
+		}
+	}
+}
+
+// AdminAuth validates the api-secret header for admin operations
+// Only accepts ADMIN_API_SECRET
+func AdminAuth(adminAPISecret string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			providedSecret := getAPISecret(c)
+
+			if providedSecret == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "api-key header is required",
+				})
+			}
+
+			if providedSecret != adminAPISecret {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Invalid api-key. Admin operations require ADMIN_API_SECRET.",
+				})
+			}
+
 			return next(c)
 		}
 	}
@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// Circuit breaker states
+const (
+	BreakerDisabled = "disabled"
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// CircuitBreaker stops forwarding requests to MongoDB after repeated failures.
+// After Threshold consecutive upstream failures within Window (network errors,
+// timeouts and failed server selection, see RecordUpstreamError) the breaker opens and
+// rejects requests with 503 for Cooldown, then lets a single trial request through
+// (half-open). A successful trial closes the breaker; a failed one reopens it, and one
+// answered with a 4xx leaves it half-open for the next request.
+type CircuitBreaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	state       string
+	failures    int
+	streakStart time.Time
+	openedAt    time.Time
+	trialActive bool
+}
+
+// NewCircuitBreaker creates a circuit breaker. A threshold of 0 disables it.
+func NewCircuitBreaker(threshold int, window, cooldown time.Duration) *CircuitBreaker {
+	state := BreakerClosed
+	if threshold <= 0 {
+		state = BreakerDisabled
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		state:     state,
+	}
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a request may proceed and whether it is the half-open trial
+func (b *CircuitBreaker) allow() (allowed, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false, false
+		}
+		b.state = BreakerHalfOpen
+		b.trialActive = true
+		return true, true
+	case BreakerHalfOpen:
+		// Only one trial request at a time while half-open
+		if b.trialActive {
+			return false, false
+		}
+		b.trialActive = true
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// record updates the breaker with the outcome of a request
+func (b *CircuitBreaker) record(failed, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trialActive = false
+		if failed {
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		} else {
+			b.state = BreakerClosed
+			b.failures = 0
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	now := time.Now()
+	if b.failures == 0 || now.Sub(b.streakStart) > b.window {
+		b.failures = 0
+		b.streakStart = now
+	}
+	b.failures++
+
+	if b.state == BreakerClosed && b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+		b.failures = 0
+	}
+}
+
+// releaseTrial ends a half-open trial without judging it, leaving the breaker half-open
+// for the next request to try
+func (b *CircuitBreaker) releaseTrial() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialActive = false
+}
+
+// Middleware returns the echo middleware enforcing the breaker. It runs before the
+// route's auth, so a trial answered with a 4xx, such as a missing api-key, may never
+// have reached MongoDB and is not taken as a success.
+func (b *CircuitBreaker) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if b.threshold <= 0 {
+				return next(c)
+			}
+
+			allowed, trial := b.allow()
+			if !allowed {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(b.cooldown.Seconds())))
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{
+					"error": "Circuit breaker open: MongoDB operations are temporarily suspended",
+				})
+			}
+
+			// Recorded in a defer, as Recover runs further out: a trial that panics must
+			// still free the trial slot, and counts as a failure
+			failed, rejected := true, false
+			defer func() {
+				if trial && rejected {
+					b.releaseTrial()
+					return
+				}
+				b.record(failed, trial)
+			}()
+
+			err := next(c)
+			failed = isUpstreamFailure(c, err)
+			rejected = !failed && isClientError(c, err)
+			return err
+		}
+	}
+}
+
+// upstreamFailureKey marks a request whose MongoDB call failed in a way the breaker counts
+const upstreamFailureKey = "breaker.upstreamFailure"
+
+// RecordUpstreamError notes err, the error behind a failed response, on the request.
+// Only errors showing that MongoDB could not be reached or did not answer in time count
+// as breaker failures: bad operators, invalid pipelines and other query errors are the
+// client's doing, and any api-key could send them to open the breaker for everyone.
+func RecordUpstreamError(c echo.Context, err error) {
+	if IsUpstreamError(err) {
+		c.Set(upstreamFailureKey, true)
+	}
+}
+
+// IsUpstreamError reports whether err is a network error, a timeout or a failed server
+// selection. A context cancelled because the client went away is none of these.
+func IsUpstreamError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var selection topology.ServerSelectionError
+	return mongo.IsNetworkError(err) || mongo.IsTimeout(err) ||
+		errors.As(err, &selection) || errors.Is(err, topology.ErrServerSelectionTimeout) ||
+		errors.Is(err, mongo.ErrClientDisconnected)
+}
+
+// isUpstreamFailure reports whether a handler outcome counts as a breaker failure: an
+// upstream error recorded by the handler or returned to echo
+func isUpstreamFailure(c echo.Context, err error) bool {
+	recorded, _ := c.Get(upstreamFailureKey).(bool)
+	return recorded || IsUpstreamError(err)
+}
+
+// isClientError reports whether a handler outcome is a 4xx response
+func isClientError(c echo.Context, err error) bool {
+	status := c.Response().Status
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+	}
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+const trialCooldown = 50 * time.Millisecond
+
+// openBreaker returns a breaker that one failure opened and whose cooldown has passed, so
+// the next request is the half-open trial
+func openBreaker(t *testing.T) *CircuitBreaker {
+	t.Helper()
+	b := NewCircuitBreaker(1, time.Minute, trialCooldown)
+	b.record(true, false)
+	time.Sleep(trialCooldown + 10*time.Millisecond)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("state = %s, want %s", state, BreakerHalfOpen)
+	}
+	return b
+}
+
+// serveBreaker runs handler behind the breaker and Recover, as main.go orders them
+func serveBreaker(b *CircuitBreaker, handler echo.HandlerFunc) int {
+	e := echo.New()
+	e.GET("/", handler, b.Middleware())
+	e.Use(recoverer)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec.Code
+}
+
+// unreachable is the error the driver returns when no server can be selected
+var unreachable = topology.ServerSelectionError{Wrapped: errors.New("connection refused")}
+
+// failingWith returns a handler answering 500 for err, the way handlers report a failed operation
+func failingWith(err error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		RecordUpstreamError(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
+
+// recoverer turns a panic into a 500, like echo's Recover middleware
+func recoverer(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = c.NoContent(http.StatusInternalServerError)
+			}
+		}()
+		return next(c)
+	}
+}
+
+func TestCircuitBreakerTrialPanicReopens(t *testing.T) {
+	b := openBreaker(t)
+
+	serveBreaker(b, func(c echo.Context) error { panic("trial failed") })
+
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("state after a panicking trial = %s, want %s", state, BreakerOpen)
+	}
+	time.Sleep(trialCooldown + 10*time.Millisecond)
+	if code := serveBreaker(b, func(c echo.Context) error { return c.NoContent(http.StatusOK) }); code != http.StatusOK {
+		t.Fatalf("next trial got %d, want it let through", code)
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("state after a successful trial = %s, want %s", state, BreakerClosed)
+	}
+}
+
+func TestCircuitBreakerTrialClientErrorIsNotJudged(t *testing.T) {
+	b := openBreaker(t)
+
+	unauthorized := func(c echo.Context) error {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "api-key header is required"})
+	}
+	if code := serveBreaker(b, unauthorized); code != http.StatusUnauthorized {
+		t.Fatalf("trial got %d, want %d", code, http.StatusUnauthorized)
+	}
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("state after a 401 trial = %s, want %s", state, BreakerHalfOpen)
+	}
+
+	if code := serveBreaker(b, failingWith(unreachable)); code != http.StatusInternalServerError {
+		t.Fatalf("second trial got %d, want it let through", code)
+	}
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("state after a failed trial = %s, want %s", state, BreakerOpen)
+	}
+}
+
+func TestIsUpstreamError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"server selection", unreachable, true},
+		{"wrapped server selection", fmt.Errorf("find: %w", unreachable), true},
+		{"network error label", mongo.CommandError{Code: 6, Name: "HostUnreachable", Labels: []string{"NetworkError"}}, true},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"maxTimeMS expired", mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}, true},
+		{"client disconnected", mongo.ErrClientDisconnected, true},
+		{"caller went away", context.Canceled, false},
+		{"unknown operator", mongo.CommandError{Code: 2, Name: "BadValue", Message: "unknown operator: $regx"}, false},
+		{"invalid pipeline", mongo.CommandError{Code: 40324, Name: "Location40324", Message: "Unrecognized pipeline stage name: '$lookp'"}, false},
+		{"other error", errors.New("invalid ObjectID"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUpstreamError(tt.err); got != tt.want {
+				t.Fatalf("IsUpstreamError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresQueryErrors(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Minute, time.Minute)
+
+	badQuery := mongo.CommandError{Code: 2, Name: "BadValue", Message: "unknown operator: $regx"}
+	for i := 0; i < 5; i++ {
+		if code := serveBreaker(b, failingWith(badQuery)); code != http.StatusInternalServerError {
+			t.Fatalf("request %d got %d, want the handler's 500", i, code)
+		}
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("state after query errors = %s, want %s", state, BreakerClosed)
+	}
+
+	serveBreaker(b, failingWith(unreachable))
+	serveBreaker(b, func(c echo.Context) error { return fmt.Errorf("count: %w", context.DeadlineExceeded) })
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("state after two upstream failures = %s, want %s", state, BreakerOpen)
+	}
+}
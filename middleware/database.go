@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DatabaseHeader selects the target database of a REST request, overriding the :db path parameter
+const DatabaseHeader = "X-Mongo-Database"
+
+// maxDatabaseNameLength is the longest database name MongoDB accepts, which must be
+// shorter than 64 bytes
+const maxDatabaseNameLength = 63
+
+// maxNamespaceLength is MongoDB's limit on "database.collection"
+const maxNamespaceLength = 255
+
+// invalidDatabaseChars may not appear in a database name
+const invalidDatabaseChars = "/\\. \"$*<>:|?\x00"
+
+// DatabaseSelector resolves the database a REST request targets and stores it as the :db
+// path parameter for the handlers. Precedence is the X-Mongo-Database header, then the
+// :db path parameter, then defaultDB (MONGO_DATABASE). When allowed is non-empty, only
+// those databases may be selected; anything else is rejected with 403. A database or
+// :collection name MongoDB would reject, or that smuggles in an operator, is a 400.
+func DatabaseSelector(defaultDB string, allowed []string) echo.MiddlewareFunc {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			name := c.Request().Header.Get(DatabaseHeader)
+			if name == "" {
+				name = c.Param("db")
+			}
+			if name == "" {
+				name = defaultDB
+			}
+			if name == "" {
+				// Routes that don't address a database, such as the database listing
+				return next(c)
+			}
+
+			if err := ValidateDatabaseName(name); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": err.Error(),
+				})
+			}
+			if len(allowedSet) > 0 && !allowedSet[name] {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Database " + name + " is not allowed",
+				})
+			}
+
+			if collection := c.Param("collection"); collection != "" {
+				if err := ValidateCollectionName(name, collection); err != nil {
+					return c.JSON(http.StatusBadRequest, map[string]string{
+						"error": err.Error(),
+					})
+				}
+			}
+
+			setParam(c, "db", name)
+			return next(c)
+		}
+	}
+}
+
+// ValidateDatabaseName applies MongoDB's database naming rules. They also keep out
+// operator-like names such as $external, which address special authentication sources.
+func ValidateDatabaseName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("database name is required")
+	case len(name) > maxDatabaseNameLength:
+		return fmt.Errorf("invalid database name %q: longer than %d bytes", name, maxDatabaseNameLength)
+	case strings.ContainsAny(name, invalidDatabaseChars):
+		return fmt.Errorf("invalid database name %q: must not contain spaces, null bytes or any of /\\.\"$*<>:|?", name)
+	}
+	return nil
+}
+
+// ValidateCollectionName rejects collection names MongoDB would refuse or misread: names
+// with "$" or a null byte, names starting or ending with ".", and names making the
+// database.collection namespace longer than MongoDB allows. Dots inside a name, as in
+// "logs.2024", are legal and accepted.
+func ValidateCollectionName(database, name string) error {
+	switch {
+	case name == "":
+		return errors.New("collection name is required")
+	case strings.ContainsAny(name, "$\x00"):
+		return fmt.Errorf("invalid collection name %q: must not contain \"$\" or null bytes", name)
+	case strings.HasPrefix(name, ".") || strings.HasSuffix(name, "."):
+		return fmt.Errorf("invalid collection name %q: must not start or end with \".\"", name)
+	case len(database)+1+len(name) > maxNamespaceLength:
+		return fmt.Errorf("invalid collection name %q: the namespace %s.%s would be longer than %d bytes", name, database, name, maxNamespaceLength)
+	}
+	return nil
+}
+
+// setParam sets a path parameter, adding it when the route does not declare it
+func setParam(c echo.Context, name, value string) {
+	names := c.ParamNames()
+	values := c.ParamValues()
+	for i, n := range names {
+		if n == name {
+			values[i] = value
+			c.SetParamValues(values...)
+			return
+		}
+	}
+	c.SetParamNames(append(append([]string{}, names...), name)...)
+	c.SetParamValues(append(values, value)...)
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// selectorServer mounts DatabaseSelector on a route with a :db segment, one without, as
+// under /api/v1/collections, and the database listing. Handlers answer with the :db they see.
+func selectorServer(defaultDB string, allowed []string) *echo.Echo {
+	e := echo.New()
+	selected := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("db"))
+	}
+	selector := DatabaseSelector(defaultDB, allowed)
+	e.GET("/databases", selected, selector)
+	e.GET("/databases/:db/collections/:collection/documents", selected, selector)
+	e.GET("/collections/:collection/documents", selected, selector)
+	return e
+}
+
+func TestDatabaseSelector(t *testing.T) {
+	tests := []struct {
+		name      string
+		defaultDB string
+		allowed   []string
+		path      string
+		header    string
+		wantCode  int
+		wantDB    string
+	}{
+		{"header wins over path and default", "shared", nil, "/databases/tenant_a/collections/users/documents", "tenant_b", http.StatusOK, "tenant_b"},
+		{"path wins over default", "shared", nil, "/databases/tenant_a/collections/users/documents", "", http.StatusOK, "tenant_a"},
+		{"default without path or header", "shared", nil, "/collections/users/documents", "", http.StatusOK, "shared"},
+		{"header supplies a route without :db", "shared", nil, "/collections/users/documents", "tenant_b", http.StatusOK, "tenant_b"},
+		{"no database anywhere passes through", "", nil, "/databases", "", http.StatusOK, ""},
+		{"allowlisted header", "", []string{"tenant_a", "tenant_b"}, "/databases/tenant_a/collections/users/documents", "tenant_b", http.StatusOK, "tenant_b"},
+		{"header outside the allowlist", "", []string{"tenant_a"}, "/databases/tenant_a/collections/users/documents", "tenant_b", http.StatusForbidden, ""},
+		{"path outside the allowlist", "", []string{"tenant_a"}, "/databases/tenant_b/collections/users/documents", "", http.StatusForbidden, ""},
+		{"default outside the allowlist", "shared", []string{"tenant_a"}, "/collections/users/documents", "", http.StatusForbidden, ""},
+		{"invalid header name", "", nil, "/databases/tenant_a/collections/users/documents", "$external", http.StatusBadRequest, ""},
+		{"invalid collection name", "", nil, "/databases/tenant_a/collections/.users/documents", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(DatabaseHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			selectorServer(tt.defaultDB, tt.allowed).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && rec.Body.String() != tt.wantDB {
+				t.Fatalf("database = %q, want %q", rec.Body.String(), tt.wantDB)
+			}
+		})
+	}
+}
+
+func TestValidateDatabaseName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"mydb", true},
+		{"tenant_a-2024", true},
+		{strings.Repeat("d", 63), true},
+		{strings.Repeat("d", 64), false},
+		{"", false},
+		{"$external", false},
+		{"my.db", false},
+		{"my db", false},
+		{"my/db", false},
+		{`my\db`, false},
+		{`my"db`, false},
+		{"my*db", false},
+		{"my<db>", false},
+		{"my:db", false},
+		{"my|db", false},
+		{"my?db", false},
+		{"my\x00db", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDatabaseName(tt.name); (err == nil) != tt.valid {
+				t.Fatalf("ValidateDatabaseName(%q) = %v, want valid %v", tt.name, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateCollectionName(t *testing.T) {
+	const database = "mydb"
+	// The longest collection name that keeps "mydb.<name>" within 255 bytes
+	longest := strings.Repeat("c", 255-len(database)-1)
+
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"users", true},
+		{"logs.2024", true},
+		{"system.profile", true},
+		{longest, true},
+		{longest + "c", false},
+		{"", false},
+		{".users", false},
+		{"users.", false},
+		{".", false},
+		{"$cmd", false},
+		{"users$", false},
+		{"us\x00ers", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCollectionName(database, tt.name); (err == nil) != tt.valid {
+				t.Fatalf("ValidateCollectionName(%q) = %v, want valid %v", tt.name, err, tt.valid)
+			}
+		})
+	}
+
+	t.Run("the limit covers the database name", func(t *testing.T) {
+		if err := ValidateCollectionName(strings.Repeat("d", 63), longest); err == nil {
+			t.Fatal("accepted a namespace longer than 255 bytes")
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// echoNamespaceKey is the echo context key EchoNamespace stores its decision under
+const echoNamespaceKey = "echoNamespace"
+
+// EchoNamespace marks requests whose responses should carry the database and collection
+// they targeted. enabled is the ECHO_NAMESPACE default; ?echoNamespace=true or false
+// overrides it per request.
+func EchoNamespace(enabled bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			echoes := enabled
+			if flag, err := strconv.ParseBool(c.QueryParam("echoNamespace")); err == nil {
+				echoes = flag
+			}
+			if echoes {
+				c.Set(echoNamespaceKey, true)
+			}
+			return next(c)
+		}
+	}
+}
+
+// EchoesNamespace reports whether EchoNamespace marked the request
+func EchoesNamespace(c echo.Context) bool {
+	echoes, _ := c.Get(echoNamespaceKey).(bool)
+	return echoes
+}
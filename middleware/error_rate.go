@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// errorRateBucket counts the requests that finished within one second
+type errorRateBucket struct {
+	second int64
+	total  int64
+	failed int64
+}
+
+// ErrorRateTracker keeps the share of server errors among recent MongoDB-backed requests
+// over a sliding window, in one-second buckets. Unlike the circuit breaker, which reacts
+// to consecutive failures, it reports a sustained partial failure rate, so readiness can
+// turn degraded and load balancers drain the instance before it fails outright.
+type ErrorRateTracker struct {
+	threshold   int
+	minRequests int64
+
+	mu      sync.Mutex
+	buckets []errorRateBucket
+}
+
+// ErrorRate is a snapshot of the tracked window
+type ErrorRate struct {
+	Requests int64
+	Failures int64
+	// Percent is the failure share of Requests, 0 when there were none
+	Percent float64
+	// Degraded is true once Percent exceeds the threshold over at least minRequests requests
+	Degraded bool
+}
+
+// NewErrorRateTracker creates a tracker over window that reports degraded above threshold
+// percent. A threshold of 0 still counts requests but never reports degraded, and fewer
+// than minRequests requests in the window are too few to judge.
+func NewErrorRateTracker(threshold int, window time.Duration, minRequests int) *ErrorRateTracker {
+	seconds := int(window / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &ErrorRateTracker{
+		threshold:   threshold,
+		minRequests: int64(minRequests),
+		buckets:     make([]errorRateBucket, seconds),
+	}
+}
+
+// record adds the outcome of one request to the current second
+func (t *ErrorRateTracker) record(failed bool) {
+	now := time.Now().Unix()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := &t.buckets[now%int64(len(t.buckets))]
+	if b.second != now {
+		*b = errorRateBucket{second: now}
+	}
+	b.total++
+	if failed {
+		b.failed++
+	}
+}
+
+// Snapshot sums the buckets still inside the window
+func (t *ErrorRateTracker) Snapshot() ErrorRate {
+	now := time.Now().Unix()
+	oldest := now - int64(len(t.buckets)) + 1
+
+	var rate ErrorRate
+	t.mu.Lock()
+	for _, b := range t.buckets {
+		if b.second >= oldest {
+			rate.Requests += b.total
+			rate.Failures += b.failed
+		}
+	}
+	t.mu.Unlock()
+
+	if rate.Requests > 0 {
+		rate.Percent = float64(rate.Failures) * 100 / float64(rate.Requests)
+	}
+	rate.Degraded = t.threshold > 0 && rate.Requests >= t.minRequests && rate.Percent > float64(t.threshold)
+	return rate
+}
+
+// Middleware returns the echo middleware recording request outcomes. Every server error
+// counts as a failure, unlike for the circuit breaker, which only counts MongoDB being
+// unreachable or too slow.
+func (t *ErrorRateTracker) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			t.record(isServerError(c, err))
+			return err
+		}
+	}
+}
+
+// isServerError reports whether a handler outcome is a 5xx response
+func isServerError(c echo.Context, err error) bool {
+	if err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			return httpErr.Code >= http.StatusInternalServerError
+		}
+		return true
+	}
+	return c.Response().Status >= http.StatusInternalServerError
+}
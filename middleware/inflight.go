@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// inflightRetryAfter is the Retry-After value (seconds) sent when the ceiling is reached
+const inflightRetryAfter = "1"
+
+// InflightLimiter counts HTTP requests currently being served and rejects new ones with
+// 503 once the ceiling is reached. This guards the process itself against connection
+// floods, independently of how much MongoDB can take.
+type InflightLimiter struct {
+	max      int64
+	current  atomic.Int64
+	rejected atomic.Int64
+}
+
+// NewInflightLimiter creates a limiter. A max of 0 only counts requests and never rejects.
+func NewInflightLimiter(max int) *InflightLimiter {
+	return &InflightLimiter{max: int64(max)}
+}
+
+// Middleware returns the echo middleware that tracks and limits in-flight requests
+func (l *InflightLimiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			n := l.current.Add(1)
+			defer l.current.Add(-1)
+
+			if l.max > 0 && n > l.max {
+				l.rejected.Add(1)
+				c.Response().Header().Set("Retry-After", inflightRetryAfter)
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{
+					"error": "Too many requests in flight, try again later",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// InFlight returns the number of requests currently being served
+func (l *InflightLimiter) InFlight() int64 {
+	return l.current.Load()
+}
+
+// Max returns the configured ceiling, 0 meaning unlimited
+func (l *InflightLimiter) Max() int64 {
+	return l.max
+}
+
+// Rejected returns how many requests have been turned away since startup
+func (l *InflightLimiter) Rejected() int64 {
+	return l.rejected.Load()
+}
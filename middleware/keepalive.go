@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"mongodb-go-proxy/database"
+)
+
+// KeepAlive honours the X-Keep-Alive header, which holds the MongoDB connection open
+// past the idle timeout. The header value is a Go duration (e.g. "15m") and is capped at
+// database.MaxKeepAlive. The connection is also pinned for the duration of the request.
+// It must run after ReadAuth or WriteAuth; without an api-key tier the header is ignored.
+func KeepAlive(dbClient *database.Client) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			value := c.Request().Header.Get("X-Keep-Alive")
+			if value == "" || KeyTier(c) == "" {
+				return next(c)
+			}
+
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "X-Keep-Alive must be a positive duration such as 30s or 15m",
+				})
+			}
+
+			dbClient.PinConnection()
+			defer dbClient.Unpin()
+			dbClient.KeepAlive(d)
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// KeyInflightLimiter caps the requests each api-key tier may have in flight at once and
+// rejects the excess with 429, so one client opening many parallel requests cannot hold
+// every MongoDB connection. It must run after ReadAuth or WriteAuth, which record the tier.
+type KeyInflightLimiter struct {
+	limits  map[string]int64
+	current map[string]*atomic.Int64
+}
+
+// NewKeyInflightLimiter creates a limiter from tier -> ceiling. Tiers with a ceiling of 0
+// are neither counted nor limited.
+func NewKeyInflightLimiter(limits map[string]int) *KeyInflightLimiter {
+	l := &KeyInflightLimiter{
+		limits:  make(map[string]int64, len(limits)),
+		current: make(map[string]*atomic.Int64, len(limits)),
+	}
+	for tier, limit := range limits {
+		if limit > 0 {
+			l.limits[tier] = int64(limit)
+			l.current[tier] = &atomic.Int64{}
+		}
+	}
+	return l
+}
+
+// Middleware returns the echo middleware that tracks and limits in-flight requests per tier
+func (l *KeyInflightLimiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tier := KeyTier(c)
+			current, ok := l.current[tier]
+			if !ok {
+				return next(c)
+			}
+
+			n := current.Add(1)
+			defer current.Add(-1)
+
+			if n > l.limits[tier] {
+				c.Response().Header().Set("Retry-After", inflightRetryAfter)
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"error": "Too many concurrent requests for this api-key, try again later",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MethodOverride lets clients that can only send POST reach PUT, PATCH or DELETE routes
+// through the X-HTTP-Method-Override header. Only the methods in allowed are honored;
+// any other override is rejected with 400 rather than silently served as a POST.
+//
+// It must run before routing (e.Pre) so the overridden request is matched, and
+// authorized, as the method it becomes: a read-only key overriding POST to DELETE
+// still meets the write route's WriteAuth.
+func MethodOverride(allowed []string) echo.MiddlewareFunc {
+	permitted := make(map[string]bool, len(allowed))
+	for _, method := range allowed {
+		permitted[strings.ToUpper(method)] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			override := strings.ToUpper(strings.TrimSpace(req.Header.Get(echo.HeaderXHTTPMethodOverride)))
+			if req.Method != http.MethodPost || override == "" || override == http.MethodPost {
+				return next(c)
+			}
+
+			if !permitted[override] {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": echo.HeaderXHTTPMethodOverride + ": " + override + " is not allowed",
+				})
+			}
+			req.Method = override
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	fullSecret     = "full-secret"
+	readOnlySecret = "read-only-secret"
+)
+
+// overrideServer routes like main.go: MethodOverride before routing, then ReadAuth on the
+// read routes and WriteAuth on the write routes. Handlers answer with the method served.
+func overrideServer(allowed []string) *echo.Echo {
+	e := echo.New()
+	e.Pre(MethodOverride(allowed))
+	served := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Request().Method)
+	}
+	e.GET("/documents/:id", served, ReadAuth(fullSecret, readOnlySecret))
+	e.POST("/documents", served, WriteAuth(fullSecret))
+	e.PUT("/documents/:id", served, WriteAuth(fullSecret))
+	e.DELETE("/documents/:id", served, WriteAuth(fullSecret))
+	return e
+}
+
+func TestMethodOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		override string
+		key      string
+		wantCode int
+		wantBody string
+	}{
+		{"POST overridden to an allowed DELETE", http.MethodPost, "/documents/1", "DELETE", fullSecret, http.StatusOK, "DELETE"},
+		{"override is case-insensitive", http.MethodPost, "/documents/1", " put ", fullSecret, http.StatusOK, "PUT"},
+		{"POST without override stays POST", http.MethodPost, "/documents", "", fullSecret, http.StatusOK, "POST"},
+		{"override to POST is a no-op", http.MethodPost, "/documents", "POST", fullSecret, http.StatusOK, "POST"},
+		{"method outside the allowed list is rejected", http.MethodPost, "/documents/1", "PATCH", fullSecret, http.StatusBadRequest, ""},
+		{"only POST is overridden", http.MethodGet, "/documents/1", "DELETE", readOnlySecret, http.StatusOK, "GET"},
+		{"read-only key meets WriteAuth on the overridden route", http.MethodPost, "/documents/1", "DELETE", readOnlySecret, http.StatusForbidden, ""},
+		{"missing key meets WriteAuth on the overridden route", http.MethodPost, "/documents/1", "DELETE", "", http.StatusUnauthorized, ""},
+	}
+	e := overrideServer([]string{"PUT", "DELETE"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.override != "" {
+				req.Header.Set(echo.HeaderXHTTPMethodOverride, tt.override)
+			}
+			if tt.key != "" {
+				req.Header.Set("api-key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Fatalf("served as %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReadOnlyMode rejects every request that could change data with 403, whichever api-key
+// it carries. GET, HEAD and OPTIONS pass. Data API actions are all POSTs, so a POST passes
+// only when the last segment of its route is one of readActions; pass nil for REST routes.
+//
+// It runs after routing, so a POST overridden to DELETE by MethodOverride is judged as
+// the DELETE it became.
+func ReadOnlyMode(readActions []string) echo.MiddlewareFunc {
+	reads := make(map[string]bool, len(readActions))
+	for _, action := range readActions {
+		reads[action] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			case http.MethodPost:
+				if reads[path.Base(c.Path())] {
+					return next(c)
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "The proxy is in read-only mode",
+			})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requestIDKey is the echo context key AssignRequestID stores the request id under
+const requestIDKey = "requestID"
+
+// maxRequestIDLength bounds a client-supplied X-Request-ID
+const maxRequestIDLength = 128
+
+// AssignRequestID gives every request an id: the client's X-Request-ID when it is a
+// short token of letters, digits and ".-_:", otherwise a random one. The id is returned
+// in the X-Request-ID response header, which echo's request log prints as "id", and is
+// attached as the comment of the MongoDB operations the request runs.
+func AssignRequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				var b [16]byte
+				if _, err := rand.Read(b[:]); err != nil {
+					return err
+				}
+				id = hex.EncodeToString(b[:])
+			}
+			// The request log prefers the request header, so it must carry the id in use
+			c.Request().Header.Set(echo.HeaderXRequestID, id)
+			c.Set(requestIDKey, id)
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			return next(c)
+		}
+	}
+}
+
+// RequestID returns the id AssignRequestID gave the request, or "" if it did not run
+func RequestID(c echo.Context) string {
+	id, _ := c.Get(requestIDKey).(string)
+	return id
+}
+
+// validRequestID reports whether a client-supplied id is safe to echo and log as is
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '-', r == '_', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"mongodb-go-proxy/database"
+)
+
+// UpstreamHealth fast-fails requests with 503 while MongoDB is known to be unreachable,
+// instead of letting each request wait for its own connection timeout. Requests failover
+// accepts are let through anyway, because their handler can answer them from another
+// deployment; pass nil when there is none.
+func UpstreamHealth(dbClient *database.Client, failover func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !dbClient.Healthy() && (failover == nil || !failover(c)) {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(database.HealthRetryInterval.Seconds())))
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{
+					"error": "MongoDB is currently unavailable",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,8 +24,102 @@ var (
 	duration    = flag.Duration("d", 30*time.Second, "Duration of the test")
 	requests    = flag.Int("n", 0, "Total number of requests (0 = run for duration)")
 	timeout     = flag.Duration("timeout", 10*time.Second, "Request timeout")
+	scenario    = flag.String("scenario", "", "Scenario file with weighted request templates (overrides -url)")
 )
 
+// RequestTemplate is one kind of request in a mixed-workload scenario
+type RequestTemplate struct {
+	Name    string            `json:"name"`
+	Weight  int               `json:"weight"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Body    json.RawMessage   `json:"body"`
+	Headers map[string]string `json:"headers"`
+	Timeout string            `json:"timeout"` // Overrides -timeout for this template, e.g. "2s"
+
+	timeout time.Duration
+	stats   *Stats
+}
+
+// Scenario is a weighted mix of request templates sent against one base URL
+type Scenario struct {
+	BaseURL  string             `json:"baseUrl"`
+	Requests []*RequestTemplate `json:"requests"`
+
+	totalWeight int
+}
+
+// loadScenario reads and validates a scenario file
+func loadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("invalid scenario file: %w", err)
+	}
+	if sc.BaseURL == "" {
+		return nil, fmt.Errorf("baseUrl is required")
+	}
+	if len(sc.Requests) == 0 {
+		return nil, fmt.Errorf("requests cannot be empty")
+	}
+
+	for i, t := range sc.Requests {
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("request-%d", i)
+		}
+		if t.Weight <= 0 {
+			return nil, fmt.Errorf("%s: weight must be positive", t.Name)
+		}
+		if t.Method == "" {
+			t.Method = http.MethodGet
+		}
+		t.Method = strings.ToUpper(t.Method)
+		if t.Path == "" {
+			return nil, fmt.Errorf("%s: path is required", t.Name)
+		}
+		t.timeout = *timeout
+		if t.Timeout != "" {
+			if t.timeout, err = time.ParseDuration(t.Timeout); err != nil || t.timeout <= 0 {
+				return nil, fmt.Errorf("%s: timeout must be a positive duration such as 2s", t.Name)
+			}
+		}
+		t.stats = NewStats()
+		sc.totalWeight += t.Weight
+	}
+	return &sc, nil
+}
+
+// singleURLScenario wraps the -url flag as a scenario with one GET template
+func singleURLScenario(url string) *Scenario {
+	return &Scenario{
+		Requests: []*RequestTemplate{{
+			Name:    "url",
+			Weight:  1,
+			Method:  http.MethodGet,
+			Path:    url,
+			timeout: *timeout,
+			stats:   NewStats(),
+		}},
+		totalWeight: 1,
+	}
+}
+
+// pick chooses a template at random in proportion to its weight
+func (sc *Scenario) pick() *RequestTemplate {
+	n := rand.Intn(sc.totalWeight)
+	for _, t := range sc.Requests {
+		if n < t.Weight {
+			return t
+		}
+		n -= t.Weight
+	}
+	return sc.Requests[len(sc.Requests)-1]
+}
+
 type Stats struct {
 	totalRequests   int64
 	successRequests int64
@@ -95,23 +196,53 @@ func (s *Stats) Print(testDuration time.Duration) {
 	}
 }
 
-func makeRequest(client *http.Client, url, apiSecret string, stats *Stats) {
+// PrintRow prints a one-line summary, used for the per-scenario breakdown
+func (s *Stats) PrintRow(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := atomic.LoadInt64(&s.totalRequests)
+	if total == 0 {
+		fmt.Printf("  %-20s %8d\n", name, 0)
+		return
+	}
+	failed := atomic.LoadInt64(&s.failedRequests)
+	fmt.Printf("  %-20s %8d %8d %12v %12v\n", name, total, failed, s.totalDuration/time.Duration(total), s.maxDuration)
+}
+
+func makeRequest(client *http.Client, baseURL string, t *RequestTemplate, apiSecret string, stats *Stats) {
 	start := time.Now()
+	record := func(statusCode int, err error) {
+		duration := time.Since(start)
+		stats.RecordRequest(duration, statusCode, err)
+		t.stats.RecordRequest(duration, statusCode, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
 
-	req, err := http.NewRequest("GET", url, nil)
+	var body io.Reader
+	if len(t.Body) > 0 {
+		body = bytes.NewReader(t.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, t.Method, baseURL+t.Path, body)
 	if err != nil {
-		stats.RecordRequest(time.Since(start), 0, err)
+		record(0, err)
 		return
 	}
 
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("api-secret", apiSecret)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range t.Headers {
+		req.Header.Set(key, value)
+	}
 
 	resp, err := client.Do(req)
-	duration := time.Since(start)
-
 	if err != nil {
-		stats.RecordRequest(duration, 0, err)
+		record(0, err)
 		return
 	}
 	defer resp.Body.Close()
@@ -119,14 +250,13 @@ func makeRequest(client *http.Client, url, apiSecret string, stats *Stats) {
 	// Read response body to ensure connection is fully processed
 	io.Copy(io.Discard, resp.Body)
 
-	stats.RecordRequest(duration, resp.StatusCode, nil)
+	record(resp.StatusCode, nil)
 }
 
-func runStressTest() {
+func runStressTest(sc *Scenario) {
 	stats := NewStats()
-	client := &http.Client{
-		Timeout: *timeout,
-	}
+	// Each request carries its own deadline, so templates may outlast -timeout
+	client := &http.Client{}
 
 	var wg sync.WaitGroup
 	stopChan := make(chan struct{})
@@ -150,7 +280,7 @@ func runStressTest() {
 						}
 						atomic.AddInt64(&totalRequestCount, 1)
 					}
-					makeRequest(client, *url, *apiSecret, stats)
+					makeRequest(client, sc.BaseURL, sc.pick(), *apiSecret, stats)
 				}
 			}
 		}()
@@ -173,13 +303,38 @@ func runStressTest() {
 
 	testDuration := time.Since(startTime)
 	stats.Print(testDuration)
+
+	if len(sc.Requests) > 1 {
+		templates := append([]*RequestTemplate(nil), sc.Requests...)
+		sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+		fmt.Printf("\nPer Scenario:\n")
+		fmt.Printf("  %-20s %8s %8s %12s %12s\n", "Name", "Requests", "Failed", "Average", "Max")
+		for _, t := range templates {
+			t.stats.PrintRow(t.Name)
+		}
+	}
 }
 
 func main() {
 	flag.Parse()
 
+	sc := singleURLScenario(*url)
+	if *scenario != "" {
+		loaded, err := loadScenario(*scenario)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load scenario: %v\n", err)
+			os.Exit(1)
+		}
+		sc = loaded
+	}
+
 	fmt.Printf("Starting stress test...\n")
-	fmt.Printf("URL: %s\n", *url)
+	if *scenario != "" {
+		fmt.Printf("Scenario: %s (%d request templates against %s)\n", *scenario, len(sc.Requests), sc.BaseURL)
+	} else {
+		fmt.Printf("URL: %s\n", *url)
+	}
 	fmt.Printf("Concurrency: %d\n", *concurrency)
 	if *requests > 0 {
 		fmt.Printf("Total Requests: %d\n", *requests)
@@ -189,5 +344,5 @@ func main() {
 	fmt.Printf("Request Timeout: %v\n", *timeout)
 	fmt.Println()
 
-	runStressTest()
+	runStressTest(sc)
 }